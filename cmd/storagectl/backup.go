@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"mock-storage/internal/backup"
+	"mock-storage/internal/config"
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/service"
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+)
+
+// runBackup 执行backup子命令
+func runBackup(cfg *config.Config, command string, args []string) error {
+	if cfg.Database.Driver != metadata.DriverSQLite {
+		return fmt.Errorf("在线备份只支持SQLite元数据（当前驱动 %s）", cfg.Database.Driver)
+	}
+
+	defaultDir := cfg.Backup.Dir
+	if defaultDir == "" {
+		defaultDir = backup.DefaultDir
+	}
+
+	flags := flag.NewFlagSet("backup "+command, flag.ExitOnError)
+	dir := flags.String("dir", defaultDir, "快照目录")
+	allowInvalid := flags.Bool("allow-invalid", false, "restore: 快照校验未通过时也执行恢复")
+	allowRunning := flags.Bool("allow-running", false, "restore: 检测到服务仍在运行时也执行恢复")
+	flags.Parse(args)
+
+	switch command {
+	case "create":
+		return createBackup(cfg, *dir)
+
+	case "list":
+		return listBackups(*dir)
+
+	case "verify", "restore":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("用法: storagectl backup %s [选项] <快照>", command)
+		}
+		snapshotPath := resolveSnapshot(*dir, flags.Arg(0))
+		report, err := verifyBackup(cfg, snapshotPath)
+		if err != nil {
+			return err
+		}
+		if command == "verify" {
+			if !report.Valid {
+				return fmt.Errorf("快照校验未通过")
+			}
+			return nil
+		}
+		return restoreBackup(cfg, snapshotPath, report, *allowInvalid, *allowRunning)
+
+	default:
+		return fmt.Errorf("未知命令: backup %s", command)
+	}
+}
+
+// createBackup 立即生成快照，服务运行时也可以使用
+func createBackup(cfg *config.Config, dir string) error {
+	db, err := metadata.OpenDatabase(cfg.Database.Driver, cfg.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	manager, err := backup.NewManager(db, dir, 0, cfg.Backup.Retention)
+	if err != nil {
+		return err
+	}
+
+	result := manager.RunOnce()
+	if result.Snapshot == nil {
+		return fmt.Errorf("备份失败: %s", result.Error)
+	}
+	fmt.Printf("已生成快照 %s（%d 字节）\n", result.Snapshot.Path, result.Snapshot.Size)
+	for _, name := range result.Removed {
+		fmt.Printf("已删除超出保留数量的快照 %s\n", name)
+	}
+	if result.Error != "" {
+		return fmt.Errorf("清理旧快照失败: %s", result.Error)
+	}
+	return nil
+}
+
+// listBackups 以表格形式输出快照
+func listBackups(dir string) error {
+	snapshots, err := backup.List(dir)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tCREATED AT\tSIZE")
+	for _, snapshot := range snapshots {
+		fmt.Fprintf(writer, "%s\t%s\t%d\n", snapshot.Name, snapshot.CreatedAt.Local().Format(time.DateTime), snapshot.Size)
+	}
+	writer.Flush()
+
+	fmt.Printf("\n共 %d 个快照（%s）\n", len(snapshots), dir)
+	return nil
+}
+
+// resolveSnapshot 参数不是已存在的文件时按快照目录中的文件名处理
+func resolveSnapshot(dir, name string) string {
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// verifyBackup 对照配置中的存储节点校验快照并输出结果
+func verifyBackup(cfg *config.Config, snapshotPath string) (*backup.ValidationReport, error) {
+	if cfg.Storage.Driver == storage.DriverMemory {
+		return nil, fmt.Errorf("内存存储节点没有持久数据，无法校验快照")
+	}
+
+	nodes := make(map[string]types.StorageNode)
+	for _, nodeConfig := range cfg.Storage.Nodes {
+		node, err := service.NewStorageNode(cfg.Storage.Driver, nodeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("创建存储节点 %s 失败: %v", nodeConfig.ID, err)
+		}
+		nodes[nodeConfig.ID] = node
+	}
+
+	report, err := backup.ValidateSnapshot(snapshotPath, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("快照: %s\n", report.Snapshot)
+	fmt.Printf("表结构版本: %d（当前程序 %d）\n", report.SchemaVersion, metadata.LatestSchemaVersion())
+	fmt.Printf("对象: %d，缺失: %d，部分副本缺失: %d\n", report.Objects, report.Missing, report.Degraded)
+	for _, problem := range report.Problems {
+		fmt.Printf("  - %s\n", problem)
+	}
+	if report.Valid {
+		fmt.Println("校验通过")
+	}
+	return report, nil
+}
+
+// restoreBackup 用快照替换当前数据库，需要先停止服务
+// allowInvalid跳过校验结果，allowRunning跳过服务运行检测
+func restoreBackup(cfg *config.Config, snapshotPath string, report *backup.ValidationReport, allowInvalid, allowRunning bool) error {
+	if !report.Valid && !allowInvalid {
+		return fmt.Errorf("快照校验未通过，未执行恢复（使用 -allow-invalid 强制恢复）")
+	}
+	if !allowRunning && serverRunning(cfg) {
+		return fmt.Errorf("服务仍在运行（%s:%s），请先停止服务再恢复（使用 -allow-running 强制恢复）", cfg.Server.Host, cfg.Server.Port)
+	}
+
+	previousPath, err := backup.Restore(snapshotPath, metadata.SQLitePath(cfg.Database.DSN))
+	if previousPath != "" {
+		fmt.Printf("原数据库已保存到 %s\n", previousPath)
+	}
+	if err != nil {
+		return fmt.Errorf("恢复失败: %v", err)
+	}
+
+	fmt.Printf("已从 %s 恢复元数据\n", snapshotPath)
+	if report.Degraded > 0 {
+		fmt.Println("注意: 部分对象副本缺失，启动服务后可执行 POST /api/v1/scrub 修复")
+	}
+	return nil
+}
+
+// serverRunning 通过健康检查接口判断服务是否仍在运行
+func serverRunning(cfg *config.Config) bool {
+	host := cfg.Server.Host
+	if host == "" || host == "0.0.0.0" {
+		host = "localhost"
+	}
+
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get("http://" + net.JoinHostPort(host, cfg.Server.Port) + "/health")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"mock-storage/internal/config"
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/types"
+)
+
+// runMetadata 执行metadata子命令，直接读写配置中的数据库
+func runMetadata(cfg *config.Config, command string, args []string) error {
+	if cfg.Database.Driver == metadata.DriverMemory {
+		return fmt.Errorf("内存元数据存储只存在于服务进程中，请使用 /api/v1/metadata 接口")
+	}
+
+	flags := flag.NewFlagSet("metadata "+command, flag.ExitOnError)
+	bucket := flags.String("bucket", "", "只处理该bucket中的对象")
+	prefix := flags.String("prefix", "", "key前缀，指定bucket时为bucket内的前缀")
+	updatedAfter := flags.String("updated-after", "", "更新时间下限（RFC3339或YYYY-MM-DD）")
+	updatedBefore := flags.String("updated-before", "", "更新时间上限（RFC3339或YYYY-MM-DD）")
+	output := flags.String("o", "-", "导出文件，- 表示标准输出")
+	conflict := flags.String("conflict", types.ImportConflictSkip, "key已存在时的策略：skip、overwrite或newer-wins")
+	dryRun := flags.Bool("dry-run", false, "只检查并统计，不写入")
+	jsonReport := flags.Bool("json", false, "以JSON输出完整的导入结果")
+	flags.Parse(args)
+
+	filter := types.CatalogFilter{Bucket: *bucket, Prefix: *prefix}
+	var err error
+	if filter.UpdatedAfter, err = parseTimeFlag("updated-after", *updatedAfter); err != nil {
+		return err
+	}
+	if filter.UpdatedBefore, err = parseTimeFlag("updated-before", *updatedBefore); err != nil {
+		return err
+	}
+
+	switch command {
+	case "export":
+		return exportMetadata(cfg, filter, *output)
+
+	case "import":
+		input := "-"
+		if flags.NArg() > 0 {
+			input = flags.Arg(0)
+		}
+		options := types.ImportOptions{Filter: filter, Conflict: *conflict, DryRun: *dryRun}
+		for _, node := range cfg.Storage.Nodes {
+			options.Nodes = append(options.Nodes, node.ID)
+		}
+		return importMetadata(cfg, options, input, *jsonReport)
+
+	default:
+		return fmt.Errorf("未知命令: metadata %s", command)
+	}
+}
+
+// exportMetadata 导出元数据，只读取数据库，不执行迁移
+// 输出到标准输出时统计信息写入标准错误
+func exportMetadata(cfg *config.Config, filter types.CatalogFilter, output string) error {
+	db, err := metadata.OpenDatabase(cfg.Database.Driver, cfg.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	var writer io.Writer = os.Stdout
+	if output != "-" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("创建导出文件失败: %v", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	exported, err := metadata.NewMetaService(db).ExportMetadata(writer, filter)
+	if err != nil {
+		return fmt.Errorf("导出失败（已导出 %d 条）: %v", exported, err)
+	}
+	fmt.Fprintf(os.Stderr, "已导出 %d 条元数据\n", exported)
+	return nil
+}
+
+// importMetadata 导入元数据，有记录失败时返回错误
+func importMetadata(cfg *config.Config, options types.ImportOptions, input string, jsonReport bool) error {
+	var reader io.Reader = os.Stdin
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return fmt.Errorf("打开导入文件失败: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	// 与服务启动时一致地初始化数据库（迁移和全文索引触发器）
+	db, err := metadata.NewDatabaseManager(cfg.Database.Driver, cfg.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	report, err := metadata.NewMetaService(db).ImportMetadata(reader, options)
+	if report != nil {
+		if jsonReport {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(report)
+		} else {
+			printImportReport(report)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("导入失败: %v", err)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d 条记录导入失败", report.Failed)
+	}
+	return nil
+}
+
+// printImportReport 输出导入结果：汇总、原因统计和明细
+func printImportReport(report *types.ImportReport) {
+	mode := ""
+	if report.DryRun {
+		mode = "（dry-run，未写入）"
+	}
+	fmt.Printf("\n导入完成%s: 共 %d 条，导入 %d，跳过 %d，失败 %d（冲突策略 %s）\n",
+		mode, report.Total, report.Imported, report.Skipped, report.Failed, report.Conflict)
+
+	if len(report.Reasons) == 0 {
+		return
+	}
+
+	reasons := make([]string, 0, len(report.Reasons))
+	for reason := range report.Reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	fmt.Println()
+	for _, reason := range reasons {
+		fmt.Printf("  %-36s %d\n", reason, report.Reasons[reason])
+	}
+
+	fmt.Println()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "LINE\tRESULT\tKEY\tREASON")
+	for _, issue := range report.Issues {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", issue.Line, issue.Result, issue.Key, issue.Reason)
+	}
+	writer.Flush()
+
+	if omitted := report.Skipped + report.Failed - len(report.Issues); omitted > 0 {
+		fmt.Printf("\n另有 %d 条明细未列出\n", omitted)
+	}
+}
+
+// parseTimeFlag 解析时间参数，支持RFC3339和日期（YYYY-MM-DD，UTC零点），未提供时返回零值
+func parseTimeFlag(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无效的 -%s %q（使用RFC3339或YYYY-MM-DD）", name, value)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"mock-storage/internal/config"
+	"mock-storage/internal/metadata"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法: storagectl [-config config.json] [-driver 驱动] [-dsn 连接串] <命令>
+
+命令:
+  migrate status          显示表结构迁移状态
+  migrate up              执行所有未应用的迁移
+  migrate down [-steps N] 回滚最近应用的N个迁移（默认1个）
+  migrate unlock          强制释放迁移锁（持有锁的进程异常退出后使用）
+  wal segments            列出操作日志的段文件
+  wal list [选项]         以表格形式列出操作日志记录
+  wal dump [选项]         以JSON Lines输出操作日志记录
+  metadata export [选项]  以NDJSON导出元数据目录
+  metadata import [选项] [文件]
+                          从NDJSON文件（默认标准输入）导入元数据目录
+  backup create           立即生成元数据快照（服务运行时也可以使用）
+  backup list             列出快照
+  backup verify <快照>    校验快照的完整性以及对象在存储节点上是否存在
+  backup restore <快照>   校验快照后替换当前数据库（需要先停止服务）
+
+wal list/dump 选项:
+  -dir 目录               日志目录，默认使用配置文件中的 wal.dir
+  -pending                只输出未完成的意图记录
+  -key KEY                只输出该对象key（bucket/key）的记录
+  -tx ID                  只输出该事务的记录
+
+metadata export/import 选项:
+  -bucket BUCKET          只处理该bucket中的对象
+  -prefix PREFIX          key前缀，指定bucket时为bucket内的前缀
+  -updated-after TIME     更新时间下限（RFC3339或YYYY-MM-DD）
+  -updated-before TIME    更新时间上限
+  -o FILE                 export: 导出文件，默认标准输出
+  -conflict POLICY        import: key已存在时 skip（默认）、overwrite 或 newer-wins
+  -dry-run                import: 只检查并统计，不写入
+  -json                   import: 以JSON输出完整的导入结果
+
+backup 选项:
+  -dir 目录               快照目录，默认使用配置文件中的 backup.dir
+  -allow-invalid          restore: 快照校验未通过时也执行恢复
+  -allow-running          restore: 检测到服务仍在运行时也执行恢复`)
+}
+
+func main() {
+	configFile := flag.String("config", "config.json", "配置文件")
+	driver := flag.String("driver", "", "数据库驱动，默认使用配置文件中的 database.driver")
+	dsn := flag.String("dsn", "", "数据库连接串，默认使用配置文件中的 database.dsn")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 || (args[0] != "migrate" && args[0] != "wal" && args[0] != "metadata" && args[0] != "backup") {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Printf("加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	if *driver != "" {
+		cfg.Database.Driver = *driver
+	}
+	if *dsn != "" {
+		cfg.Database.DSN = *dsn
+	}
+
+	if args[0] == "wal" {
+		if err := runWAL(cfg, args[1], args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if args[0] == "metadata" || args[0] == "backup" {
+		run := runMetadata
+		if args[0] == "backup" {
+			run = runBackup
+		}
+		if err := run(cfg, args[1], args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.Database.Driver == metadata.DriverMemory {
+		fmt.Println("内存元数据存储没有表结构，无需迁移")
+		return
+	}
+
+	db, err := metadata.OpenDatabase(cfg.Database.Driver, cfg.Database.DSN)
+	if err != nil {
+		fmt.Printf("连接数据库失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = runMigrate(db, args[1], args[2:])
+	db.Close()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runMigrate 执行migrate子命令
+func runMigrate(db *metadata.DatabaseManager, command string, args []string) error {
+	switch command {
+	case "status":
+		return printStatus(db)
+
+	case "up":
+		applied, err := db.Migrate()
+		if err != nil {
+			return fmt.Errorf("迁移失败: %v", err)
+		}
+		fmt.Printf("已应用 %d 个迁移，当前版本 %d\n", applied, metadata.LatestSchemaVersion())
+		return nil
+
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "回滚的迁移数量")
+		flags.Parse(args)
+
+		rolledBack, err := db.Rollback(*steps)
+		if err != nil {
+			return fmt.Errorf("回滚失败（已回滚 %d 个）: %v", rolledBack, err)
+		}
+		fmt.Printf("已回滚 %d 个迁移\n", rolledBack)
+		fmt.Println("注意: API服务启动时会自动重新应用未应用的迁移")
+		return nil
+
+	case "unlock":
+		if err := db.ForceUnlockMigrations(); err != nil {
+			return err
+		}
+		fmt.Println("迁移锁已释放")
+		return nil
+
+	default:
+		return fmt.Errorf("未知命令: migrate %s", command)
+	}
+}
+
+// printStatus 以表格形式输出迁移状态
+func printStatus(db *metadata.DatabaseManager) error {
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		return fmt.Errorf("获取迁移状态失败: %v", err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+
+	pending := 0
+	for _, status := range statuses {
+		state, appliedAt, name := "pending", "-", status.Name
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Local().Format(time.DateTime)
+		} else {
+			pending++
+		}
+		if status.Unknown {
+			name = "(unknown, newer binary)"
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", status.Version, name, state, appliedAt)
+	}
+	writer.Flush()
+
+	fmt.Printf("\n最新版本 %d，待应用 %d 个\n", metadata.LatestSchemaVersion(), pending)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"mock-storage/internal/config"
+	"mock-storage/internal/wal"
+)
+
+// runWAL 执行wal子命令，只读取日志文件，服务运行时也可以使用
+func runWAL(cfg *config.Config, command string, args []string) error {
+	flags := flag.NewFlagSet("wal "+command, flag.ExitOnError)
+	dir := flags.String("dir", cfg.WAL.Dir, "日志目录")
+	pendingOnly := flags.Bool("pending", false, "只输出未完成的意图记录")
+	key := flags.String("key", "", "只输出该对象key的记录")
+	txID := flags.String("tx", "", "只输出该事务的记录")
+	flags.Parse(args)
+
+	records, segments, err := wal.ReadDir(*dir)
+	if err != nil {
+		return fmt.Errorf("读取操作日志失败: %v", err)
+	}
+
+	switch command {
+	case "segments":
+		return printSegments(segments)
+
+	case "list", "dump":
+		// 事务的状态由提交或中止记录决定，没有时为pending
+		states := make(map[string]string)
+		for _, record := range records {
+			if record.Type != wal.RecordIntent {
+				states[record.TxID] = record.Type
+			}
+		}
+
+		var selected []*wal.Record
+		for _, record := range records {
+			if *pendingOnly && (record.Type != wal.RecordIntent || states[record.TxID] != "") {
+				continue
+			}
+			if *txID != "" && record.TxID != *txID {
+				continue
+			}
+			if *key != "" && record.Key != *key && !intentHasKey(records, record.TxID, *key) {
+				continue
+			}
+			selected = append(selected, record)
+		}
+
+		if command == "dump" {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, record := range selected {
+				if err := encoder.Encode(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return printRecords(selected, states)
+
+	default:
+		return fmt.Errorf("未知命令: wal %s", command)
+	}
+}
+
+// intentHasKey 判断事务的意图记录是否属于该key，提交和中止记录本身不带key
+func intentHasKey(records []*wal.Record, txID, key string) bool {
+	for _, record := range records {
+		if record.Type == wal.RecordIntent && record.TxID == txID {
+			return record.Key == key
+		}
+	}
+	return false
+}
+
+// printSegments 以表格形式输出段文件信息
+func printSegments(segments []wal.SegmentInfo) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "FIRST SEQ\tRECORDS\tPENDING\tSIZE\tPATH")
+	for _, segment := range segments {
+		fmt.Fprintf(writer, "%d\t%d\t%d\t%d\t%s\n", segment.FirstSeq, segment.Records, segment.Pending, segment.Size, segment.Path)
+	}
+	writer.Flush()
+
+	fmt.Printf("\n共 %d 个段文件\n", len(segments))
+	return nil
+}
+
+// printRecords 以表格形式输出日志记录，意图记录显示事务的最终状态
+func printRecords(records []*wal.Record, states map[string]string) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "SEQ\tTIME\tTYPE\tTX\tOP\tKEY\tSTATUS")
+
+	pending := 0
+	for _, record := range records {
+		status := "-"
+		if record.Type == wal.RecordIntent {
+			status = states[record.TxID]
+			if status == "" {
+				status = "pending"
+				pending++
+			}
+		}
+		if record.Error != "" {
+			status = "error: " + record.Error
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", record.Seq, record.Time.Local().Format(time.DateTime),
+			record.Type, record.TxID, record.Op, record.Key, status)
+	}
+	writer.Flush()
+
+	fmt.Printf("\n共 %d 条记录，未完成 %d 个\n", len(records), pending)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"mock-storage/internal/storage"
+	"mock-storage/internal/storagenode"
+
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	nodeID := flag.String("id", "stg1", "存储节点ID")
+	dataDir := flag.String("dir", "./data/stg1", "数据目录")
+	listen := flag.String("listen", ":9001", "监听地址")
+	token := flag.String("token", os.Getenv("STORAGE_NODE_TOKEN"), "节点令牌，默认读取环境变量 STORAGE_NODE_TOKEN")
+	flag.Parse()
+
+	// 创建本地存储节点
+	node, err := storage.NewFileStorageNode(*nodeID, *dataDir)
+	if err != nil {
+		fmt.Printf("创建存储节点失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Logger())
+	router.Use(gin.Recovery())
+
+	storagenode.NewServer(node, *token).SetupRoutes(router)
+
+	server := &http.Server{
+		Addr:    *listen,
+		Handler: router,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("存储节点启动失败: %v\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	fmt.Printf("存储节点 %s 已启动: %s (数据目录 %s)\n", *nodeID, *listen, *dataDir)
+	if *token == "" {
+		fmt.Println("⚠️  未设置节点令牌，任何能访问该地址的客户端都可以读写数据")
+	}
+
+	// 设置优雅关闭
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	if err := server.Close(); err != nil {
+		fmt.Printf("停止存储节点时出错: %v\n", err)
+	}
+	fmt.Printf("存储节点 %s 已停止\n", *nodeID)
+}
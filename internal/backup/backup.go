@@ -0,0 +1,261 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Database 支持在线备份的元数据数据库（避免循环依赖）
+type Database interface {
+	BackupTo(path string) error
+}
+
+// 快照文件名：metadata-<UTC时间>.db，按文件名排序即按时间排序
+const (
+	snapshotPrefix     = "metadata-"
+	snapshotSuffix     = ".db"
+	snapshotTimeLayout = "20060102-150405.000"
+)
+
+// DefaultDir 配置中未指定快照目录时使用的目录
+const DefaultDir = "./data/backups"
+
+// Snapshot 备份目录中的一个快照
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RunResult 一次备份的结果
+type RunResult struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Snapshot   *Snapshot `json:"snapshot,omitempty"`
+	Removed    []string  `json:"removed,omitempty"` // 超出保留数量被删除的快照
+	Error      string    `json:"error,omitempty"`
+}
+
+// Manager 元数据备份任务，定期生成快照并按数量保留
+type Manager struct {
+	db        Database
+	dir       string
+	interval  time.Duration
+	retention int
+
+	mutex   sync.Mutex
+	runMu   sync.Mutex
+	running bool
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+	lastRun *RunResult
+}
+
+// NewManager 创建备份任务，retention为保留的快照数量（<=0时不删除旧快照）
+func NewManager(db Database, dir string, interval time.Duration, retention int) (*Manager, error) {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory %s: %v", dir, err)
+	}
+
+	return &Manager{
+		db:        db,
+		dir:       dir,
+		interval:  interval,
+		retention: retention,
+	}, nil
+}
+
+// Start 启动定期备份循环
+func (m *Manager) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.running || m.interval <= 0 {
+		return
+	}
+
+	m.running = true
+	m.stopCh = make(chan struct{})
+	m.doneCh = make(chan struct{})
+
+	go m.loop(m.stopCh, m.doneCh)
+	fmt.Printf("[BACKUP] Scheduled backups started (interval: %v, retention: %d)\n", m.interval, m.retention)
+}
+
+// Stop 停止定期备份循环
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	if !m.running {
+		m.mutex.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stopCh)
+	doneCh := m.doneCh
+	m.mutex.Unlock()
+
+	<-doneCh
+	fmt.Println("[BACKUP] Scheduled backups stopped")
+}
+
+// loop 备份循环
+func (m *Manager) loop(stopCh, doneCh chan struct{}) {
+	defer close(doneCh)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			m.RunOnce()
+		}
+	}
+}
+
+// RunOnce 立即生成一个快照并清理超出保留数量的旧快照，同一时间只允许一次执行
+// 快照先写入临时文件，完成后再改名，目录中不会出现不完整的快照
+func (m *Manager) RunOnce() *RunResult {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	result := &RunResult{StartedAt: time.Now()}
+	snapshot, err := m.createSnapshot(result.StartedAt)
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Printf("[BACKUP] Backup failed: %v\n", err)
+	} else {
+		result.Snapshot = snapshot
+		fmt.Printf("[BACKUP] Created snapshot %s (%d bytes)\n", snapshot.Name, snapshot.Size)
+
+		result.Removed, err = m.prune()
+		if err != nil {
+			result.Error = err.Error()
+			fmt.Printf("[BACKUP] Failed to remove old snapshots: %v\n", err)
+		}
+	}
+	result.FinishedAt = time.Now()
+
+	m.mutex.Lock()
+	m.lastRun = result
+	m.mutex.Unlock()
+	return result
+}
+
+// createSnapshot 生成快照文件
+func (m *Manager) createSnapshot(now time.Time) (*Snapshot, error) {
+	name := snapshotPrefix + now.UTC().Format(snapshotTimeLayout) + snapshotSuffix
+	path := filepath.Join(m.dir, name)
+	tempPath := path + ".tmp"
+
+	os.Remove(tempPath)
+	if err := m.db.BackupTo(tempPath); err != nil {
+		os.Remove(tempPath)
+		return nil, err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to finalize snapshot: %v", err)
+	}
+
+	return statSnapshot(path)
+}
+
+// prune 删除超出保留数量的最旧快照
+func (m *Manager) prune() ([]string, error) {
+	if m.retention <= 0 {
+		return nil, nil
+	}
+
+	snapshots, err := List(m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for len(snapshots) > m.retention {
+		oldest := snapshots[len(snapshots)-1]
+		if err := os.Remove(oldest.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove snapshot %s: %v", oldest.Name, err)
+		}
+		removed = append(removed, oldest.Name)
+		snapshots = snapshots[:len(snapshots)-1]
+	}
+	return removed, nil
+}
+
+// Snapshots 列出备份目录中的快照，最新的在前
+func (m *Manager) Snapshots() ([]*Snapshot, error) {
+	return List(m.dir)
+}
+
+// GetStatus 获取备份任务状态
+func (m *Manager) GetStatus() map[string]any {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return map[string]any{
+		"running":   m.running,
+		"dir":       m.dir,
+		"interval":  m.interval.String(),
+		"retention": m.retention,
+		"last_run":  m.lastRun,
+	}
+}
+
+// List 列出目录中的快照，最新的在前；目录不存在时返回空列表
+func List(dir string) ([]*Snapshot, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	var snapshots []*Snapshot
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+			continue
+		}
+		snapshot, err := statSnapshot(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name > snapshots[j].Name
+	})
+	return snapshots, nil
+}
+
+// statSnapshot 读取快照文件信息，创建时间取自文件名
+func statSnapshot(path string) (*Snapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(path)
+	createdAt := info.ModTime()
+	timestamp := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix)
+	if t, err := time.Parse(snapshotTimeLayout, timestamp); err == nil {
+		createdAt = t
+	}
+
+	return &Snapshot{Name: name, Path: path, Size: info.Size(), CreatedAt: createdAt}, nil
+}
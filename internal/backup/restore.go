@@ -0,0 +1,204 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/types"
+)
+
+// 校验结果中最多保留的问题条数
+const maxValidationProblems = 50
+
+// ValidationReport 快照校验结果
+type ValidationReport struct {
+	Snapshot      string   `json:"snapshot"`
+	SchemaVersion int      `json:"schema_version"`
+	Objects       int      `json:"objects"`  // 校验的当前对象数
+	Missing       int      `json:"missing"`  // 所有副本都不存在的对象数
+	Degraded      int      `json:"degraded"` // 部分副本不存在的对象数（可通过副本校验修复）
+	Problems      []string `json:"problems,omitempty"`
+	Valid         bool     `json:"valid"` // 文件完整、表结构可用且没有数据缺失的对象
+}
+
+// addProblem 记录问题，超过上限的只计数不保留
+func (r *ValidationReport) addProblem(format string, args ...any) {
+	if len(r.Problems) < maxValidationProblems {
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// ValidateSnapshot 校验快照：SQLite文件完整性、表结构版本不高于当前程序支持的版本，
+// 以及快照中每个当前对象在其存储节点上至少有一个副本（只检查存在，不读取数据）
+// 表结构较旧的快照与恢复后服务启动时一样先迁移到最新版本（在临时副本上进行，不修改快照），迁移失败时校验不通过
+// 版本历史中的旧版本不做检查
+func ValidateSnapshot(path string, nodes map[string]types.StorageNode) (*ValidationReport, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("snapshot not found: %v", err)
+	}
+
+	db, err := metadata.OpenDatabase(metadata.DriverSQLite, "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	report := &ValidationReport{Snapshot: path}
+	if err := db.CheckIntegrity(); err != nil {
+		report.addProblem("%v", err)
+		return report, nil
+	}
+
+	report.SchemaVersion, err = db.SchemaVersion()
+	if err != nil {
+		report.addProblem("%v", err)
+		return report, nil
+	}
+	if report.SchemaVersion > metadata.LatestSchemaVersion() {
+		report.addProblem("schema version %d is newer than supported version %d", report.SchemaVersion, metadata.LatestSchemaVersion())
+		return report, nil
+	}
+
+	store := db
+	if report.SchemaVersion < metadata.LatestSchemaVersion() {
+		migrated, cleanup, err := migrateCopy(path)
+		if err != nil {
+			report.addProblem("failed to migrate schema version %d to %d: %v", report.SchemaVersion, metadata.LatestSchemaVersion(), err)
+			return report, nil
+		}
+		defer cleanup()
+		store = migrated
+	}
+
+	err = metadata.NewMetaService(store).WalkMetadata(types.CatalogFilter{}, func(entry *types.MetadataEntry) error {
+		report.Objects++
+		checkReplicas(report, entry, nodes)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot metadata: %v", err)
+	}
+
+	report.Valid = report.Missing == 0
+	return report, nil
+}
+
+// migrateCopy 将快照复制到临时文件并迁移到最新表结构，返回迁移后的数据库和清理函数
+func migrateCopy(path string) (*metadata.DatabaseManager, func(), error) {
+	dir, err := os.MkdirTemp("", "snapshot-validate-")
+	if err != nil {
+		return nil, nil, err
+	}
+	copyPath := filepath.Join(dir, filepath.Base(path))
+	if err := copyFile(path, copyPath); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	db, err := metadata.NewDatabaseManager(metadata.DriverSQLite, copyPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// checkReplicas 检查对象在其存储节点上的副本
+func checkReplicas(report *ValidationReport, entry *types.MetadataEntry, nodes map[string]types.StorageNode) {
+	storageKey := types.StorageKey(entry.Key, entry.VersionID)
+
+	present := 0
+	var absent []string
+	for _, nodeID := range entry.StorageNodes {
+		node, ok := nodes[nodeID]
+		if !ok {
+			absent = append(absent, nodeID+" (not configured)")
+			continue
+		}
+		if _, err := node.Stat(storageKey); err != nil {
+			absent = append(absent, nodeID)
+			continue
+		}
+		present++
+	}
+
+	switch {
+	case present == 0:
+		report.Missing++
+		report.addProblem("%s: no replica found on %v", entry.Key, absent)
+	case len(absent) > 0:
+		report.Degraded++
+		report.addProblem("%s: replicas missing on %v", entry.Key, absent)
+	}
+}
+
+// Restore 用快照替换dbPath处的SQLite数据库，必须在服务停止时执行
+// 替换前将当前数据库备份到同目录下的 <文件名>.pre-restore-<时间>，返回该路径（当前数据库不存在时为空）
+func Restore(snapshotPath, dbPath string) (string, error) {
+	var previousPath string
+	if _, err := os.Stat(dbPath); err == nil {
+		previousPath = dbPath + ".pre-restore-" + time.Now().UTC().Format("20060102-150405")
+		current, err := metadata.OpenDatabase(metadata.DriverSQLite, dbPath)
+		if err != nil {
+			return "", err
+		}
+		err = current.BackupTo(previousPath)
+		current.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to save current database: %v", err)
+		}
+	}
+
+	tempPath := dbPath + ".restore.tmp"
+	if err := copyFile(snapshotPath, tempPath); err != nil {
+		os.Remove(tempPath)
+		return previousPath, fmt.Errorf("failed to copy snapshot: %v", err)
+	}
+
+	// 旧数据库的WAL和共享内存文件不属于快照，必须在替换前删除
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			os.Remove(tempPath)
+			return previousPath, fmt.Errorf("failed to remove %s: %v", dbPath+suffix, err)
+		}
+	}
+
+	if err := os.Rename(tempPath, dbPath); err != nil {
+		os.Remove(tempPath)
+		return previousPath, fmt.Errorf("failed to swap in snapshot: %v", err)
+	}
+	return previousPath, nil
+}
+
+// copyFile 复制文件并落盘
+func copyFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	target, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+	if err := target.Sync(); err != nil {
+		target.Close()
+		return err
+	}
+	return target.Close()
+}
@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+)
+
+func TestValidateSnapshotWithOlderSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	db, err := metadata.NewDatabaseManager(metadata.DriverSQLite, path)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	entry := &types.MetadataEntry{ID: "id", Key: "bucket/object", Size: 4, StorageNodes: []string{"stg1"}}
+	if err := db.SaveMetadata(entry); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	// 回滚到添加对象标签之前的表结构
+	if _, err := db.Rollback(1); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	db.Close()
+
+	node := storage.NewMemoryStorageNode("stg1")
+	if err := node.Write(&types.FileObject{Key: "bucket/object", Data: []byte("data"), Size: 4}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	report, err := ValidateSnapshot(path, map[string]types.StorageNode{"stg1": node})
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !report.Valid || report.Objects != 1 || report.SchemaVersion != metadata.LatestSchemaVersion()-1 {
+		t.Fatalf("report = %+v, want a valid snapshot with one object at the older schema version", report)
+	}
+
+	// 迁移在临时副本上进行，快照本身不变
+	db, err = metadata.OpenDatabase(metadata.DriverSQLite, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	if version, err := db.SchemaVersion(); err != nil || version != report.SchemaVersion {
+		t.Fatalf("snapshot schema version = %d, %v, want %d", version, err, report.SchemaVersion)
+	}
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+
+	"mock-storage/internal/types"
 )
 
 // Config 系统配置
@@ -13,63 +16,185 @@ type Config struct {
 	} `json:"server"`
 
 	Storage struct {
-		DataDir string `json:"data_dir"`
-		Nodes   []struct {
-			ID   string `json:"id"`
-			Path string `json:"path"`
-		} `json:"nodes"`
+		Driver  string              `json:"driver"` // file（默认）或 memory
+		DataDir string              `json:"data_dir"`
+		Nodes   []NodeConfig        `json:"nodes"`
+		Classes map[string][]string `json:"classes"` // 存储类别 -> 节点ID列表，为空时所有节点属于STANDARD
 	} `json:"storage"`
 
 	Database struct {
-		Driver string `json:"driver"`
+		Driver string `json:"driver"` // sqlite3、postgres 或 memory
 		DSN    string `json:"dsn"`
 	} `json:"database"`
 
 	Queue struct {
-		Size int `json:"size"`
+		Size         int    `json:"size"`
+		Driver       string `json:"driver"`        // sqlite3（持久化）或 memory，为空时内存模式使用memory，否则使用sqlite3
+		Path         string `json:"path"`          // sqlite3队列的数据库文件
+		LeaseTimeout int    `json:"lease_timeout"` // 任务租约时长（秒），处理期间自动续租，工作节点崩溃后租约到期的任务重新被领取
+		Retention    int    `json:"retention"`     // 成功任务保留的时间（秒），超过后删除；死信队列中的任务不会自动删除
+		// Retry 失败任务的重试策略，键为任务类型，"default"用于未单独配置的类型
+		Retry map[string]RetryConfig `json:"retry"`
+		// Handlers 按任务类型覆盖处理器的并发上限和超时
+		Handlers map[string]HandlerConfig `json:"handlers"`
 	} `json:"queue"`
+
+	Encryption struct {
+		KeyFile     string `json:"key_file"`     // SSE-S3主密钥文件，不存在时自动生成
+		DefaultMode string `json:"default_mode"` // 未指定加密头时的默认模式：""或"SSE-S3"
+	} `json:"encryption"`
+
+	Cache struct {
+		Enabled       bool  `json:"enabled"`
+		MaxBytes      int64 `json:"max_bytes"`       // 缓存总容量（字节）
+		MaxObjectSize int64 `json:"max_object_size"` // 可缓存的单个对象最大大小（字节）
+	} `json:"cache"`
+
+	ThirdParty struct {
+		NegativeCacheTTL int                     `json:"negative_cache_ttl"` // 源站404结果的缓存时间（秒）
+		Default          *OriginConfig           `json:"default,omitempty"`  // 未单独配置的bucket使用的源站
+		Buckets          map[string]OriginConfig `json:"buckets"`            // 按bucket配置的源站
+	} `json:"third_party"`
+
+	HedgedRead struct {
+		Enabled    bool `json:"enabled"`
+		Delay      int  `json:"delay"`       // 发起对冲请求前的等待时间（毫秒）
+		Adaptive   bool `json:"adaptive"`    // 使用最近读取延迟的p95作为等待时间，样本不足时使用delay
+		MinSamples int  `json:"min_samples"` // adaptive模式生效所需的最少样本数
+	} `json:"hedged_read"`
+
+	FaultInjection struct {
+		Enabled bool                         `json:"enabled"` // 启用后所有节点被故障注入装饰器包装，可通过管理API调整
+		Nodes   map[string]types.FaultConfig `json:"nodes"`   // 节点ID -> 初始故障配置
+	} `json:"fault_injection"`
+
+	WAL struct {
+		Enabled     bool   `json:"enabled"`      // 启用后上传、删除和副本修复先写入操作日志，启动时恢复未完成的操作
+		Dir         string `json:"dir"`          // 日志段文件目录
+		SegmentSize int64  `json:"segment_size"` // 单个段文件的大小上限（字节），超过后切换到新段
+	} `json:"wal"`
+
+	Backup struct {
+		Enabled   bool   `json:"enabled"`   // 启用后定期生成元数据快照（仅SQLite）
+		Dir       string `json:"dir"`       // 快照目录
+		Interval  int    `json:"interval"`  // 备份间隔（秒）
+		Retention int    `json:"retention"` // 保留的快照数量，超出后删除最旧的
+	} `json:"backup"`
+
+	Tiering struct {
+		Enabled  bool `json:"enabled"`
+		Interval int  `json:"interval"` // 扫描间隔（秒）
+		Rules    []struct {
+			From      string            `json:"from"`
+			To        string            `json:"to"`
+			Prefix    string            `json:"prefix"`    // 可选，只匹配该前缀（bucket/key）
+			Tags      map[string]string `json:"tags"`      // 可选，只匹配带有全部这些标签的对象
+			Condition string            `json:"condition"` // age（创建时间）或 idle（最后访问时间）
+			Days      int               `json:"days"`
+		} `json:"rules"`
+	} `json:"tiering"`
+
+	Scheduler struct {
+		Enabled bool `json:"enabled"` // 启用后按时间表将定时任务加入任务队列
+		Jobs    []struct {
+			Name     string         `json:"name"`
+			Schedule string         `json:"schedule"` // cron表达式（分 时 日 月 周，本地时区）、@daily等简写或 @every <间隔>
+			Task     string         `json:"task"`     // 加入队列的任务类型，如 scrub、cleanup
+			Data     map[string]any `json:"data"`     // 任务数据
+		} `json:"jobs"`
+	} `json:"scheduler"`
+}
+
+// NodeConfig 存储节点配置
+type NodeConfig struct {
+	ID       string `json:"id"`
+	Path     string `json:"path,omitempty"`     // 本地节点的数据目录
+	Endpoint string `json:"endpoint,omitempty"` // 远程节点地址（storagenode进程），设置后忽略path
+	Token    string `json:"token,omitempty"`    // 远程节点令牌
+	Timeout  int    `json:"timeout,omitempty"`  // 远程节点请求超时（毫秒）
+}
+
+// RetryConfig 任务重试策略，未设置（为0）的字段使用默认值
+type RetryConfig struct {
+	MaxAttempts    int     `json:"max_attempts"`    // 最多处理次数（包括第一次），用尽后进入死信队列
+	InitialBackoff int     `json:"initial_backoff"` // 第一次重试前的等待时间（毫秒）
+	MaxBackoff     int     `json:"max_backoff"`     // 等待时间上限（毫秒）
+	Multiplier     float64 `json:"multiplier"`      // 每次重试等待时间的增长倍数
+	Jitter         float64 `json:"jitter"`          // 随机抖动比例（0-1）
+}
+
+// HandlerConfig 任务处理器选项，未设置（为0）的字段使用处理器注册时的值
+type HandlerConfig struct {
+	Concurrency int `json:"concurrency"` // 同时处理该类型任务的最大数量
+	Timeout     int `json:"timeout"`     // 单次处理的超时时间（秒）
+}
+
+// OriginConfig 回源源站配置
+type OriginConfig struct {
+	Type       string `json:"type"`     // http、s3 或 mock
+	Endpoint   string `json:"endpoint"` // http: GET <endpoint>/<key>；s3: 服务地址
+	Bucket     string `json:"bucket,omitempty"`
+	Region     string `json:"region,omitempty"`
+	AccessKey  string `json:"access_key,omitempty"`
+	SecretKey  string `json:"secret_key,omitempty"`
+	Timeout    int    `json:"timeout"`     // 请求超时（毫秒）
+	MaxRetries int    `json:"max_retries"` // 失败重试次数
+	WriteMode  string `json:"write_mode"`  // ""（只读）、write-through 或 write-back
 }
 
 // Default 返回默认配置
 func Default() *Config {
-	return &Config{
-		Server: struct {
-			Port string `json:"port"`
-			Host string `json:"host"`
-		}{
-			Port: "8080",
-			Host: "localhost",
-		},
-		Storage: struct {
-			DataDir string `json:"data_dir"`
-			Nodes   []struct {
-				ID   string `json:"id"`
-				Path string `json:"path"`
-			} `json:"nodes"`
-		}{
-			DataDir: "./data",
-			Nodes: []struct {
-				ID   string `json:"id"`
-				Path string `json:"path"`
-			}{
-				{ID: "stg1", Path: "./data/stg1"},
-				{ID: "stg2", Path: "./data/stg2"},
-				{ID: "stg3", Path: "./data/stg3"},
-			},
-		},
-		Database: struct {
-			Driver string `json:"driver"`
-			DSN    string `json:"dsn"`
-		}{
-			Driver: "sqlite3",
-			DSN:    "./data/metadata.db",
-		},
-		Queue: struct {
-			Size int `json:"size"`
-		}{
-			Size: 1000,
-		},
+	config := &Config{}
+
+	config.Server.Port = "8080"
+	config.Server.Host = "localhost"
+
+	config.Storage.Driver = "file"
+	config.Storage.DataDir = "./data"
+	config.Storage.Nodes = []NodeConfig{
+		{ID: "stg1", Path: "./data/stg1"},
+		{ID: "stg2", Path: "./data/stg2"},
+		{ID: "stg3", Path: "./data/stg3"},
 	}
+
+	config.Database.Driver = "sqlite3"
+	config.Database.DSN = "./data/metadata.db"
+
+	config.Queue.Size = 1000
+	config.Queue.Driver = "sqlite3"
+	config.Queue.Path = "./data/queue.db"
+	config.Queue.LeaseTimeout = 300
+	config.Queue.Retention = 86400
+
+	config.Encryption.KeyFile = "./data/master.key"
+
+	config.Cache.Enabled = true
+	config.Cache.MaxBytes = 64 << 20
+	config.Cache.MaxObjectSize = 4 << 20
+
+	config.ThirdParty.NegativeCacheTTL = 60
+	config.ThirdParty.Buckets = map[string]OriginConfig{}
+
+	config.HedgedRead.Delay = 50
+	config.HedgedRead.MinSamples = 20
+
+	config.FaultInjection.Nodes = map[string]types.FaultConfig{}
+
+	config.WAL.Dir = "./data/wal"
+	config.WAL.SegmentSize = 16 << 20
+
+	config.Backup.Dir = "./data/backups"
+	config.Backup.Interval = 86400
+	config.Backup.Retention = 7
+
+	config.Tiering.Interval = 3600
+
+	return config
+}
+
+// IsEphemeral 存储节点和元数据都在内存中时，服务不读写磁盘
+func (c *Config) IsEphemeral() bool {
+	return c.Storage.Driver == "memory" && c.Database.Driver == "memory"
 }
 
 // Load 从文件加载配置
@@ -92,9 +217,23 @@ func Load(filename string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
+// validate 校验无法在使用时安全处理的配置项
+func (c *Config) validate() error {
+	for nodeID, faults := range c.FaultInjection.Nodes {
+		if err := faults.Validate(); err != nil {
+			return fmt.Errorf("fault_injection.nodes.%s: %v", nodeID, err)
+		}
+	}
+	return nil
+}
+
 // Save 保存配置到文件
 func (c *Config) Save(filename string) error {
 	file, err := os.Create(filename)
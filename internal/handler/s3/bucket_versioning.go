@@ -0,0 +1,199 @@
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"mock-storage/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// S3版本控制相关请求头
+const (
+	headerVersionID    = "x-amz-version-id"
+	headerDeleteMarker = "x-amz-delete-marker"
+
+	headerCopySourceVersionID = "x-amz-copy-source-version-id"
+)
+
+// setVersionHeaders 返回对象的版本ID，删除标记额外返回x-amz-delete-marker
+func setVersionHeaders(c *gin.Context, entry *types.MetadataEntry) {
+	if entry.VersionID != "" {
+		c.Header(headerVersionID, entry.VersionID)
+	}
+	if entry.DeleteMarker {
+		c.Header(headerDeleteMarker, "true")
+	}
+}
+
+// respondDeleteMarker 对象没有当前版本且最新版本为删除标记时返回404，不再回源
+// 返回true表示已写入响应
+func (h *Handler) respondDeleteMarker(c *gin.Context, objectKey string) bool {
+	marker := h.service.GetLatestDeleteMarker(objectKey)
+	if marker == nil {
+		return false
+	}
+
+	setVersionHeaders(c, marker)
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": fmt.Sprintf("Object not found: %s is a delete marker", objectKey),
+	})
+	return true
+}
+
+// getObjectVersion 获取GET/HEAD请求中versionId指定的版本，版本为删除标记时返回405
+// 返回false表示已写入响应
+func (h *Handler) getObjectVersion(c *gin.Context, objectKey, versionID string) (*types.MetadataEntry, bool) {
+	metadata, err := h.service.GetObjectVersion(objectKey, versionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Version not found: %v", err),
+		})
+		return nil, false
+	}
+
+	if metadata.DeleteMarker {
+		setVersionHeaders(c, metadata)
+		c.Header("Allow", "DELETE")
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"error": "The specified method is not allowed against a delete marker",
+		})
+		return nil, false
+	}
+	return metadata, true
+}
+
+// PutBucket 处理PUT bucket请求，目前只支持?versioning设置版本控制
+func (h *Handler) PutBucket(c *gin.Context) {
+	if _, ok := c.GetQuery("versioning"); ok {
+		h.PutBucketVersioning(c)
+		return
+	}
+
+	c.JSON(http.StatusNotImplemented, gin.H{
+		"error": "Unsupported bucket operation",
+	})
+}
+
+// PutBucketVersioning 处理PUT /:bucket?versioning请求，请求体为VersioningConfiguration
+// 启用后不能恢复为未启用状态，只能暂停
+func (h *Handler) PutBucketVersioning(c *gin.Context) {
+	bucket := c.Param("bucket")
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read request body",
+		})
+		return
+	}
+
+	var config types.VersioningConfiguration
+	if err := xml.Unmarshal(body, &config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Malformed versioning configuration: %v", err),
+		})
+		return
+	}
+
+	if config.Status != types.VersioningEnabled && config.Status != types.VersioningSuspended {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid versioning status: %q (must be Enabled or Suspended)", config.Status),
+		})
+		return
+	}
+
+	err = h.service.SetBucketVersioning(bucket, config.Status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to set bucket versioning: %v", err),
+		})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// GetBucketVersioning 处理GET /:bucket?versioning请求，从未启用过时Status为空
+func (h *Handler) GetBucketVersioning(c *gin.Context) {
+	bucket := c.Param("bucket")
+
+	status, err := h.service.GetBucketVersioning(bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to get bucket versioning: %v", err),
+		})
+		return
+	}
+
+	c.XML(http.StatusOK, types.VersioningConfiguration{Status: status})
+}
+
+// ListObjectVersions 处理GET /:bucket?versions请求，列出对象的所有版本和删除标记
+func (h *Handler) ListObjectVersions(c *gin.Context) {
+	bucket := c.Param("bucket")
+	prefix := c.Query("prefix")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("max-keys", "1000"))
+	if err != nil || limit <= 0 {
+		limit = 1000
+	}
+
+	bucketPrefix := bucket + "/"
+	entries, err := h.service.ListVersions(bucketPrefix+prefix, limit+1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list object versions",
+		})
+		return
+	}
+
+	truncated := len(entries) > limit
+	if truncated {
+		entries = entries[:limit]
+	}
+
+	result := types.ListVersionsResult{
+		Name:        bucket,
+		Prefix:      prefix,
+		MaxKeys:     limit,
+		IsTruncated: truncated,
+	}
+
+	// 版本按key正序、创建时间倒序排列，每个key的第一个版本为最新版本
+	previousKey := ""
+	for _, entry := range entries {
+		isLatest := entry.Key != previousKey
+		previousKey = entry.Key
+
+		objectKey := h.extractObjectKey(entry.Key, bucketPrefix)
+		versionID := types.NormalizeVersionID(entry.VersionID)
+		lastModified := entry.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z")
+
+		if entry.DeleteMarker {
+			result.DeleteMarkers = append(result.DeleteMarkers, types.DeleteMarkerEntry{
+				Key:          objectKey,
+				VersionID:    versionID,
+				IsLatest:     isLatest,
+				LastModified: lastModified,
+			})
+			continue
+		}
+
+		result.Versions = append(result.Versions, types.ObjectVersion{
+			Key:          objectKey,
+			VersionID:    versionID,
+			IsLatest:     isLatest,
+			LastModified: lastModified,
+			ETag:         objectETag(entry),
+			Size:         entry.Size,
+			StorageClass: entry.StorageClass,
+		})
+	}
+
+	c.XML(http.StatusOK, result)
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 元数据导出的HTTP trailer，响应体开始发送后才能确定导出结果
+const (
+	trailerExportCount = "X-Export-Count"
+	trailerExportError = "X-Export-Error"
+)
+
+// ExportMetadataAPI 处理元数据导出请求，以NDJSON流式返回符合条件的所有对象元数据
+// 导出的条数和中途发生的错误通过trailer返回
+func (h *Handler) ExportMetadataAPI(c *gin.Context) {
+	filter, err := parseCatalogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("metadata-%s.ndjson", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Trailer", trailerExportCount+", "+trailerExportError)
+	c.Status(http.StatusOK)
+
+	exported, err := h.service.ExportMetadata(c.Writer, filter)
+	c.Writer.Header().Set(trailerExportCount, strconv.Itoa(exported))
+	if err != nil {
+		fmt.Printf("[META] Export failed after %d entries: %v\n", exported, err)
+		c.Writer.Header().Set(trailerExportError, err.Error())
+		return
+	}
+	fmt.Printf("[META] Exported %d metadata entries\n", exported)
+}
+
+// ImportMetadataAPI 处理元数据导入请求，请求体为NDJSON，返回导入结果
+// 参数conflict指定key已存在时的策略，dry_run=true时只检查不写入
+func (h *Handler) ImportMetadataAPI(c *gin.Context) {
+	filter, err := parseCatalogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options := types.ImportOptions{
+		Filter:   filter,
+		Conflict: c.Query("conflict"),
+	}
+	if dryRun := c.Query("dry_run"); dryRun != "" {
+		if options.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid dry_run %q", dryRun)})
+			return
+		}
+	}
+
+	report, err := h.service.ImportMetadata(c.Request.Body, options)
+	if errors.Is(err, metadata.ErrInvalidImport) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		// 读取请求体中途失败，返回已处理部分的结果
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseCatalogFilter 解析导出和导入的过滤参数
+func parseCatalogFilter(c *gin.Context) (types.CatalogFilter, error) {
+	filter := types.CatalogFilter{
+		Bucket: c.Query("bucket"),
+		Prefix: c.Query("prefix"),
+	}
+
+	var err error
+	if filter.UpdatedAfter, err = parseTimeParam(c, "updated_after"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedBefore, err = parseTimeParam(c, "updated_before"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
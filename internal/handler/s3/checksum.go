@@ -0,0 +1,90 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+
+	"mock-storage/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// S3附加校验和相关请求头
+const (
+	headerChecksumAlgorithm    = "x-amz-checksum-algorithm"
+	headerSDKChecksumAlgorithm = "x-amz-sdk-checksum-algorithm"
+	headerChecksumMode         = "x-amz-checksum-mode"
+	headerChecksumPrefix       = "x-amz-checksum-"
+
+	checksumModeEnabled = "ENABLED"
+)
+
+// errBadDigest 客户端提供的校验和与数据不一致
+type errBadDigest struct {
+	algorithm string
+}
+
+func (e *errBadDigest) Error() string {
+	return fmt.Sprintf("the %s checksum you specified did not match the calculated checksum", e.algorithm)
+}
+
+// parseChecksums 根据请求头计算并校验对象的附加校验和
+// x-amz-checksum-algorithm 指定的算法会被计算并保存，x-amz-checksum-{算法} 提供的值会被校验
+func parseChecksums(c *gin.Context, data []byte) (map[string]string, error) {
+	checksums := make(map[string]string)
+
+	algorithm := c.GetHeader(headerChecksumAlgorithm)
+	if algorithm == "" {
+		algorithm = c.GetHeader(headerSDKChecksumAlgorithm)
+	}
+	if algorithm != "" {
+		algorithm = strings.ToUpper(algorithm)
+		if !utils.IsChecksumAlgorithm(algorithm) {
+			return nil, fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+		}
+
+		value, err := utils.CalculateChecksum(algorithm, data)
+		if err != nil {
+			return nil, err
+		}
+		checksums[algorithm] = value
+	}
+
+	for _, supported := range utils.ChecksumAlgorithms {
+		expected := c.GetHeader(headerChecksumPrefix + strings.ToLower(supported))
+		if expected == "" {
+			continue
+		}
+
+		value, ok := checksums[supported]
+		if !ok {
+			calculated, err := utils.CalculateChecksum(supported, data)
+			if err != nil {
+				return nil, err
+			}
+			value = calculated
+		}
+
+		if value != expected {
+			return nil, &errBadDigest{algorithm: supported}
+		}
+		checksums[supported] = value
+	}
+
+	if len(checksums) == 0 {
+		return nil, nil
+	}
+	return checksums, nil
+}
+
+// checksumRequested 检查GET/HEAD请求是否要求返回校验和
+func checksumRequested(c *gin.Context) bool {
+	return strings.EqualFold(c.GetHeader(headerChecksumMode), checksumModeEnabled)
+}
+
+// setChecksumHeaders 设置响应中的校验和头
+func setChecksumHeaders(c *gin.Context, checksums map[string]string) {
+	for algorithm, value := range checksums {
+		c.Header(headerChecksumPrefix+strings.ToLower(algorithm), value)
+	}
+}
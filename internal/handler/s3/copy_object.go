@@ -0,0 +1,248 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mock-storage/internal/types"
+	"mock-storage/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// S3复制对象相关请求头
+const (
+	headerCopySource        = "x-amz-copy-source"
+	headerMetadataDirective = "x-amz-metadata-directive"
+
+	// x-amz-metadata-directive与x-amz-tagging-directive的取值
+	metadataDirectiveCopy    = "COPY"
+	metadataDirectiveReplace = "REPLACE"
+)
+
+// parseCopySource 解析x-amz-copy-source（/bucket/key或bucket/key，可URL编码，可带?versionId=），
+// 返回源对象的完整key和版本ID
+func parseCopySource(copySource string) (string, string, error) {
+	path, query, _ := strings.Cut(copySource, "?")
+
+	versionID := ""
+	if query != "" {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid copy source query: %v", err)
+		}
+		for name := range values {
+			if name != "versionId" {
+				return "", "", fmt.Errorf("unsupported copy source query parameter: %s", name)
+			}
+		}
+		versionID = values.Get("versionId")
+		if versionID == "" {
+			return "", "", fmt.Errorf("copy source version ID cannot be empty")
+		}
+	}
+
+	source, err := url.PathUnescape(strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid copy source encoding: %v", err)
+	}
+
+	bucket, key := splitObjectKey(source)
+	if bucket == "" || key == "" {
+		return "", "", fmt.Errorf("copy source must be in the form bucket/key")
+	}
+	return source, versionID, nil
+}
+
+// CopyObject 处理复制对象请求（携带x-amz-copy-source的PUT请求）
+// x-amz-metadata-directive为COPY（默认）时保留源对象的内容类型、标准头和用户元数据，
+// 为REPLACE时使用本次请求中的值；x-amz-tagging-directive同样控制标签的复制
+func (h *Handler) CopyObject(c *gin.Context) {
+	bucket := c.Param("bucket")
+	key := c.Param("key")
+	objectKey := h.buildObjectKey(bucket, key)
+	if h.respondInvalidKey(c, objectKey) {
+		return
+	}
+
+	sourceKey, sourceVersionID, err := parseCopySource(c.GetHeader(headerCopySource))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid copy source: %v", err),
+		})
+		return
+	}
+
+	directive := strings.ToUpper(c.GetHeader(headerMetadataDirective))
+	if directive == "" {
+		directive = metadataDirectiveCopy
+	}
+	if directive != metadataDirectiveCopy && directive != metadataDirectiveReplace {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid metadata directive: %s", directive),
+		})
+		return
+	}
+
+	taggingDirective := strings.ToUpper(c.GetHeader(headerTaggingDirective))
+	if taggingDirective == "" {
+		taggingDirective = metadataDirectiveCopy
+	}
+	if taggingDirective != metadataDirectiveCopy && taggingDirective != metadataDirectiveReplace {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid tagging directive: %s", taggingDirective),
+		})
+		return
+	}
+	tags, err := parseTaggingHeader(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("InvalidTag: %v", err),
+		})
+		return
+	}
+
+	// 目标对象的加密参数与源对象的SSE-C密钥
+	sse, err := parseSSEHeaders(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid encryption headers: %v", err),
+		})
+		return
+	}
+	sourceSSE, err := parseCopySourceSSEHeaders(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid copy source encryption headers: %v", err),
+		})
+		return
+	}
+
+	storageClass := c.GetHeader(headerStorageClass)
+	if storageClass == "" {
+		storageClass = types.StorageClassStandard
+	}
+	if !h.service.HasStorageClass(storageClass) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid storage class: %s", storageClass),
+		})
+		return
+	}
+
+	var source *types.MetadataEntry
+	if sourceVersionID != "" {
+		source, err = h.service.GetObjectVersion(sourceKey, sourceVersionID)
+	} else {
+		source, err = h.service.GetMetadata(sourceKey)
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Copy source not found: %v", err),
+		})
+		return
+	}
+	if source.DeleteMarker {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "The source of a copy request may not specifically refer to a delete marker by version id",
+		})
+		return
+	}
+
+	// 与S3一致，复制到自身时必须修改元数据、存储类别或加密方式
+	if sourceKey == objectKey && sourceVersionID == "" && directive == metadataDirectiveCopy &&
+		taggingDirective == metadataDirectiveCopy && storageClass == source.StorageClass && sse.Mode == types.EncryptionNone {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "This copy request is illegal because it is trying to copy an object to itself " +
+				"without changing the object's metadata, storage class, or encryption attributes",
+		})
+		return
+	}
+
+	if !checkCustomerKey(c, source, sourceSSE) {
+		return
+	}
+
+	sourceObj, err := h.service.ReadObject(source, sourceSSE.CustomerKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to read copy source: %v", err),
+		})
+		return
+	}
+
+	checksums, err := parseChecksums(c, sourceObj.Data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid checksum headers: %v", err),
+		})
+		return
+	}
+	if checksums == nil {
+		checksums = source.Checksums
+	}
+
+	fileObj := &types.FileObject{
+		ID:        uuid.New().String(),
+		Key:       objectKey,
+		Size:      sourceObj.Size,
+		Data:      sourceObj.Data,
+		MD5Hash:   utils.CalculateMD5(sourceObj.Data),
+		CreatedAt: time.Now(),
+
+		StorageClass: storageClass,
+		Checksums:    checksums,
+	}
+
+	if directive == metadataDirectiveReplace {
+		headers, err := parseObjectHeaders(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid metadata headers: %v", err),
+			})
+			return
+		}
+
+		fileObj.ContentType = c.GetHeader("Content-Type")
+		if fileObj.ContentType == "" {
+			fileObj.ContentType = "application/octet-stream"
+		}
+		fileObj.ObjectHeaders = headers
+	} else {
+		fileObj.ContentType = source.ContentType
+		fileObj.ObjectHeaders = source.ObjectHeaders
+	}
+	if taggingDirective == metadataDirectiveCopy {
+		tags = source.Tags
+	}
+	fileObj.Tags = tags
+	h.applyEncryption(fileObj, sse)
+
+	err = h.service.ExecuteUploadFlow(fileObj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Copy failed: %v", err),
+		})
+		return
+	}
+
+	etag := `"` + fileObj.MD5Hash + `"`
+	c.Header("ETag", etag)
+	setEncryptionHeaders(c, fileObj.Encryption, fileObj.EncryptionKeyMD5)
+	setStorageClassHeader(c, fileObj.StorageClass)
+	if fileObj.VersionID != "" {
+		c.Header(headerVersionID, fileObj.VersionID)
+	}
+	if source.VersionID != "" {
+		c.Header(headerCopySourceVersionID, source.VersionID)
+	}
+
+	// 构建S3兼容的响应
+	c.XML(http.StatusOK, gin.H{
+		"ETag":         etag,
+		"LastModified": fileObj.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+	})
+}
@@ -1,6 +1,7 @@
 package s3
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -8,27 +9,68 @@ import (
 )
 
 // DeleteObject 处理DELETE对象请求
+// 版本控制bucket中不带versionId时写入删除标记，带versionId时永久删除该版本
 func (h *Handler) DeleteObject(c *gin.Context) {
+	if _, ok := c.GetQuery("tagging"); ok {
+		h.DeleteObjectTagging(c)
+		return
+	}
+
 	bucket := c.Param("bucket")
 	key := c.Param("key")
 
 	// 构建对象key（包含bucket前缀）
 	objectKey := h.buildObjectKey(bucket, key)
+	if h.respondInvalidKey(c, objectKey) {
+		return
+	}
+
+	// 永久删除指定版本，源站不保存版本，不传播到源站
+	if versionID, ok := c.GetQuery("versionId"); ok {
+		deleted, err := h.service.DeleteObjectVersion(objectKey, versionID)
+		if errors.Is(err, errObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": fmt.Sprintf("Version not found: %v", err),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("Failed to delete version: %v", err),
+			})
+			return
+		}
+
+		setVersionHeaders(c, deleted)
+		c.Status(http.StatusNoContent)
+		return
+	}
 
-	// 从元数据服务删除
-	err := h.service.DeleteMetadata(objectKey)
+	// 将删除传播到源站（write-through/write-back模式）
+	err := h.service.PropagateDelete(objectKey)
 	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("Failed to delete from origin: %v", err),
+		})
+		return
+	}
+
+	// 删除元数据并异步删除存储节点中的文件，版本控制bucket中写入删除标记
+	marker, err := h.service.DeleteObject(objectKey)
+	if errors.Is(err, errObjectNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": fmt.Sprintf("Object not found: %v", err),
 		})
 		return
 	}
-
-	// 异步删除存储节点中的文件
-	err = h.service.EnqueueDeleteTask(objectKey)
 	if err != nil {
-		fmt.Printf("Warning: failed to enqueue delete task: %v\n", err)
-		// 不返回错误，因为元数据已删除
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Delete failed, changes rolled back: %v", err),
+		})
+		return
+	}
+	if marker != nil {
+		setVersionHeaders(c, marker)
 	}
 
 	// 返回成功响应
@@ -38,21 +80,32 @@ func (h *Handler) DeleteObject(c *gin.Context) {
 // DeleteObjectAPI 处理API DELETE对象请求
 func (h *Handler) DeleteObjectAPI(c *gin.Context) {
 	key := c.Param("key")
+	if h.respondInvalidKey(c, key) {
+		return
+	}
 
-	err := h.service.DeleteMetadata(key)
+	err := h.service.PropagateDelete(key)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 异步删除存储节点中的文件
-	err = h.service.EnqueueDeleteTask(key)
+	marker, err := h.service.DeleteObject(key)
+	if errors.Is(err, errObjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
 	if err != nil {
-		fmt.Printf("Warning: failed to enqueue delete task: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success": true,
 		"message": "Object deleted successfully",
-	})
-}
\ No newline at end of file
+	}
+	if marker != nil {
+		response["delete_marker_version_id"] = marker.VersionID
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// S3服务端加密相关请求头
+const (
+	headerSSE                  = "x-amz-server-side-encryption"
+	headerSSECustomerAlgorithm = "x-amz-server-side-encryption-customer-algorithm"
+	headerSSECustomerKey       = "x-amz-server-side-encryption-customer-key"
+	headerSSECustomerKeyMD5    = "x-amz-server-side-encryption-customer-key-MD5"
+
+	// 复制请求中用于解密源对象的SSE-C密钥
+	headerCopySourceSSECustomerAlgorithm = "x-amz-copy-source-server-side-encryption-customer-algorithm"
+	headerCopySourceSSECustomerKey       = "x-amz-copy-source-server-side-encryption-customer-key"
+	headerCopySourceSSECustomerKeyMD5    = "x-amz-copy-source-server-side-encryption-customer-key-MD5"
+
+	sseAlgorithmAES256 = "AES256"
+)
+
+// sseRequest 从请求头解析出的加密参数
+type sseRequest struct {
+	Mode        string
+	CustomerKey []byte
+	KeyMD5      string
+}
+
+// parseSSEHeaders 解析服务端加密请求头
+func parseSSEHeaders(c *gin.Context) (*sseRequest, error) {
+	req, err := parseCustomerKeyHeaders(c, headerSSECustomerAlgorithm, headerSSECustomerKey, headerSSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	if sse := c.GetHeader(headerSSE); sse != "" {
+		if sse != sseAlgorithmAES256 {
+			return nil, fmt.Errorf("unsupported server-side encryption: %q", sse)
+		}
+		if req.Mode == types.EncryptionSSEC {
+			return nil, fmt.Errorf("server-side encryption and customer-provided keys cannot be combined")
+		}
+		req.Mode = types.EncryptionSSES3
+	}
+
+	return req, nil
+}
+
+// parseCopySourceSSEHeaders 解析复制请求中源对象的SSE-C密钥
+func parseCopySourceSSEHeaders(c *gin.Context) (*sseRequest, error) {
+	return parseCustomerKeyHeaders(c, headerCopySourceSSECustomerAlgorithm,
+		headerCopySourceSSECustomerKey, headerCopySourceSSECustomerKeyMD5)
+}
+
+// parseCustomerKeyHeaders 解析SSE-C客户密钥请求头，未提供时返回空的加密参数
+func parseCustomerKeyHeaders(c *gin.Context, algorithmHeader, keyHeader, keyMD5Header string) (*sseRequest, error) {
+	req := &sseRequest{}
+
+	algorithm := c.GetHeader(algorithmHeader)
+	encodedKey := c.GetHeader(keyHeader)
+	keyMD5 := c.GetHeader(keyMD5Header)
+
+	if algorithm != "" || encodedKey != "" || keyMD5 != "" {
+		if algorithm != sseAlgorithmAES256 {
+			return nil, fmt.Errorf("unsupported customer encryption algorithm: %q", algorithm)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid customer key encoding: %v", err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("customer key must be 256 bits")
+		}
+
+		calculated := storage.CustomerKeyMD5(key)
+		if keyMD5 != "" && keyMD5 != calculated {
+			return nil, fmt.Errorf("customer key MD5 does not match the supplied key")
+		}
+
+		req.Mode = types.EncryptionSSEC
+		req.CustomerKey = key
+		req.KeyMD5 = calculated
+	}
+
+	return req, nil
+}
+
+// applyEncryption 根据加密参数设置上传对象的加密字段
+func (h *Handler) applyEncryption(fileObj *types.FileObject, sse *sseRequest) {
+	mode := sse.Mode
+	if mode == types.EncryptionNone {
+		mode = h.service.DefaultEncryption()
+	}
+
+	fileObj.Encryption = mode
+	if mode == types.EncryptionSSEC {
+		fileObj.CustomerKey = sse.CustomerKey
+		fileObj.EncryptionKeyMD5 = sse.KeyMD5
+	}
+}
+
+// checkCustomerKey 校验读取SSE-C对象时提供的密钥，失败时写入错误响应并返回false
+func checkCustomerKey(c *gin.Context, entry *types.MetadataEntry, sse *sseRequest) bool {
+	if entry.Encryption != types.EncryptionSSEC {
+		return true
+	}
+
+	if len(sse.CustomerKey) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": storage.ErrCustomerKeyRequired.Error(),
+		})
+		return false
+	}
+
+	if sse.KeyMD5 != entry.EncryptionKeyMD5 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": storage.ErrCustomerKeyMismatch.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+// setEncryptionHeaders 在响应中返回对象的加密信息
+func setEncryptionHeaders(c *gin.Context, mode, keyMD5 string) {
+	switch mode {
+	case types.EncryptionSSES3:
+		c.Header(headerSSE, sseAlgorithmAES256)
+	case types.EncryptionSSEC:
+		c.Header(headerSSECustomerAlgorithm, sseAlgorithmAES256)
+		c.Header(headerSSECustomerKeyMD5, keyMD5)
+	}
+}
@@ -5,19 +5,62 @@ import (
 	"net/http"
 	"strconv"
 
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+
 	"github.com/gin-gonic/gin"
 )
 
 // GetObject 处理GET对象请求
 func (h *Handler) GetObject(c *gin.Context) {
+	if _, ok := c.GetQuery("tagging"); ok {
+		h.GetObjectTagging(c)
+		return
+	}
+
 	bucket := c.Param("bucket")
 	key := c.Param("key")
 
 	// 构建对象key（包含bucket前缀）
 	objectKey := h.buildObjectKey(bucket, key)
 
+	// 解析SSE-C密钥
+	sse, err := parseSSEHeaders(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid encryption headers: %v", err),
+		})
+		return
+	}
+
+	// 指定versionId时读取对象的历史版本
+	if versionID, ok := c.GetQuery("versionId"); ok {
+		metadata, ok := h.getObjectVersion(c, objectKey, versionID)
+		if ok {
+			h.writeObject(c, metadata, sse)
+		}
+		return
+	}
+
 	// 从元数据服务获取文件信息
 	metadata, err := h.service.GetMetadata(objectKey)
+	if err != nil && h.respondDeleteMarker(c, objectKey) {
+		return
+	}
+	// 带版本分隔符的key不能写入本地，不回源
+	if err != nil && h.service.HasOrigin(bucket) && types.ValidateObjectKey(objectKey) == nil {
+		// 本地不存在，从bucket配置的源站回源
+		fmt.Printf("Object %s not found locally, pulling from origin\n", objectKey)
+		fetchErr := h.service.HandleThirdPartyFetchAndUpload(objectKey)
+		if fetchErr == nil {
+			metadata, err = h.service.GetMetadata(objectKey)
+		} else if fetchErr != storage.ErrOriginNotFound {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": fmt.Sprintf("Failed to fetch from origin: %v", fetchErr),
+			})
+			return
+		}
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": fmt.Sprintf("Object not found: %v", err),
@@ -46,8 +89,17 @@ func (h *Handler) GetObject(c *gin.Context) {
 		}
 	}
 
-	// 从存储管理器读取文件
-	fileObj, err := h.service.ReadFromStg1OrThirdParty(objectKey)
+	h.writeObject(c, metadata, sse)
+}
+
+// writeObject 读取对象数据并写入GET响应
+func (h *Handler) writeObject(c *gin.Context, metadata *types.MetadataEntry, sse *sseRequest) {
+	if !checkCustomerKey(c, metadata, sse) {
+		return
+	}
+
+	// 从存储管理器读取文件并解密
+	fileObj, err := h.service.ReadObject(metadata, sse.CustomerKey)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("Failed to read object: %v", err),
@@ -58,8 +110,16 @@ func (h *Handler) GetObject(c *gin.Context) {
 	// 设置响应头
 	c.Header("Content-Type", fileObj.ContentType)
 	c.Header("Content-Length", strconv.FormatInt(fileObj.Size, 10))
-	c.Header("ETag", `"`+fileObj.MD5Hash+`"`)
-	c.Header("Last-Modified", fileObj.CreatedAt.Format(http.TimeFormat))
+	c.Header("ETag", objectETag(metadata))
+	c.Header("Last-Modified", metadata.UpdatedAt.Format(http.TimeFormat))
+	setEncryptionHeaders(c, metadata.Encryption, metadata.EncryptionKeyMD5)
+	setStorageClassHeader(c, metadata.StorageClass)
+	setObjectHeaders(c, metadata.ObjectHeaders)
+	setVersionHeaders(c, metadata)
+	setTaggingCountHeader(c, metadata.Tags)
+	if checksumRequested(c) {
+		setChecksumHeaders(c, metadata.Checksums)
+	}
 
 	// 返回文件数据
 	c.Data(http.StatusOK, fileObj.ContentType, fileObj.Data)
@@ -75,7 +135,17 @@ func (h *Handler) GetObjectAPI(c *gin.Context) {
 		return
 	}
 
-	fileObj, err := h.service.ReadFromStg1OrThirdParty(key)
+	// SSE-C对象同样需要提供正确的客户密钥
+	sse, err := parseSSEHeaders(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid encryption headers: %v", err)})
+		return
+	}
+	if !checkCustomerKey(c, metadata, sse) {
+		return
+	}
+
+	fileObj, err := h.service.ReadObject(metadata, sse.CustomerKey)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -89,4 +159,4 @@ func (h *Handler) GetObjectAPI(c *gin.Context) {
 		"created_at":   fileObj.CreatedAt,
 		"metadata":     metadata,
 	})
-}
\ No newline at end of file
+}
@@ -1,6 +1,11 @@
 package s3
 
 import (
+	"fmt"
+	"net/http"
+
+	"mock-storage/internal/types"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,6 +29,7 @@ func (h *Handler) SetupRoutes(router *gin.Engine) {
 	router.DELETE("/:bucket/:key", h.DeleteObject)
 	router.HEAD("/:bucket/:key", h.HeadObject)
 	router.GET("/:bucket", h.ListObjects)
+	router.PUT("/:bucket", h.PutBucket)
 
 	// 管理接口
 	api := router.Group("/api/v1")
@@ -34,6 +40,12 @@ func (h *Handler) SetupRoutes(router *gin.Engine) {
 		api.DELETE("/objects/:key", h.DeleteObjectAPI)
 		api.GET("/stats", h.GetStatsAPI)
 		api.GET("/search", h.SearchObjectsAPI)
+		api.GET("/sync/unsynced", h.ListUnsyncedAPI)
+		api.POST("/sync/retry", h.RetrySyncAPI)
+		api.GET("/scrub", h.ScrubStatusAPI)
+		api.POST("/scrub", h.ScrubAPI)
+		api.GET("/metadata/export", h.ExportMetadataAPI)
+		api.POST("/metadata/import", h.ImportMetadataAPI)
 	}
 }
 
@@ -42,10 +54,39 @@ func (h *Handler) buildObjectKey(bucket, key string) string {
 	return bucket + "/" + key
 }
 
+// respondInvalidKey 对象key不能写入时返回400，返回true表示已写入响应
+func (h *Handler) respondInvalidKey(c *gin.Context, objectKey string) bool {
+	if err := types.ValidateObjectKey(objectKey); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid object key: %v", err),
+		})
+		return true
+	}
+	return false
+}
+
 // extractObjectKey 从完整key中提取对象key（移除bucket前缀）
 func (h *Handler) extractObjectKey(fullKey, bucketPrefix string) string {
 	if len(fullKey) > len(bucketPrefix) {
 		return fullKey[len(bucketPrefix):]
 	}
 	return fullKey
-}
\ No newline at end of file
+}
+
+// objectETag 获取对象的ETag，来自源站的对象保留源站ETag
+func objectETag(entry *types.MetadataEntry) string {
+	if entry.ETag != "" {
+		return `"` + entry.ETag + `"`
+	}
+	return `"` + entry.MD5Hash + `"`
+}
+
+// headerStorageClass S3存储类别请求头
+const headerStorageClass = "x-amz-storage-class"
+
+// setStorageClassHeader 返回对象的存储类别，与S3一致STANDARD不返回
+func setStorageClassHeader(c *gin.Context, storageClass string) {
+	if storageClass != "" && storageClass != types.StorageClassStandard {
+		c.Header(headerStorageClass, storageClass)
+	}
+}
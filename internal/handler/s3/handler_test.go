@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+	"mock-storage/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newTestHandler 创建使用内存存储节点和指定元数据存储的处理器及路由
+func newTestHandler(t *testing.T, store metadata.MetadataStore) (*Handler, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	storageManager := storage.NewManager()
+	for _, id := range []string{"stg1", "stg2", "stg3"} {
+		storageManager.AddNode(storage.NewMemoryStorageNode(id))
+	}
+	encryptor, err := storage.NewEphemeralEncryptor()
+	if err != nil {
+		t.Fatalf("create encryptor: %v", err)
+	}
+	storageManager.SetEncryptor(encryptor)
+
+	handler := NewHandler(NewService(storageManager, metadata.NewMetaService(store), nil))
+	router := gin.New()
+	handler.SetupRoutes(router)
+	return handler, router
+}
+
+// newTestObject 创建上传用的对象
+func newTestObject(key string, data []byte) *types.FileObject {
+	return &types.FileObject{
+		ID:          uuid.New().String(),
+		Key:         key,
+		Size:        int64(len(data)),
+		ContentType: "text/plain",
+		Data:        data,
+		MD5Hash:     utils.CalculateMD5(data),
+		CreatedAt:   time.Now(),
+	}
+}
+
+// customerKeyHeaders 生成SSE-C请求头
+func customerKeyHeaders(key []byte) http.Header {
+	header := http.Header{}
+	header.Set(headerSSECustomerAlgorithm, sseAlgorithmAES256)
+	header.Set(headerSSECustomerKey, base64.StdEncoding.EncodeToString(key))
+	header.Set(headerSSECustomerKeyMD5, storage.CustomerKeyMD5(key))
+	return header
+}
+
+func randomKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func TestGetObjectAPIRequiresCustomerKey(t *testing.T) {
+	handler, router := newTestHandler(t, metadata.NewMemoryStore())
+
+	data := []byte("customer encrypted")
+	customerKey := randomKey(t)
+	fileObj := newTestObject("secret.txt", data)
+	fileObj.Encryption = types.EncryptionSSEC
+	fileObj.CustomerKey = customerKey
+	fileObj.EncryptionKeyMD5 = storage.CustomerKeyMD5(customerKey)
+	if err := handler.service.ExecuteUploadFlow(fileObj); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"missing key", http.Header{}, http.StatusBadRequest},
+		{"wrong key", customerKeyHeaders(randomKey(t)), http.StatusForbidden},
+		{"correct key", customerKeyHeaders(customerKey), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/objects/secret.txt", nil)
+			req.Header = tt.header
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.want == http.StatusOK && !bytes.Contains(rec.Body.Bytes(), []byte(`"size":18`)) {
+				t.Fatalf("unexpected body: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestKeyCannotAddressOtherVersions(t *testing.T) {
+	store := metadata.NewMemoryStore()
+	if err := store.SetBucketVersioning("versioned", types.VersioningEnabled); err != nil {
+		t.Fatalf("enable versioning: %v", err)
+	}
+	_, router := newTestHandler(t, store)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/versioned/object", strings.NewReader("version one")))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", rec.Code, rec.Body.String())
+	}
+	versionID := rec.Header().Get(headerVersionID)
+
+	// 版本数据保存在"key#版本ID"，该key不能被当作普通对象写入或删除
+	collidingPath := "/versioned/object%23" + versionID
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, collidingPath, strings.NewReader("overwrite")))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s = %d, want 400", method, collidingPath, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/versioned/object?versionId="+versionID, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "version one" {
+		t.Fatalf("GET version = %d %q, want original data", rec.Code, rec.Body.String())
+	}
+}
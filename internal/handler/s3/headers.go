@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+
+	"mock-storage/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 对象头相关常量
+const (
+	headerUserMetadataPrefix = "x-amz-meta-"
+
+	// S3限制用户元数据（名称与值）总大小不超过2KB
+	maxUserMetadataSize = 2 << 10
+)
+
+// errMetadataTooLarge 用户元数据超过大小限制
+type errMetadataTooLarge struct {
+	size int
+}
+
+func (e *errMetadataTooLarge) Error() string {
+	return fmt.Sprintf("your metadata headers exceed the maximum allowed metadata size (%d > %d bytes)",
+		e.size, maxUserMetadataSize)
+}
+
+// parseObjectHeaders 解析上传请求中需要保存的标准头和x-amz-meta-*用户元数据
+func parseObjectHeaders(c *gin.Context) (types.ObjectHeaders, error) {
+	headers := types.ObjectHeaders{
+		CacheControl:       c.GetHeader("Cache-Control"),
+		ContentDisposition: c.GetHeader("Content-Disposition"),
+		ContentEncoding:    c.GetHeader("Content-Encoding"),
+		ContentLanguage:    c.GetHeader("Content-Language"),
+		Expires:            c.GetHeader("Expires"),
+	}
+
+	size := 0
+	for name, values := range c.Request.Header {
+		lower := strings.ToLower(name)
+		if !strings.HasPrefix(lower, headerUserMetadataPrefix) {
+			continue
+		}
+
+		metaName := strings.TrimPrefix(lower, headerUserMetadataPrefix)
+		if metaName == "" {
+			return headers, fmt.Errorf("user metadata name cannot be empty")
+		}
+
+		// 同名头出现多次时与S3一致用逗号合并
+		value := strings.Join(values, ",")
+		if headers.UserMetadata == nil {
+			headers.UserMetadata = make(map[string]string)
+		}
+		headers.UserMetadata[metaName] = value
+		size += len(metaName) + len(value)
+	}
+
+	if size > maxUserMetadataSize {
+		return headers, &errMetadataTooLarge{size: size}
+	}
+	return headers, nil
+}
+
+// setObjectHeaders 在GET/HEAD响应中返回对象保存的标准头和用户元数据
+func setObjectHeaders(c *gin.Context, headers types.ObjectHeaders) {
+	standard := []struct {
+		name  string
+		value string
+	}{
+		{"Cache-Control", headers.CacheControl},
+		{"Content-Disposition", headers.ContentDisposition},
+		{"Content-Encoding", headers.ContentEncoding},
+		{"Content-Language", headers.ContentLanguage},
+		{"Expires", headers.Expires},
+	}
+	for _, header := range standard {
+		if header.value != "" {
+			c.Header(header.name, header.value)
+		}
+	}
+
+	for name, value := range headers.UserMetadata {
+		c.Header(headerUserMetadataPrefix+name, value)
+	}
+}
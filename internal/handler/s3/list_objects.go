@@ -5,11 +5,23 @@ import (
 	"strconv"
 	"strings"
 
+	"mock-storage/internal/types"
+
 	"github.com/gin-gonic/gin"
 )
 
 // ListObjects 处理LIST对象请求
 func (h *Handler) ListObjects(c *gin.Context) {
+	// bucket子资源请求
+	if _, ok := c.GetQuery("versioning"); ok {
+		h.GetBucketVersioning(c)
+		return
+	}
+	if _, ok := c.GetQuery("versions"); ok {
+		h.ListObjectVersions(c)
+		return
+	}
+
 	bucket := c.Param("bucket")
 
 	// 获取查询参数
@@ -47,8 +59,8 @@ func (h *Handler) ListObjects(c *gin.Context) {
 				"Key":          objectKey,
 				"Size":         metadata.Size,
 				"LastModified": metadata.UpdatedAt.Format("2006-01-02T15:04:05.000Z"),
-				"ETag":         `"` + metadata.MD5Hash + `"`,
-				"StorageClass": "STANDARD",
+				"ETag":         objectETag(metadata),
+				"StorageClass": metadata.StorageClass,
 			})
 		}
 	}
@@ -75,18 +87,55 @@ func (h *Handler) HeadObject(c *gin.Context) {
 	// 构建对象key（包含bucket前缀）
 	objectKey := h.buildObjectKey(bucket, key)
 
-	// 从元数据服务获取文件信息
-	metadata, err := h.service.GetMetadata(objectKey)
+	// 从元数据服务获取文件信息，指定versionId时获取对象的历史版本
+	var metadata *types.MetadataEntry
+	if versionID, ok := c.GetQuery("versionId"); ok {
+		version, err := h.service.GetObjectVersion(objectKey, versionID)
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		if version.DeleteMarker {
+			setVersionHeaders(c, version)
+			c.Status(http.StatusMethodNotAllowed)
+			return
+		}
+		metadata = version
+	} else {
+		current, err := h.service.GetMetadata(objectKey)
+		if err != nil {
+			if marker := h.service.GetLatestDeleteMarker(objectKey); marker != nil {
+				setVersionHeaders(c, marker)
+			}
+			c.Status(http.StatusNotFound)
+			return
+		}
+		metadata = current
+	}
+
+	// SSE-C对象需要提供正确的密钥才能获取元数据
+	sse, err := parseSSEHeaders(c)
 	if err != nil {
-		c.Status(http.StatusNotFound)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	if !checkCustomerKey(c, metadata, sse) {
 		return
 	}
 
 	// 设置响应头
 	c.Header("Content-Type", metadata.ContentType)
 	c.Header("Content-Length", strconv.FormatInt(metadata.Size, 10))
-	c.Header("ETag", `"`+metadata.MD5Hash+`"`)
+	c.Header("ETag", objectETag(metadata))
 	c.Header("Last-Modified", metadata.UpdatedAt.Format(http.TimeFormat))
+	setEncryptionHeaders(c, metadata.Encryption, metadata.EncryptionKeyMD5)
+	setStorageClassHeader(c, metadata.StorageClass)
+	setObjectHeaders(c, metadata.ObjectHeaders)
+	setVersionHeaders(c, metadata)
+	setTaggingCountHeader(c, metadata.Tags)
+	if checksumRequested(c) {
+		setChecksumHeaders(c, metadata.Checksums)
+	}
 
 	c.Status(http.StatusOK)
 }
@@ -130,31 +179,3 @@ func (h *Handler) GetStatsAPI(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
-
-// SearchObjectsAPI 处理搜索对象请求
-func (h *Handler) SearchObjectsAPI(c *gin.Context) {
-	query := c.Query("q")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
-		return
-	}
-
-	limitStr := c.DefaultQuery("limit", "50")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		limit = 50
-	}
-
-	results, err := h.service.SearchMetadata(query, limit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"query":   query,
-		"results": results,
-		"total":   len(results),
-		"limit":   limit,
-	})
-}
\ No newline at end of file
@@ -0,0 +1,275 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/types"
+	"mock-storage/internal/wal"
+)
+
+// 删除操作的意图记录中的mode
+const (
+	deleteModeObject  = "object"  // 未启用版本控制，删除元数据和节点数据
+	deleteModeMarker  = "marker"  // 写入删除标记
+	deleteModeVersion = "version" // 永久删除指定版本
+)
+
+// beginOperation 写入操作的意图记录，未启用操作日志时返回空事务ID
+// 意图记录fsync失败时操作不能继续，否则崩溃后无法恢复
+func (s *Service) beginOperation(op, key string, entry *types.MetadataEntry, data map[string]string) (string, error) {
+	if s.wal == nil {
+		return "", nil
+	}
+
+	txID, err := s.wal.Begin(op, key, entry, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to write operation log: %v", err)
+	}
+	return txID, nil
+}
+
+// finishOperation 根据操作结果写入提交或中止记录
+// 写入失败时意图记录保持未完成，重启后由恢复流程重新检查
+func (s *Service) finishOperation(txID string, opErr error) {
+	if s.wal == nil || txID == "" {
+		return
+	}
+
+	var err error
+	if opErr != nil {
+		err = s.wal.Abort(txID, opErr)
+	} else {
+		err = s.wal.Commit(txID)
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to finish operation %s in log: %v\n", txID, err)
+	}
+}
+
+// RecoverOperations 重放操作日志中未完成的操作：已生效的只补写提交记录，
+// 未生效的尽量完成，无法完成的撤销。应在启动时、开始处理请求之前调用
+// 返回成功处理的操作数，恢复失败的操作保持未完成，下次启动时重试
+func (s *Service) RecoverOperations() (int, error) {
+	if s.wal == nil {
+		return 0, nil
+	}
+
+	pending := s.wal.Pending()
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	fmt.Printf("[WAL] Recovering %d incomplete operations\n", len(pending))
+
+	recovered := 0
+	var lastErr error
+	for _, record := range pending {
+		action, err := s.recoverOperation(record)
+		if err != nil {
+			fmt.Printf("[WAL] Failed to recover %s %s (tx %s): %v\n", record.Op, record.Key, record.TxID, err)
+			lastErr = err
+			continue
+		}
+
+		fmt.Printf("[WAL] Recovered %s %s (tx %s): %s\n", record.Op, record.Key, record.TxID, action)
+		if action == recoveryUndone {
+			err = s.wal.Abort(record.TxID, errors.New("undone during recovery"))
+		} else {
+			err = s.wal.Commit(record.TxID)
+		}
+		if err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+
+	return recovered, lastErr
+}
+
+// 恢复结果
+const (
+	recoveryApplied  = "already applied"
+	recoveryFinished = "finished"
+	recoveryUndone   = "undone"
+)
+
+// recoverOperation 按操作类型恢复一条未完成的意图记录
+func (s *Service) recoverOperation(record *wal.Record) (string, error) {
+	switch record.Op {
+	case wal.OpPut:
+		return s.recoverPut(record)
+	case wal.OpDelete:
+		return s.recoverDelete(record)
+	case wal.OpRepair:
+		return s.recoverRepair(record)
+	default:
+		return "", fmt.Errorf("unknown operation: %s", record.Op)
+	}
+}
+
+// recoverPut 恢复未完成的上传
+// 元数据已指向本次上传时只需补写提交记录；否则有节点保存了完整数据时补齐其余节点并写入元数据，
+// 没有任何节点保存完整数据时上传未生效，清理残留数据
+func (s *Service) recoverPut(record *wal.Record) (string, error) {
+	entry := record.Entry
+	if entry == nil {
+		return "", fmt.Errorf("put record has no metadata")
+	}
+
+	if s.putApplied(entry) {
+		current, err := s.metadataService.GetMetadata(entry.Key)
+		if err == nil && current.ID == entry.ID {
+			s.resumeOriginSync(current)
+		}
+		return recoveryApplied, nil
+	}
+
+	storageKey := types.StorageKey(entry.Key, entry.VersionID)
+	var source *types.FileObject
+	var holders []string
+	for _, nodeID := range entry.StorageNodes {
+		stored, err := s.storageManager.ReadFromNode(context.Background(), nodeID, storageKey)
+		if err != nil || stored.MD5Hash != record.Data["stored_md5"] {
+			continue
+		}
+		holders = append(holders, nodeID)
+		source = stored
+	}
+
+	if source == nil {
+		return recoveryUndone, s.undoPut(entry)
+	}
+
+	// 用完整的副本补齐崩溃前没有写入的节点
+	storageNodes := holders
+	for _, nodeID := range entry.StorageNodes {
+		if slices.Contains(holders, nodeID) {
+			continue
+		}
+		if err := s.storageManager.RestoreReplica(nodeID, source); err != nil {
+			fmt.Printf("[WAL] Warning: failed to restore %s on node %s: %v\n", storageKey, nodeID, err)
+			continue
+		}
+		storageNodes = append(storageNodes, nodeID)
+	}
+
+	finished := *entry
+	finished.StorageNodes = storageNodes
+
+	// write-through的源站写入结果未知，改为待同步
+	if finished.SyncStatus == types.SyncStatusSynced {
+		finished.SyncStatus = types.SyncStatusPending
+	}
+
+	err := s.metadataService.WithTransaction(func(meta *metadata.MetaService) error {
+		if finished.VersionID != "" {
+			if _, err := meta.ArchiveCurrentVersion(finished.Key); err != nil {
+				return err
+			}
+		}
+		return meta.SaveEntry(&finished)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.storageManager.InvalidateCache(finished.Key)
+	s.resumeOriginSync(&finished)
+	return recoveryFinished, nil
+}
+
+// putApplied 判断上传的元数据是否已经提交（当前版本或版本历史中存在同一对象）
+func (s *Service) putApplied(entry *types.MetadataEntry) bool {
+	current, err := s.metadataService.GetMetadata(entry.Key)
+	if err == nil && current.ID == entry.ID {
+		return true
+	}
+	if entry.VersionID == "" {
+		return false
+	}
+	version, err := s.metadataService.GetVersion(entry.Key, entry.VersionID)
+	return err == nil && version.ID == entry.ID
+}
+
+// undoPut 撤销未生效的上传
+// 独立版本的节点key只属于本次上传，直接删除；与其他对象共用节点key时，
+// 部分写入的数据可能覆盖了已有副本，通过副本校验用健康副本修复
+func (s *Service) undoPut(entry *types.MetadataEntry) error {
+	storageKey := types.StorageKey(entry.Key, entry.VersionID)
+	if storageKey != entry.Key {
+		return s.storageManager.DeleteFromNodes(storageKey, entry.StorageNodes)
+	}
+
+	current, err := s.metadataService.GetMetadata(entry.Key)
+	if err == nil && types.StorageKey(current.Key, current.VersionID) == storageKey {
+		_, err = s.ScrubObject(context.Background(), current.Key)
+		return err
+	}
+
+	// 版本历史中的null版本同样使用对象key，无法校验时保留节点上的数据
+	if version, err := s.metadataService.GetVersion(entry.Key, types.NullVersionID); err == nil && !version.DeleteMarker {
+		return nil
+	}
+	return s.storageManager.DeleteFromNodes(storageKey, entry.StorageNodes)
+}
+
+// resumeOriginSync 崩溃前可能没有入队的write-back同步任务重新入队
+func (s *Service) resumeOriginSync(entry *types.MetadataEntry) {
+	if entry.SyncStatus != types.SyncStatusPending {
+		return
+	}
+	if err := s.enqueueOriginSync(entry.Key, originSyncPut); err != nil {
+		fmt.Printf("[WAL] Warning: failed to enqueue origin sync for %s: %v\n", entry.Key, err)
+	}
+}
+
+// recoverDelete 恢复未完成的删除：元数据仍存在时重新执行删除，删除在事务中完成，可以安全重做
+func (s *Service) recoverDelete(record *wal.Record) (string, error) {
+	switch record.Data["mode"] {
+	case deleteModeObject:
+		current, err := s.metadataService.GetMetadata(record.Key)
+		if err != nil || record.Entry == nil || current.ID != record.Entry.ID {
+			return recoveryApplied, nil
+		}
+		return recoveryFinished, s.deleteCurrentObject(record.Key)
+
+	case deleteModeMarker:
+		marker := record.Entry
+		if marker == nil {
+			return "", fmt.Errorf("delete marker record has no marker")
+		}
+		existing, err := s.metadataService.GetVersion(record.Key, marker.VersionID)
+		if err == nil && existing.ID == marker.ID {
+			return recoveryApplied, nil
+		}
+		return recoveryFinished, s.createDeleteMarker(marker, record.Data["status"])
+
+	case deleteModeVersion:
+		_, err := s.deleteObjectVersion(record.Key, record.Data["version_id"])
+		if errors.Is(err, errObjectNotFound) {
+			return recoveryApplied, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return recoveryFinished, nil
+
+	default:
+		return "", fmt.Errorf("unknown delete mode: %s", record.Data["mode"])
+	}
+}
+
+// recoverRepair 恢复中断的副本修复：重新校验对象，对象已被删除时无需处理
+func (s *Service) recoverRepair(record *wal.Record) (string, error) {
+	if _, err := s.metadataService.GetMetadata(record.Key); err != nil {
+		return recoveryApplied, nil
+	}
+
+	if _, err := s.ScrubObject(context.Background(), record.Key); err != nil {
+		return "", err
+	}
+	return recoveryFinished, nil
+}
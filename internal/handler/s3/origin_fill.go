@@ -0,0 +1,39 @@
+package s3
+
+import "sync"
+
+// originFills 合并同一对象并发的回源填充，一次源站未命中只获取和上传一次
+type originFills struct {
+	mu       sync.Mutex
+	inflight map[string]*originFill
+}
+
+// originFill 一次进行中的回源填充
+type originFill struct {
+	done chan struct{}
+	err  error
+}
+
+// do 执行objectKey的回源填充，已有填充在进行时等待其完成并返回相同的结果
+func (f *originFills) do(objectKey string, fill func() error) error {
+	f.mu.Lock()
+	if call, ok := f.inflight[objectKey]; ok {
+		f.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	if f.inflight == nil {
+		f.inflight = make(map[string]*originFill)
+	}
+	call := &originFill{done: make(chan struct{})}
+	f.inflight[objectKey] = call
+	f.mu.Unlock()
+
+	call.err = fill()
+
+	f.mu.Lock()
+	delete(f.inflight, objectKey)
+	f.mu.Unlock()
+	close(call.done)
+	return call.err
+}
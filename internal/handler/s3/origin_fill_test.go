@@ -0,0 +1,123 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+)
+
+// slowOrigin 延迟返回固定内容的源站，记录被请求的次数
+type slowOrigin struct {
+	data    []byte
+	fetches atomic.Int32
+}
+
+func (o *slowOrigin) GetObject(key string) (*types.FileObject, error) {
+	o.fetches.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	return newTestObject(key, o.data), nil
+}
+
+func TestConcurrentOriginMissUploadsOnce(t *testing.T) {
+	store := metadata.NewMemoryStore()
+	if err := store.SetBucketVersioning("versioned", types.VersioningEnabled); err != nil {
+		t.Fatalf("enable versioning: %v", err)
+	}
+	handler, router := newTestHandler(t, store)
+	handler.service.SetDefaultEncryption(types.EncryptionSSES3)
+
+	origin := &slowOrigin{data: []byte("from origin")}
+	cache := storage.NewPullThroughCache(0)
+	cache.SetDefaultOrigin(origin, storage.WriteModeNone)
+	handler.service.storageManager.SetThirdPartyService(cache)
+
+	const readers = 8
+	var wg sync.WaitGroup
+	failures := make(chan string, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/versioned/object", nil))
+			if rec.Code != http.StatusOK || rec.Body.String() != "from origin" {
+				failures <- rec.Body.String()
+			}
+		}()
+	}
+	wg.Wait()
+	close(failures)
+	for failure := range failures {
+		t.Errorf("GET returned %q", failure)
+	}
+
+	if fetches := origin.fetches.Load(); fetches != 1 {
+		t.Errorf("origin fetched %d times, want 1", fetches)
+	}
+	versions, err := store.ListVersions("versioned/object", 100)
+	if err != nil {
+		t.Fatalf("list versions: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Errorf("origin miss created %d versions, want 1", len(versions))
+	}
+}
+
+func TestOriginFallbackOnlyServesMatchingCurrentObject(t *testing.T) {
+	store := metadata.NewMemoryStore()
+	if err := store.SetBucketVersioning("versioned", types.VersioningEnabled); err != nil {
+		t.Fatalf("enable versioning: %v", err)
+	}
+	handler, router := newTestHandler(t, store)
+	origin := &slowOrigin{}
+	cache := storage.NewPullThroughCache(0)
+	cache.SetDefaultOrigin(origin, storage.WriteModeNone)
+	handler.service.storageManager.SetThirdPartyService(cache)
+
+	tests := []struct {
+		name        string
+		path        string
+		originData  string
+		wantStatus  int
+		wantFetches int32
+	}{
+		{"matching origin content", "/plain/same", "local data", http.StatusOK, 1},
+		{"changed origin content", "/plain/changed", "origin data", http.StatusInternalServerError, 1},
+		{"versioned object", "/versioned/object", "local data", http.StatusInternalServerError, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			putObject(t, router, tt.path, "local data")
+			entry, err := store.GetMetadata(tt.path[1:])
+			if err != nil {
+				t.Fatalf("get metadata: %v", err)
+			}
+			// 删除全部本地副本，读取只能回源
+			storageKey := types.StorageKey(entry.Key, entry.VersionID)
+			if err := handler.service.storageManager.DeleteFromNodes(storageKey, entry.StorageNodes); err != nil {
+				t.Fatalf("delete replicas: %v", err)
+			}
+
+			origin.data = []byte(tt.originData)
+			origin.fetches.Store(0)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GET = %d %q, want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if rec.Code == http.StatusOK && rec.Body.String() != "local data" {
+				t.Errorf("GET returned %q, want local data", rec.Body.String())
+			}
+			if fetches := origin.fetches.Load(); fetches != tt.wantFetches {
+				t.Errorf("origin fetched %d times, want %d", fetches, tt.wantFetches)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"testing"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+)
+
+func TestSweepOrphansKeepsReferencedFiles(t *testing.T) {
+	storageManager := storage.NewManager()
+	node, err := storage.NewFileStorageNode("stg1", t.TempDir())
+	if err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+	storageManager.AddNode(node)
+	store := metadata.NewMemoryStore()
+	if err := store.SetBucketVersioning("versioned", types.VersioningEnabled); err != nil {
+		t.Fatalf("enable versioning: %v", err)
+	}
+	service := NewService(storageManager, metadata.NewMetaService(store), nil)
+
+	for _, key := range []string{"plain/object", "versioned/object", "versioned/object"} {
+		if err := service.ExecuteUploadFlow(newTestObject(key, []byte(key))); err != nil {
+			t.Fatalf("upload %s: %v", key, err)
+		}
+	}
+	if err := node.Write(&types.FileObject{Key: "plain/orphan", Data: []byte("orphan")}); err != nil {
+		t.Fatalf("write orphan: %v", err)
+	}
+
+	result, err := service.SweepOrphans(0, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if result.Checked != 4 || len(result.Orphans) != 1 || result.Orphans[0] != "stg1:plain/orphan" || result.Removed != 0 {
+		t.Fatalf("dry run result = %+v, want only plain/orphan reported", result)
+	}
+
+	if _, err := service.SweepOrphans(0, false); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if _, err := node.Stat("plain/orphan"); err == nil {
+		t.Errorf("orphan file still exists")
+	}
+	result, err = service.SweepOrphans(0, true)
+	if err != nil || result.Checked != 3 || len(result.Orphans) != 0 {
+		t.Fatalf("after sweep = %+v, %v, want the three referenced files kept", result, err)
+	}
+}
@@ -15,8 +15,64 @@ import (
 
 // PutObject 处理PUT对象请求
 func (h *Handler) PutObject(c *gin.Context) {
+	if _, ok := c.GetQuery("tagging"); ok {
+		h.PutObjectTagging(c)
+		return
+	}
+	if c.GetHeader(headerCopySource) != "" {
+		h.CopyObject(c)
+		return
+	}
+
 	bucket := c.Param("bucket")
 	key := c.Param("key")
+	if h.respondInvalidKey(c, h.buildObjectKey(bucket, key)) {
+		return
+	}
+
+	// 解析服务端加密参数
+	sse, err := parseSSEHeaders(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid encryption headers: %v", err),
+		})
+		return
+	}
+
+	// 解析存储类别
+	storageClass := c.GetHeader(headerStorageClass)
+	if storageClass == "" {
+		storageClass = types.StorageClassStandard
+	}
+	if !h.service.HasStorageClass(storageClass) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid storage class: %s", storageClass),
+		})
+		return
+	}
+
+	// 解析需要保存的标准头和用户元数据
+	headers, err := parseObjectHeaders(c)
+	if err != nil {
+		if _, ok := err.(*errMetadataTooLarge); ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("MetadataTooLarge: %v", err),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid metadata headers: %v", err),
+		})
+		return
+	}
+
+	tags, err := parseTaggingHeader(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("InvalidTag: %v", err),
+		})
+		return
+	}
 
 	// 读取请求体
 	data, err := io.ReadAll(c.Request.Body)
@@ -27,6 +83,21 @@ func (h *Handler) PutObject(c *gin.Context) {
 		return
 	}
 
+	// 计算并校验附加校验和
+	checksums, err := parseChecksums(c, data)
+	if err != nil {
+		if _, ok := err.(*errBadDigest); ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("BadDigest: %v", err),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid checksum headers: %v", err),
+		})
+		return
+	}
+
 	// 获取Content-Type
 	contentType := c.GetHeader("Content-Type")
 	if contentType == "" {
@@ -45,7 +116,15 @@ func (h *Handler) PutObject(c *gin.Context) {
 		Data:        data,
 		MD5Hash:     utils.CalculateMD5(data),
 		CreatedAt:   time.Now(),
+
+		StorageClass: storageClass,
+		Checksums:    checksums,
+
+		ObjectHeaders: headers,
+
+		Tags: tags,
 	}
+	h.applyEncryption(fileObj, sse)
 
 	// 执行完整的上传流程
 	err = h.service.ExecuteUploadFlow(fileObj)
@@ -58,6 +137,12 @@ func (h *Handler) PutObject(c *gin.Context) {
 
 	// 返回成功响应
 	c.Header("ETag", `"`+fileObj.MD5Hash+`"`)
+	setEncryptionHeaders(c, fileObj.Encryption, fileObj.EncryptionKeyMD5)
+	setStorageClassHeader(c, fileObj.StorageClass)
+	setChecksumHeaders(c, fileObj.Checksums)
+	if fileObj.VersionID != "" {
+		c.Header(headerVersionID, fileObj.VersionID)
+	}
 	c.JSON(http.StatusOK, types.UploadResponse{
 		Success:  true,
 		ObjectID: fileObj.ID,
@@ -75,6 +160,9 @@ func (h *Handler) PutObjectAPI(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if h.respondInvalidKey(c, req.Key) {
+		return
+	}
 
 	fileObj := &types.FileObject{
 		ID:          uuid.New().String(),
@@ -85,6 +173,7 @@ func (h *Handler) PutObjectAPI(c *gin.Context) {
 		MD5Hash:     utils.CalculateMD5(req.Data),
 		CreatedAt:   time.Now(),
 	}
+	h.applyEncryption(fileObj, &sseRequest{})
 
 	err := h.service.ExecuteUploadFlow(fileObj)
 	if err != nil {
@@ -100,4 +189,4 @@ func (h *Handler) PutObjectAPI(c *gin.Context) {
 		MD5Hash:  fileObj.MD5Hash,
 		Message:  "Object uploaded successfully",
 	})
-}
\ No newline at end of file
+}
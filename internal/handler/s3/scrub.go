@@ -0,0 +1,276 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+	"mock-storage/internal/utils"
+	"mock-storage/internal/wal"
+)
+
+// 全量校验时每页读取的元数据数量
+const scrubPageSize = 500
+
+// scrubState 全量副本校验的运行状态
+type scrubState struct {
+	mu         sync.Mutex
+	running    bool
+	startedAt  time.Time
+	finishedAt time.Time
+	scanned    int
+	unhealthy  []string
+	repaired   int
+}
+
+// ScrubObject 校验对象在各节点上的副本，并用健康副本修复损坏或缺失的副本
+// 使用对象最强的可用校验和（SHA256 > SHA1 > CRC32C > CRC32 > MD5）校验解密后的数据
+// ctx取消时停止读取和修复，返回ctx的错误
+func (s *Service) ScrubObject(ctx context.Context, objectKey string) (*types.ScrubResult, error) {
+	entry, err := s.metadataService.GetMetadata(objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, expected, ok := utils.StrongestChecksum(entry.Checksums)
+	if !ok {
+		algorithm, expected = utils.ChecksumMD5, entry.MD5Hash
+	}
+
+	result := &types.ScrubResult{
+		Key:       objectKey,
+		Algorithm: algorithm,
+		Replicas:  make(map[string]string),
+		Errors:    make(map[string]string),
+	}
+
+	var healthy *types.FileObject
+	storageKey := types.StorageKey(entry.Key, entry.VersionID)
+	for _, nodeID := range entry.StorageNodes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		stored, err := s.storageManager.ReadFromNode(ctx, nodeID, storageKey)
+		if err != nil {
+			result.Replicas[nodeID] = types.ReplicaMissing
+			result.Errors[nodeID] = err.Error()
+			continue
+		}
+
+		// SSE-C对象没有客户密钥无法解密，只能跳过
+		if entry.Encryption == types.EncryptionSSEC {
+			result.Replicas[nodeID] = types.ReplicaUnverifiable
+			continue
+		}
+
+		if err := s.verifyReplica(stored, entry, algorithm, expected); err != nil {
+			result.Replicas[nodeID] = types.ReplicaCorrupt
+			result.Errors[nodeID] = err.Error()
+			continue
+		}
+
+		result.Replicas[nodeID] = types.ReplicaHealthy
+		if healthy == nil {
+			healthy = stored
+		}
+	}
+
+	// 用健康副本修复其余副本
+	result.Healthy = true
+	repairing, txID := false, ""
+	for nodeID, status := range result.Replicas {
+		if status != types.ReplicaCorrupt && status != types.ReplicaMissing {
+			continue
+		}
+
+		if healthy == nil {
+			result.Healthy = false
+			continue
+		}
+
+		// 已修复的副本无需回滚，中断的修复由下次校验继续
+		if err := ctx.Err(); err != nil {
+			s.finishOperation(txID, err)
+			return nil, err
+		}
+
+		// 第一次修复前写入意图记录，修复中途崩溃时重启后重新校验
+		if !repairing {
+			if txID, err = s.beginOperation(wal.OpRepair, objectKey, entry, nil); err != nil {
+				result.Healthy = false
+				result.Errors[nodeID] = fmt.Sprintf("%s; repair skipped: %v", result.Errors[nodeID], err)
+				continue
+			}
+			repairing = true
+		}
+
+		if err := s.storageManager.RestoreReplica(nodeID, healthy); err != nil {
+			result.Healthy = false
+			result.Errors[nodeID] = fmt.Sprintf("%s; repair failed: %v", result.Errors[nodeID], err)
+			continue
+		}
+
+		fmt.Printf("[SCRUB] Repaired %s replica of %s on node %s\n", status, objectKey, nodeID)
+		result.Replicas[nodeID] = types.ReplicaRepaired
+	}
+
+	s.finishOperation(txID, nil)
+
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	return result, nil
+}
+
+// verifyReplica 校验节点上存储的原始数据
+func (s *Service) verifyReplica(stored *types.FileObject, entry *types.MetadataEntry, algorithm, expected string) error {
+	plain, err := s.storageManager.DecryptObject(stored, entry, nil)
+	if err != nil {
+		return err
+	}
+
+	if algorithm == utils.ChecksumMD5 {
+		if calculated := utils.CalculateMD5(plain.Data); calculated != expected {
+			return fmt.Errorf("MD5 mismatch: expected %s, got %s", expected, calculated)
+		}
+		return nil
+	}
+
+	calculated, err := utils.CalculateChecksum(algorithm, plain.Data)
+	if err != nil {
+		return err
+	}
+	if calculated != expected {
+		return fmt.Errorf("%s mismatch: expected %s, got %s", algorithm, expected, calculated)
+	}
+	return nil
+}
+
+// EnqueueScrubTask 将单个对象的副本校验任务加入队列
+func (s *Service) EnqueueScrubTask(objectKey string) error {
+	if _, err := s.metadataService.GetMetadata(objectKey); err != nil {
+		return err
+	}
+
+	task := &types.TaskMessage{
+		Type:     types.TaskTypeReplicationCheck,
+		ObjectID: objectKey,
+		Data: map[string]any{
+			"key": objectKey,
+		},
+		CreatedAt: time.Now(),
+	}
+
+	return s.queueManager.Enqueue(task)
+}
+
+// StartScrubAll 在后台校验所有对象，已有校验在运行时返回错误
+func (s *Service) StartScrubAll() error {
+	if err := s.beginScrub(); err != nil {
+		return err
+	}
+
+	go s.scrubAll(context.Background())
+	return nil
+}
+
+// ScrubAll 校验所有对象并等待完成，用于定时任务；结果同样记录在全量校验状态中
+// ctx取消时在当前对象处停止并返回ctx的错误
+func (s *Service) ScrubAll(ctx context.Context) error {
+	if err := s.beginScrub(); err != nil {
+		return err
+	}
+
+	if err := s.scrubAll(ctx); err != nil {
+		return err
+	}
+
+	status := s.GetScrubStatus()
+	fmt.Printf("[SCRUB] Scrubbed %d objects: %d replicas repaired, %d objects unhealthy\n",
+		status["scanned"], status["repaired"], len(status["unhealthy"].([]string)))
+	return nil
+}
+
+// beginScrub 标记全量校验开始，已有校验在运行时返回错误
+func (s *Service) beginScrub() error {
+	s.scrub.mu.Lock()
+	defer s.scrub.mu.Unlock()
+
+	if s.scrub.running {
+		return fmt.Errorf("scrub is already running")
+	}
+	s.scrub.running = true
+	s.scrub.startedAt = time.Now()
+	s.scrub.finishedAt = time.Time{}
+	s.scrub.scanned = 0
+	s.scrub.unhealthy = nil
+	s.scrub.repaired = 0
+	return nil
+}
+
+// scrubAll 分页校验所有对象
+func (s *Service) scrubAll(ctx context.Context) error {
+	defer func() {
+		s.scrub.mu.Lock()
+		s.scrub.running = false
+		s.scrub.finishedAt = time.Now()
+		s.scrub.mu.Unlock()
+	}()
+
+	for offset := 0; ; offset += scrubPageSize {
+		entries, err := s.metadataService.ListMetadata(scrubPageSize, offset)
+		if err != nil {
+			fmt.Printf("[SCRUB] Failed to list metadata: %v\n", err)
+			return fmt.Errorf("failed to list metadata: %v", err)
+		}
+
+		for _, entry := range entries {
+			result, err := s.ScrubObject(ctx, entry.Key)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				fmt.Printf("[SCRUB] Stopped after %d objects: %v\n", s.GetScrubStatus()["scanned"], ctxErr)
+				return ctxErr
+			}
+
+			s.scrub.mu.Lock()
+			s.scrub.scanned++
+			if err != nil || !result.Healthy {
+				s.scrub.unhealthy = append(s.scrub.unhealthy, entry.Key)
+			}
+			if result != nil {
+				for _, status := range result.Replicas {
+					if status == types.ReplicaRepaired {
+						s.scrub.repaired++
+					}
+				}
+			}
+			s.scrub.mu.Unlock()
+		}
+
+		if len(entries) < scrubPageSize {
+			return nil
+		}
+	}
+}
+
+// GetScrubStatus 获取全量副本校验状态
+func (s *Service) GetScrubStatus() map[string]any {
+	s.scrub.mu.Lock()
+	defer s.scrub.mu.Unlock()
+
+	status := map[string]any{
+		"running":   s.scrub.running,
+		"scanned":   s.scrub.scanned,
+		"repaired":  s.scrub.repaired,
+		"unhealthy": append([]string{}, s.scrub.unhealthy...),
+	}
+	if !s.scrub.startedAt.IsZero() {
+		status["started_at"] = s.scrub.startedAt
+	}
+	if !s.scrub.finishedAt.IsZero() {
+		status["finished_at"] = s.scrub.finishedAt
+	}
+	return status
+}
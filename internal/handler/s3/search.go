@@ -0,0 +1,158 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 搜索接口中按用户元数据和对象标签过滤的参数前缀
+const (
+	searchMetadataPrefix = "meta."
+	searchTagPrefix      = "tag."
+)
+
+// SearchObjectsAPI 处理搜索对象请求
+// 所有条件同时生效，不带条件时返回全部对象；结果按sort/order排序，通过next_cursor获取下一页
+func (h *Handler) SearchObjectsAPI(c *gin.Context) {
+	query, err := parseSearchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.SearchMetadata(query)
+	if errors.Is(err, metadata.ErrInvalidSearch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	order := "asc"
+	if query.Descending {
+		order = "desc"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"query":       query.Text,
+		"metadata":    query.UserMetadata,
+		"tags":        query.Tags,
+		"results":     result.Entries,
+		"total":       len(result.Entries),
+		"limit":       query.Limit,
+		"sort":        query.SortBy,
+		"order":       order,
+		"next_cursor": result.NextCursor,
+	})
+}
+
+// parseSearchQuery 解析搜索参数
+// bucket指定时prefix和pattern针对bucket内的对象key，否则针对完整key（bucket/key）
+func parseSearchQuery(c *gin.Context) (*types.SearchQuery, error) {
+	query := &types.SearchQuery{
+		Text:         c.Query("q"),
+		ContentType:  c.Query("content_type"),
+		StorageClass: c.Query("storage_class"),
+		SortBy:       c.DefaultQuery("sort", types.SearchSortCreated),
+		Cursor:       c.Query("cursor"),
+		UserMetadata: make(map[string]string),
+		Tags:         make(map[string]string),
+	}
+
+	prefix, pattern := c.Query("prefix"), c.Query("pattern")
+	if bucket := c.Query("bucket"); bucket != "" {
+		prefix = bucket + "/" + prefix
+		if pattern != "" {
+			pattern = bucket + "/" + pattern
+		}
+	}
+	query.KeyPrefix, query.KeyPattern = prefix, pattern
+
+	for param, values := range c.Request.URL.Query() {
+		if name, ok := strings.CutPrefix(param, searchMetadataPrefix); ok && name != "" && len(values) > 0 {
+			query.UserMetadata[strings.ToLower(name)] = values[0]
+		}
+		// 标签名区分大小写
+		if name, ok := strings.CutPrefix(param, searchTagPrefix); ok && name != "" && len(values) > 0 {
+			query.Tags[name] = values[0]
+		}
+	}
+
+	// 按key排序默认升序，其余默认降序（最新、最大的在前）
+	switch order := c.Query("order"); order {
+	case "":
+		query.Descending = query.SortBy != types.SearchSortKey
+	case "asc", "desc":
+		query.Descending = order == "desc"
+	default:
+		return nil, fmt.Errorf("invalid order %q (must be asc or desc)", order)
+	}
+
+	var err error
+	if query.MinSize, err = parseSizeParam(c, "min_size"); err != nil {
+		return nil, err
+	}
+	if query.MaxSize, err = parseSizeParam(c, "max_size"); err != nil {
+		return nil, err
+	}
+
+	timeParams := map[string]*time.Time{
+		"created_after":  &query.CreatedAfter,
+		"created_before": &query.CreatedBefore,
+		"updated_after":  &query.UpdatedAfter,
+		"updated_before": &query.UpdatedBefore,
+	}
+	for param, target := range timeParams {
+		if *target, err = parseTimeParam(c, param); err != nil {
+			return nil, err
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if query.Limit, err = strconv.Atoi(limitStr); err != nil || query.Limit <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", limitStr)
+		}
+	}
+
+	return query, nil
+}
+
+// parseSizeParam 解析大小参数（字节），未提供时返回nil
+func parseSizeParam(c *gin.Context, param string) (*int64, error) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid %s %q", param, value)
+	}
+	return &size, nil
+}
+
+// parseTimeParam 解析时间参数，支持RFC3339和日期（YYYY-MM-DD，UTC零点），未提供时返回零值
+func parseTimeParam(c *gin.Context, param string) (time.Time, error) {
+	value := c.Query(param)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q (use RFC3339 or YYYY-MM-DD)", param, value)
+}
@@ -1,13 +1,18 @@
 package s3
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"mock-storage/internal/metadata"
 	"mock-storage/internal/queue"
 	"mock-storage/internal/storage"
 	"mock-storage/internal/types"
+	"mock-storage/internal/utils"
+	"mock-storage/internal/wal"
 )
 
 // Service S3业务逻辑服务
@@ -15,6 +20,15 @@ type Service struct {
 	storageManager  *storage.Manager
 	metadataService *metadata.MetaService
 	queueManager    *queue.Manager
+
+	defaultEncryption string // 未指定加密头时使用的加密模式
+
+	scrub scrubState // 全量副本校验状态
+
+	objectLocks objectLocks // 上传和删除时锁定对象key
+	originFills originFills // 合并同一对象并发的回源填充
+
+	wal *wal.Log // 操作日志，为nil时不记录
 }
 
 // NewService 创建S3业务服务
@@ -26,28 +40,117 @@ func NewService(storageManager *storage.Manager, metadataService *metadata.MetaS
 	}
 }
 
+// lastAccessResolution 最后访问时间的更新粒度
+const lastAccessResolution = time.Hour
+
+// HasStorageClass 判断存储类别是否已配置
+func (s *Service) HasStorageClass(class string) bool {
+	return s.storageManager.HasStorageClass(class)
+}
+
+// SetDefaultEncryption 设置默认的服务端加密模式
+func (s *Service) SetDefaultEncryption(mode string) {
+	s.defaultEncryption = mode
+}
+
+// SetWAL 设置操作日志，上传、删除和副本修复在修改节点前写入意图记录
+func (s *Service) SetWAL(log *wal.Log) {
+	s.wal = log
+}
+
+// DefaultEncryption 获取默认的服务端加密模式
+func (s *Service) DefaultEncryption() string {
+	return s.defaultEncryption
+}
+
 // ExecuteUploadFlow 执行完整的上传流程
 func (s *Service) ExecuteUploadFlow(fileObj *types.FileObject) error {
 	fmt.Printf("Starting upload flow for key: %s\n", fileObj.Key)
 
-	// 步骤1-3: 顺序写入存储节点
-	err := s.storageManager.WriteToAllNodes(fileObj)
+	// 版本数据保存在"key#版本ID"，带分隔符的key会覆盖其他对象的版本
+	if err := types.ValidateObjectKey(fileObj.Key); err != nil {
+		return err
+	}
+
+	// 按bucket的版本控制状态分配版本ID，不同版本的数据分别保存在存储节点上
+	versioning := s.assignVersionID(fileObj)
+
+	// 源站写入：write-through在元数据提交前同步写入，write-back标记为待同步
+	writeMode := s.originWriteMode(fileObj)
+	switch writeMode {
+	case storage.WriteModeThrough:
+		fileObj.SyncStatus = types.SyncStatusSynced
+	case storage.WriteModeBack:
+		fileObj.SyncStatus = types.SyncStatusPending
+	}
+
+	stored, err := s.storageManager.PrepareStored(fileObj)
+	if err != nil {
+		return err
+	}
+	nodeIDs := s.storageManager.NodeIDsForClass(fileObj.StorageClass)
+
+	// 写入节点前记录完整的元数据和节点数据的MD5，崩溃后重启时据此完成或撤销上传
+	txID, err := s.beginOperation(wal.OpPut, fileObj.Key, metadata.NewEntry(fileObj, nodeIDs), map[string]string{
+		"stored_md5": utils.CalculateMD5(stored.Data),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to write to storage nodes: %v", err)
+		return err
 	}
 
-	// 步骤4: 写入元数据服务
-	storageNodeIDs := s.storageManager.GetNodeIDs()
-	err = s.metadataService.SaveMetadata(fileObj, storageNodeIDs)
+	// 节点写入、源站写入和元数据写入任一步失败时回滚元数据事务并撤销节点写入
+	// 节点和源站写入在数据库事务之外完成，元数据事务只包含元数据的读写
+	var storageNodeIDs []string
+	err = s.runTransaction(fileObj.Key, func(nodes *storage.NodeTransaction) error {
+		// 步骤1-3: 顺序写入存储类别对应的节点
+		storageNodeIDs, err = nodes.WriteStored(stored, nodeIDs)
+		if err != nil {
+			return fmt.Errorf("failed to write to storage nodes: %v", err)
+		}
+
+		// write-through在元数据提交前写入源站，元数据提交失败时源站上的对象比本地新，之后可以回源获取
+		if writeMode == storage.WriteModeThrough {
+			if err := s.storageManager.PutToThirdParty(fileObj); err != nil {
+				return fmt.Errorf("failed to write through to origin: %v", err)
+			}
+		}
+		return nil
+	}, func(meta *metadata.MetaService) error {
+		// 版本控制bucket中被覆盖的当前版本保留在版本历史中
+		if versioning != types.VersioningUnversioned {
+			if _, err := meta.ArchiveCurrentVersion(fileObj.Key); err != nil {
+				return fmt.Errorf("failed to archive current version: %v", err)
+			}
+		}
+
+		// 步骤4: 写入元数据服务
+		if err := meta.SaveMetadata(fileObj, storageNodeIDs); err != nil {
+			return fmt.Errorf("failed to save metadata: %v", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to save metadata: %v", err)
+		s.finishOperation(txID, err)
+		return err
+	}
+
+	// 步骤5: 元数据事务已提交
+
+	// write-back模式：通过队列异步写入源站
+	if writeMode == storage.WriteModeBack {
+		err = s.enqueueOriginSync(fileObj.Key, originSyncPut)
+		if err != nil {
+			fmt.Printf("Warning: failed to enqueue origin sync task: %v\n", err)
+			s.metadataService.UpdateSyncStatus(fileObj.Key, types.SyncStatusFailed, err.Error())
+		}
 	}
 
-	// 步骤5: 数据已经通过元数据服务保存到数据库
+	// 源站同步任务入队后才提交，之前崩溃时恢复流程会重新入队
+	s.finishOperation(txID, nil)
 
 	// // 异步任务：发送到队列进行后续处理
 	// task := &types.TaskMessage{
-	// 	Type:     "upload_completed",
+	// 	Type:     types.TaskTypeUploadCompleted,
 	// 	ObjectID: fileObj.ID,
 	// 	Data: map[string]any{
 	// 		"key":          fileObj.Key,
@@ -67,14 +170,42 @@ func (s *Service) ExecuteUploadFlow(fileObj *types.FileObject) error {
 	return nil
 }
 
+// HasOrigin 判断bucket是否配置了回源源站
+func (s *Service) HasOrigin(bucket string) bool {
+	return s.storageManager.HasThirdPartyOrigin(bucket)
+}
+
 // HandleThirdPartyFetchAndUpload 处理从第三方获取并上传的逻辑
+// 同一对象的并发回源合并为一次获取和上传，等待者返回相同的结果
+// 源站上不存在对象时返回storage.ErrOriginNotFound
 func (s *Service) HandleThirdPartyFetchAndUpload(objectKey string) error {
+	return s.originFills.do(objectKey, func() error {
+		return s.fetchAndUpload(objectKey)
+	})
+}
+
+// fetchAndUpload 从第三方获取对象并上传，对象已被之前的回源写入本地时直接返回
+func (s *Service) fetchAndUpload(objectKey string) error {
+	if entry, err := s.metadataService.GetMetadata(objectKey); err == nil && len(entry.StorageNodes) > 0 {
+		return nil
+	}
+
 	// 从第三方服务获取对象
-	fileObj, err := s.storageManager.ReadFromStg1OrThirdParty(objectKey)
+	fileObj, err := s.storageManager.FetchFromThirdParty(objectKey)
 	if err != nil {
+		if err == storage.ErrOriginNotFound {
+			return err
+		}
 		return fmt.Errorf("failed to fetch from third party: %v", err)
 	}
 
+	if fileObj.Encryption == types.EncryptionNone {
+		fileObj.Encryption = s.defaultEncryption
+	}
+
+	// 对象来自源站，无需再写回
+	fileObj.SyncStatus = types.SyncStatusSynced
+
 	// 执行上传流程
 	err = s.ExecuteUploadFlow(fileObj)
 	if err != nil {
@@ -116,11 +247,6 @@ func (s *Service) GetMetadata(objectKey string) (*types.MetadataEntry, error) {
 	return s.metadataService.GetMetadata(objectKey)
 }
 
-// DeleteMetadata 删除对象元数据
-func (s *Service) DeleteMetadata(objectKey string) error {
-	return s.metadataService.DeleteMetadata(objectKey)
-}
-
 // ListMetadata 列出对象元数据
 func (s *Service) ListMetadata(limit, offset int) ([]*types.MetadataEntry, error) {
 	return s.metadataService.ListMetadata(limit, offset)
@@ -131,13 +257,124 @@ func (s *Service) ReadFromStg1OrThirdParty(objectKey string) (*types.FileObject,
 	return s.storageManager.ReadFromStg1OrThirdParty(objectKey)
 }
 
-// EnqueueDeleteTask 将删除任务加入队列
-func (s *Service) EnqueueDeleteTask(objectKey string) error {
+// ReadObject 读取对象并按元数据中的加密信息解密
+func (s *Service) ReadObject(entry *types.MetadataEntry, customerKey []byte) (*types.FileObject, error) {
+	storageKey := types.StorageKey(entry.Key, entry.VersionID)
+	stored, err := s.storageManager.ReadObject(storageKey, entry.ID+":"+entry.MD5Hash, entry.StorageNodes)
+	if err != nil {
+		stored, err = s.readFromOrigin(entry, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 记录访问时间供分层使用，按小时粒度更新以减少写入
+	if time.Since(entry.LastAccessedAt) > lastAccessResolution {
+		if err := s.metadataService.TouchLastAccess(entry.Key); err != nil {
+			fmt.Printf("Warning: failed to record last access for %s: %v\n", entry.Key, err)
+		}
+	}
+
+	return s.decodeStored(entry, stored, customerKey)
+}
+
+// readFromOrigin 本地副本全部读取失败时从源站读取对象，localErr为本地读取的错误
+// 源站只保存未加密对象的当前内容：带版本ID或加密的对象不回源，
+// 源站内容与元数据的MD5不一致（源站上的对象已被修改）时同样返回本地读取的错误
+func (s *Service) readFromOrigin(entry *types.MetadataEntry, localErr error) (*types.FileObject, error) {
+	bucket, _ := splitObjectKey(entry.Key)
+	if entry.VersionID != "" || entry.Encryption != types.EncryptionNone || !s.HasOrigin(bucket) {
+		return nil, localErr
+	}
+
+	fmt.Printf("All local replicas of %s failed, reading from origin\n", entry.Key)
+	obj, err := s.storageManager.FetchFromThirdParty(entry.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%v; origin fallback failed: %v", localErr, err)
+	}
+	if calculated := utils.CalculateMD5(obj.Data); calculated != entry.MD5Hash {
+		return nil, fmt.Errorf("%v; origin content of %s does not match (MD5 %s, expected %s)",
+			localErr, entry.Key, calculated, entry.MD5Hash)
+	}
+	return obj, nil
+}
+
+// readLocalObject 只从本地存储节点读取对象并解密，不经过读缓存和源站回源
+// 用于写回源站：本地副本缺失时返回错误，而不是把源站上的数据再写回源站
+func (s *Service) readLocalObject(entry *types.MetadataEntry) (*types.FileObject, error) {
+	storageKey := types.StorageKey(entry.Key, entry.VersionID)
+	stored, err := s.storageManager.ReadFromLocalNodes(storageKey, entry.StorageNodes)
+	if err != nil {
+		return nil, err
+	}
+	return s.decodeStored(entry, stored, nil)
+}
+
+// decodeStored 将节点上存储的数据还原为对象：恢复对象key并解密
+func (s *Service) decodeStored(entry *types.MetadataEntry, stored *types.FileObject, customerKey []byte) (*types.FileObject, error) {
+	// 节点上的key带有版本后缀，返回对象key本身（不修改缓存中的对象）
+	fileObj := *stored
+	fileObj.Key = entry.Key
+
+	decrypted, err := s.storageManager.DecryptObject(&fileObj, entry, customerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 节点上不保存内容类型，以元数据为准
+	decrypted.ContentType = entry.ContentType
+	return decrypted, nil
+}
+
+// 源站同步操作类型
+const (
+	originSyncPut    = "put"
+	originSyncDelete = "delete"
+)
+
+// originWriteMode 获取上传对象需要使用的源站写入模式
+func (s *Service) originWriteMode(fileObj *types.FileObject) string {
+	// 已与源站一致的对象（例如回源获取的对象）不再写回
+	if fileObj.SyncStatus == types.SyncStatusSynced {
+		return storage.WriteModeNone
+	}
+
+	bucket, _ := splitObjectKey(fileObj.Key)
+	mode := s.storageManager.OriginWriteMode(bucket)
+
+	// SSE-C对象在异步任务中无法解密，只能在请求期间同步写入
+	if mode == storage.WriteModeBack && fileObj.Encryption == types.EncryptionSSEC {
+		return storage.WriteModeThrough
+	}
+	return mode
+}
+
+// PropagateDelete 将删除操作传播到源站
+// write-through模式同步删除，write-back模式加入队列异步删除
+func (s *Service) PropagateDelete(objectKey string) error {
+	bucket, _ := splitObjectKey(objectKey)
+
+	switch s.storageManager.OriginWriteMode(bucket) {
+	case storage.WriteModeThrough:
+		err := s.storageManager.DeleteFromThirdParty(objectKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete from origin: %v", err)
+		}
+	case storage.WriteModeBack:
+		return s.enqueueOriginSync(objectKey, originSyncDelete)
+	}
+
+	return nil
+}
+
+// enqueueOriginSync 将源站同步任务加入队列
+func (s *Service) enqueueOriginSync(objectKey, operation string) error {
 	task := &types.TaskMessage{
-		Type:     "delete_from_storage",
+		Type:     types.TaskTypeOriginSync,
 		ObjectID: objectKey,
 		Data: map[string]any{
-			"key": objectKey,
+			"key":       objectKey,
+			"operation": operation,
 		},
 		CreatedAt: time.Now(),
 	}
@@ -145,12 +382,105 @@ func (s *Service) EnqueueDeleteTask(objectKey string) error {
 	return s.queueManager.Enqueue(task)
 }
 
+// SyncToOrigin 执行源站同步任务（由队列工作节点调用）
+// ctx已取消时不再访问源站，任务按失败处理并重试
+func (s *Service) SyncToOrigin(ctx context.Context, objectKey, operation string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if operation == originSyncDelete {
+		return s.storageManager.DeleteFromThirdParty(objectKey)
+	}
+
+	entry, err := s.metadataService.GetMetadata(objectKey)
+	if err != nil {
+		// 对象已被删除，删除操作会单独同步
+		fmt.Printf("Skipping origin sync for %s: %v\n", objectKey, err)
+		return nil
+	}
+
+	fileObj, err := s.readLocalObject(entry)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		fileObj.Key = entry.Key
+		err = s.storageManager.PutToThirdParty(fileObj)
+	}
+	if err != nil {
+		s.metadataService.UpdateSyncStatus(objectKey, types.SyncStatusFailed, err.Error())
+		return fmt.Errorf("failed to sync %s to origin: %v", objectKey, err)
+	}
+
+	return s.metadataService.UpdateSyncStatus(objectKey, types.SyncStatusSynced, "")
+}
+
+// RetryOriginSync 重新将对象加入源站同步队列
+func (s *Service) RetryOriginSync(objectKey string) error {
+	entry, err := s.metadataService.GetMetadata(objectKey)
+	if err != nil {
+		return err
+	}
+
+	if entry.SyncStatus == types.SyncStatusNone || entry.SyncStatus == types.SyncStatusSynced {
+		return fmt.Errorf("object %s is not pending origin sync", objectKey)
+	}
+
+	err = s.metadataService.UpdateSyncStatus(objectKey, types.SyncStatusPending, "")
+	if err != nil {
+		return err
+	}
+
+	return s.enqueueOriginSync(objectKey, originSyncPut)
+}
+
+// ListUnsynced 列出尚未同步到源站的对象
+func (s *Service) ListUnsynced(limit, offset int) ([]*types.MetadataEntry, error) {
+	return s.metadataService.ListUnsynced(limit, offset)
+}
+
+// splitObjectKey 将完整key拆分为bucket和对象key
+func splitObjectKey(objectKey string) (string, string) {
+	if idx := strings.Index(objectKey, "/"); idx >= 0 {
+		return objectKey[:idx], objectKey[idx+1:]
+	}
+	return "", objectKey
+}
+
 // GetStats 获取统计信息
 func (s *Service) GetStats() (map[string]any, error) {
-	return s.metadataService.GetStats()
+	stats, err := s.metadataService.GetStats()
+	if err != nil {
+		return nil, err
+	}
+
+	if hedgeStats := s.storageManager.GetHedgeStats(); hedgeStats != nil {
+		stats["hedged_read"] = hedgeStats
+	}
+
+	if cacheStats := s.storageManager.GetCacheStats(); cacheStats != nil {
+		stats["cache"] = cacheStats
+	}
+
+	if thirdPartyStats := s.storageManager.GetThirdPartyStats(); thirdPartyStats != nil {
+		stats["third_party"] = thirdPartyStats
+	}
+
+	return stats, nil
+}
+
+// SearchMetadata 按结构化条件搜索元数据
+func (s *Service) SearchMetadata(query *types.SearchQuery) (*types.SearchResult, error) {
+	return s.metadataService.SearchMetadata(query)
+}
+
+// ExportMetadata 以NDJSON流式导出元数据目录
+func (s *Service) ExportMetadata(w io.Writer, filter types.CatalogFilter) (int, error) {
+	return s.metadataService.ExportMetadata(w, filter)
 }
 
-// SearchMetadata 搜索元数据
-func (s *Service) SearchMetadata(query string, limit int) ([]*types.MetadataEntry, error) {
-	return s.metadataService.SearchMetadata(query, limit)
+// ImportMetadata 从NDJSON流导入元数据目录，引用未配置的存储节点的记录导入失败
+func (s *Service) ImportMetadata(r io.Reader, options types.ImportOptions) (*types.ImportReport, error) {
+	options.Nodes = s.storageManager.GetNodeIDs()
+	return s.metadataService.ImportMetadata(r, options)
 }
@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"fmt"
+	"slices"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+)
+
+// MoveStorageClass 将对象的当前版本迁移到目标存储类别（由分层任务调用）
+// 与上传和删除一样在对象锁内执行：先复制到目标节点组，再在事务中更新元数据，失败时撤销复制；
+// 提交后仍在锁内删除旧节点上的副本，不会删除同一key随后上传写入的副本。
+// entry为扫描时读取的元数据，对象在此之后被覆盖、删除或迁移时返回errConcurrentUpdate
+func (s *Service) MoveStorageClass(entry *types.MetadataEntry, toClass string) error {
+	objectKey := entry.Key
+	defer s.lockObject(objectKey)()
+
+	var current *types.MetadataEntry
+	var newNodes []string
+	storageKey := types.StorageKey(entry.Key, entry.VersionID)
+	err := s.runLocked(func(nodes *storage.NodeTransaction) error {
+		current = currentEntry(s.metadataService, objectKey)
+		if !sameStorageClass(current, entry) {
+			return errConcurrentUpdate
+		}
+
+		var err error
+		newNodes, err = nodes.CopyToClass(storageKey, current.StorageNodes, toClass)
+		return err
+	}, func(meta *metadata.MetaService) error {
+		if !sameStorageClass(currentEntry(meta, objectKey), current) {
+			return errConcurrentUpdate
+		}
+		return meta.UpdateStorageClass(objectKey, current.ID, toClass, newNodes)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 元数据提交后再删除旧副本，中途失败时只会留下多余的副本
+	staleNodes := slices.DeleteFunc(slices.Clone(current.StorageNodes), func(nodeID string) bool {
+		return slices.Contains(newNodes, nodeID)
+	})
+	if err := s.storageManager.DeleteFromNodes(storageKey, staleNodes); err != nil {
+		fmt.Printf("[TIERING] Warning: failed to remove old copies of %s: %v\n", objectKey, err)
+	}
+	return nil
+}
+
+// sameStorageClass 判断对象仍是同一次写入且存储类别和节点没有变化
+func sameStorageClass(a, b *types.MetadataEntry) bool {
+	return sameEntry(a, b) && a != nil &&
+		a.StorageClass == b.StorageClass && slices.Equal(a.StorageNodes, b.StorageNodes)
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/types"
+)
+
+// newTieredHandler 创建STANDARD使用stg1、stg2，COLD使用stg3的处理器
+func newTieredHandler(t *testing.T, store metadata.MetadataStore) (*Handler, http.Handler) {
+	t.Helper()
+	handler, router := newTestHandler(t, store)
+	storageManager := handler.service.storageManager
+	if err := storageManager.SetStorageClass(types.StorageClassStandard, []string{"stg1", "stg2"}); err != nil {
+		t.Fatalf("configure standard class: %v", err)
+	}
+	if err := storageManager.SetStorageClass(types.StorageClassCold, []string{"stg3"}); err != nil {
+		t.Fatalf("configure cold class: %v", err)
+	}
+	return handler, router
+}
+
+func putObject(t *testing.T, router http.Handler, path, body string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT %s: %d %s", path, rec.Code, rec.Body.String())
+	}
+}
+
+func TestMoveStorageClassUpdatesVersionHistory(t *testing.T) {
+	store := metadata.NewMemoryStore()
+	if err := store.SetBucketVersioning("versioned", types.VersioningEnabled); err != nil {
+		t.Fatalf("enable versioning: %v", err)
+	}
+	handler, router := newTieredHandler(t, store)
+	putObject(t, router, "/versioned/object", "cold data")
+
+	entry, err := store.GetMetadata("versioned/object")
+	if err != nil {
+		t.Fatalf("get metadata: %v", err)
+	}
+	if err := handler.service.MoveStorageClass(entry, types.StorageClassCold); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	version, err := store.GetVersion(entry.Key, entry.VersionID)
+	if err != nil {
+		t.Fatalf("get version: %v", err)
+	}
+	if version.StorageClass != types.StorageClassCold || !slices.Equal(version.StorageNodes, []string{"stg3"}) {
+		t.Errorf("version history has class %s on %v, want COLD on [stg3]", version.StorageClass, version.StorageNodes)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/versioned/object", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "cold data" {
+		t.Errorf("GET after move = %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMoveStorageClassKeepsOverwrittenObject(t *testing.T) {
+	store := metadata.NewMemoryStore()
+	handler, router := newTieredHandler(t, store)
+	putObject(t, router, "/plain/object", "old data")
+
+	scanned, err := store.GetMetadata("plain/object")
+	if err != nil {
+		t.Fatalf("get metadata: %v", err)
+	}
+
+	// 分层扫描之后、迁移之前对象被覆盖，迁移不能删除新写入的副本
+	putObject(t, router, "/plain/object", "new data")
+	if err := handler.service.MoveStorageClass(scanned, types.StorageClassCold); !errors.Is(err, errConcurrentUpdate) {
+		t.Fatalf("move of overwritten object = %v, want errConcurrentUpdate", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/plain/object", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "new data" {
+		t.Errorf("GET after rejected move = %d %q, want new data", rec.Code, rec.Body.String())
+	}
+	for _, nodeID := range []string{"stg1", "stg2"} {
+		if _, err := handler.service.storageManager.ReadFromNode(context.Background(), nodeID, "plain/object"); err != nil {
+			t.Errorf("replica on %s removed: %v", nodeID, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package s3
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListUnsyncedAPI 列出尚未同步到源站的对象（pending或failed）
+func (h *Handler) ListUnsyncedAPI(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "100")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		offset = 0
+	}
+
+	entries, err := h.service.ListUnsynced(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	objects := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		objects = append(objects, gin.H{
+			"key":         entry.Key,
+			"size":        entry.Size,
+			"sync_status": entry.SyncStatus,
+			"sync_error":  entry.SyncError,
+			"updated_at":  entry.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"objects": objects,
+		"total":   len(objects),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// RetrySyncAPI 重新将对象加入源站同步队列
+func (h *Handler) RetrySyncAPI(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'key' is required"})
+		return
+	}
+
+	err := h.service.RetryOriginSync(key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Origin sync task enqueued",
+	})
+}
+
+// ScrubAPI 校验对象副本
+// 指定key时同步校验并返回结果（async=true时加入队列），未指定key时在后台校验所有对象
+func (h *Handler) ScrubAPI(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		if err := h.service.StartScrubAll(); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "Scrub started"})
+		return
+	}
+
+	if c.Query("async") == "true" {
+		if err := h.service.EnqueueScrubTask(key); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "Replication check task enqueued"})
+		return
+	}
+
+	result, err := h.service.ScrubObject(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ScrubStatusAPI 获取全量副本校验状态
+func (h *Handler) ScrubStatusAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.GetScrubStatus())
+}
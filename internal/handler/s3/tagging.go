@@ -0,0 +1,248 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// S3对象标签相关请求头
+const (
+	headerTagging          = "x-amz-tagging"
+	headerTaggingCount     = "x-amz-tagging-count"
+	headerTaggingDirective = "x-amz-tagging-directive"
+
+	// aws:前缀的标签由S3保留，用户不能设置
+	reservedTagPrefix = "aws:"
+)
+
+// errDeleteMarkerVersion 指定的版本是删除标记，不能设置标签
+var errDeleteMarkerVersion = errors.New("the specified version is a delete marker")
+
+// validateTags 校验标签数量以及标签名和值的长度
+func validateTags(tags map[string]string) error {
+	if len(tags) > types.MaxObjectTags {
+		return fmt.Errorf("object tags cannot be greater than %d", types.MaxObjectTags)
+	}
+	for key, value := range tags {
+		if key == "" || utf8.RuneCountInString(key) > types.MaxTagKeyLength {
+			return fmt.Errorf("tag key must be between 1 and %d characters: %q", types.MaxTagKeyLength, key)
+		}
+		if utf8.RuneCountInString(value) > types.MaxTagValueLength {
+			return fmt.Errorf("tag value of %q exceeds %d characters", key, types.MaxTagValueLength)
+		}
+		if strings.HasPrefix(strings.ToLower(key), reservedTagPrefix) {
+			return fmt.Errorf("tag key cannot start with %q: %q", reservedTagPrefix, key)
+		}
+	}
+	return nil
+}
+
+// parseTagSet 将Tagging请求体中的标签转换为map，标签名不能重复
+func parseTagSet(tagSet []types.Tag) (map[string]string, error) {
+	tags := make(map[string]string, len(tagSet))
+	for _, tag := range tagSet {
+		if _, exists := tags[tag.Key]; exists {
+			return nil, fmt.Errorf("duplicate tag key: %q", tag.Key)
+		}
+		tags[tag.Key] = tag.Value
+	}
+	if err := validateTags(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// parseTaggingHeader 解析上传请求的x-amz-tagging（URL查询参数格式，如 project=a&class=b），
+// 未携带时返回nil
+func parseTaggingHeader(c *gin.Context) (map[string]string, error) {
+	header := c.GetHeader(headerTagging)
+	if header == "" {
+		return nil, nil
+	}
+
+	values, err := url.ParseQuery(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tagging header encoding: %v", err)
+	}
+
+	tags := make(map[string]string, len(values))
+	for key, value := range values {
+		if len(value) > 1 {
+			return nil, fmt.Errorf("duplicate tag key: %q", key)
+		}
+		tags[key] = value[0]
+	}
+	if err := validateTags(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// newTagging 将标签转换为Tagging响应，按标签名排序保证响应稳定
+func newTagging(tags map[string]string) types.Tagging {
+	tagging := types.Tagging{TagSet: make([]types.Tag, 0, len(tags))}
+	for key, value := range tags {
+		tagging.TagSet = append(tagging.TagSet, types.Tag{Key: key, Value: value})
+	}
+	sort.Slice(tagging.TagSet, func(i, j int) bool { return tagging.TagSet[i].Key < tagging.TagSet[j].Key })
+	return tagging
+}
+
+// setTaggingCountHeader 在GET/HEAD响应中返回对象的标签数量
+func setTaggingCountHeader(c *gin.Context, tags map[string]string) {
+	if len(tags) > 0 {
+		c.Header(headerTaggingCount, strconv.Itoa(len(tags)))
+	}
+}
+
+// SetObjectTags 替换对象当前版本或versionID指定版本的标签，tags为空时删除全部标签
+// 当前版本同时存在于版本历史中时一并更新，返回被修改的版本
+func (s *Service) SetObjectTags(objectKey, versionID string, tags map[string]string) (*types.MetadataEntry, error) {
+	var target *types.MetadataEntry
+	err := s.metadataService.WithTransaction(func(meta *metadata.MetaService) error {
+		current, err := meta.GetMetadata(objectKey)
+		if err == nil && (versionID == "" || types.NormalizeVersionID(current.VersionID) == versionID) {
+			target = current
+			if err := meta.SetTags(objectKey, current.ID, tags); err != nil {
+				return err
+			}
+			if current.VersionID == "" {
+				return nil
+			}
+			versionID = current.VersionID
+		} else if versionID == "" {
+			return fmt.Errorf("%w: %v", errObjectNotFound, err)
+		}
+
+		version, err := meta.GetVersion(objectKey, versionID)
+		if err != nil {
+			if target != nil {
+				return nil
+			}
+			return fmt.Errorf("%w: %v", errObjectNotFound, err)
+		}
+		if version.DeleteMarker {
+			return errDeleteMarkerVersion
+		}
+
+		version.Tags = tags
+		if target == nil {
+			target = version
+		}
+		return meta.SaveVersion(version)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	target.Tags = tags
+	return target, nil
+}
+
+// GetObjectTagging 处理GET /:bucket/:key?tagging请求，可通过versionId指定版本
+func (h *Handler) GetObjectTagging(c *gin.Context) {
+	objectKey := h.buildObjectKey(c.Param("bucket"), c.Param("key"))
+
+	var entry *types.MetadataEntry
+	if versionID, ok := c.GetQuery("versionId"); ok {
+		version, ok := h.getObjectVersion(c, objectKey, versionID)
+		if !ok {
+			return
+		}
+		entry = version
+	} else {
+		current, err := h.service.GetMetadata(objectKey)
+		if err != nil {
+			if h.respondDeleteMarker(c, objectKey) {
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": fmt.Sprintf("Object not found: %v", err),
+			})
+			return
+		}
+		entry = current
+	}
+
+	setVersionHeaders(c, entry)
+	c.XML(http.StatusOK, newTagging(entry.Tags))
+}
+
+// PutObjectTagging 处理PUT /:bucket/:key?tagging请求，请求体为Tagging，替换对象的全部标签
+func (h *Handler) PutObjectTagging(c *gin.Context) {
+	objectKey := h.buildObjectKey(c.Param("bucket"), c.Param("key"))
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read request body",
+		})
+		return
+	}
+
+	var tagging types.Tagging
+	if err := xml.Unmarshal(body, &tagging); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("MalformedXML: %v", err),
+		})
+		return
+	}
+
+	tags, err := parseTagSet(tagging.TagSet)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("InvalidTag: %v", err),
+		})
+		return
+	}
+
+	h.setObjectTags(c, objectKey, tags, http.StatusOK)
+}
+
+// DeleteObjectTagging 处理DELETE /:bucket/:key?tagging请求，删除对象的全部标签
+func (h *Handler) DeleteObjectTagging(c *gin.Context) {
+	objectKey := h.buildObjectKey(c.Param("bucket"), c.Param("key"))
+	h.setObjectTags(c, objectKey, nil, http.StatusNoContent)
+}
+
+// setObjectTags 替换标签并写入响应，成功时返回status
+func (h *Handler) setObjectTags(c *gin.Context, objectKey string, tags map[string]string, status int) {
+	versionID := c.Query("versionId")
+
+	entry, err := h.service.SetObjectTags(objectKey, versionID, tags)
+	if errors.Is(err, errObjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Object not found: %v", err),
+		})
+		return
+	}
+	if errors.Is(err, errDeleteMarkerVersion) {
+		c.Header("Allow", "DELETE")
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"error": "The specified method is not allowed against a delete marker",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to update tags: %v", err),
+		})
+		return
+	}
+
+	setVersionHeaders(c, entry)
+	c.Status(status)
+}
@@ -0,0 +1,63 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mock-storage/internal/queue"
+	"mock-storage/internal/types"
+)
+
+// RegisterTaskHandlers 注册由S3服务执行的任务处理器：origin_sync、replication_check、scrub、orphan_sweep
+func (s *Service) RegisterTaskHandlers(registry *queue.Registry) error {
+	if err := queue.RegisterTyped(registry, types.TaskTypeOriginSync, s.processOriginSync, queue.HandlerOptions{}); err != nil {
+		return err
+	}
+	if err := queue.RegisterTyped(registry, types.TaskTypeReplicationCheck, s.processReplicationCheck, queue.HandlerOptions{}); err != nil {
+		return err
+	}
+	// 全量校验和孤立文件清理同一时间只能运行一次
+	if err := registry.Register(types.TaskTypeScrub, s.processScrub, queue.HandlerOptions{Concurrency: 1}); err != nil {
+		return err
+	}
+	return queue.RegisterTyped(registry, types.TaskTypeOrphanSweep, s.processOrphanSweep, queue.HandlerOptions{Concurrency: 1})
+}
+
+// processOriginSync 处理源站同步任务（write-back模式）
+func (s *Service) processOriginSync(ctx context.Context, task *types.TaskMessage, payload *types.OriginSyncPayload) error {
+	fmt.Printf("[WORKER] Processing origin sync for object: %s\n", task.ObjectID)
+	return s.SyncToOrigin(ctx, payload.Key, payload.Operation)
+}
+
+// processReplicationCheck 处理副本检查任务
+func (s *Service) processReplicationCheck(ctx context.Context, task *types.TaskMessage, payload *types.ObjectTaskPayload) error {
+	fmt.Printf("[WORKER] Processing replication check for object: %s\n", task.ObjectID)
+
+	result, err := s.ScrubObject(ctx, payload.Key)
+	if err != nil {
+		return err
+	}
+
+	if !result.Healthy {
+		return fmt.Errorf("object %s has unrecoverable replicas: %v", payload.Key, result.Replicas)
+	}
+
+	return nil
+}
+
+// processScrub 校验并修复所有对象的副本
+func (s *Service) processScrub(ctx context.Context, task *types.TaskMessage) error {
+	fmt.Println("[WORKER] Processing full scrub")
+	return s.ScrubAll(ctx)
+}
+
+// processOrphanSweep 删除各本地存储节点上没有元数据引用的对象文件
+func (s *Service) processOrphanSweep(ctx context.Context, task *types.TaskMessage, payload *types.OrphanSweepPayload) error {
+	olderThan := defaultOrphanAge
+	if payload.OlderThan > 0 {
+		olderThan = time.Duration(payload.OlderThan) * time.Second
+	}
+	_, err := s.SweepOrphans(olderThan, payload.DryRun)
+	return err
+}
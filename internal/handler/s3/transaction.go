@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/storage"
+)
+
+// errConcurrentUpdate 修改节点期间对象的元数据被其他请求修改
+var errConcurrentUpdate = errors.New("object was modified concurrently")
+
+// objectLockStripes 对象锁的分段数，不同key可能共用一个锁
+const objectLockStripes = 64
+
+// objectLocks 按对象key分段的锁
+type objectLocks [objectLockStripes]sync.Mutex
+
+// lockObject 锁定对象key，返回解锁函数
+// 同一对象的上传和删除依次执行，避免节点上的数据和最终提交的元数据来自不同的请求
+func (s *Service) lockObject(objectKey string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(objectKey))
+	mu := &s.objectLocks[h.Sum32()%objectLockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// runTransaction 在对象锁内协调一次上传或删除：
+// modifyNodes通过nodes修改存储节点（记录原有数据），在数据库事务之外执行，可以包含源站请求等耗时操作；
+// commitMetadata随后在一个只包含元数据读写的数据库事务中执行。任一步返回错误或事务提交失败时
+// 回滚元数据并撤销节点修改，不会留下没有元数据的文件或指向不存在文件的元数据。
+// commitMetadata需要确认modifyNodes所依据的元数据没有被不经过对象锁的写入修改，否则返回errConcurrentUpdate
+func (s *Service) runTransaction(objectKey string, modifyNodes func(nodes *storage.NodeTransaction) error, commitMetadata func(meta *metadata.MetaService) error) error {
+	defer s.lockObject(objectKey)()
+	return s.runLocked(modifyNodes, commitMetadata)
+}
+
+// runLocked 与runTransaction相同，但调用方已持有对象锁，用于提交后仍需在锁内继续修改节点的操作
+func (s *Service) runLocked(modifyNodes func(nodes *storage.NodeTransaction) error, commitMetadata func(meta *metadata.MetaService) error) error {
+	nodes := s.storageManager.BeginNodeTransaction()
+
+	err := modifyNodes(nodes)
+	if err == nil {
+		err = s.metadataService.WithTransaction(commitMetadata)
+	}
+	if err != nil {
+		if rollbackErr := nodes.Rollback(); rollbackErr != nil {
+			fmt.Printf("Warning: storage node rollback incomplete: %v\n", rollbackErr)
+		}
+		return err
+	}
+
+	nodes.Commit()
+	return nil
+}
@@ -0,0 +1,320 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"mock-storage/internal/metadata"
+	"mock-storage/internal/storage"
+	"mock-storage/internal/types"
+	"mock-storage/internal/wal"
+
+	"github.com/google/uuid"
+)
+
+// errObjectNotFound 要删除的对象或版本不存在
+var errObjectNotFound = errors.New("object not found")
+
+// GetBucketVersioning 获取bucket的版本控制状态
+func (s *Service) GetBucketVersioning(bucket string) (string, error) {
+	return s.metadataService.GetBucketVersioning(bucket)
+}
+
+// SetBucketVersioning 设置bucket的版本控制状态（Enabled/Suspended）
+func (s *Service) SetBucketVersioning(bucket, status string) error {
+	return s.metadataService.SetBucketVersioning(bucket, status)
+}
+
+// ListVersions 列出key以prefix开头的所有对象版本和删除标记
+func (s *Service) ListVersions(prefix string, limit int) ([]*types.MetadataEntry, error) {
+	return s.metadataService.ListVersions(prefix, limit)
+}
+
+// bucketVersioning 获取对象所在bucket的版本控制状态，查询失败时按未启用处理
+func (s *Service) bucketVersioning(objectKey string) string {
+	bucket, _ := splitObjectKey(objectKey)
+	status, err := s.metadataService.GetBucketVersioning(bucket)
+	if err != nil {
+		fmt.Printf("Warning: failed to get versioning status of bucket %s: %v\n", bucket, err)
+		return types.VersioningUnversioned
+	}
+	return status
+}
+
+// assignVersionID 根据bucket的版本控制状态为新写入的对象分配版本ID
+// 启用时使用对象ID，暂停时为null版本，未启用时为空
+func (s *Service) assignVersionID(fileObj *types.FileObject) string {
+	status := s.bucketVersioning(fileObj.Key)
+	switch status {
+	case types.VersioningEnabled:
+		fileObj.VersionID = fileObj.ID
+	case types.VersioningSuspended:
+		fileObj.VersionID = types.NullVersionID
+	default:
+		fileObj.VersionID = ""
+	}
+	return status
+}
+
+// GetObjectVersion 获取对象的指定版本，versionID为当前版本时返回当前元数据
+func (s *Service) GetObjectVersion(objectKey, versionID string) (*types.MetadataEntry, error) {
+	current, err := s.metadataService.GetMetadata(objectKey)
+	if err == nil && types.NormalizeVersionID(current.VersionID) == versionID {
+		return current, nil
+	}
+	return s.metadataService.GetVersion(objectKey, versionID)
+}
+
+// GetLatestDeleteMarker 对象没有当前版本且最新版本为删除标记时返回该标记
+func (s *Service) GetLatestDeleteMarker(objectKey string) *types.MetadataEntry {
+	latest, err := s.metadataService.GetLatestVersion(objectKey)
+	if err != nil || !latest.DeleteMarker {
+		return nil
+	}
+	return latest
+}
+
+// CreateDeleteMarker 在版本控制bucket中删除对象：保留已有版本，写入删除标记作为最新版本
+// 暂停版本控制时删除标记为null版本，会替换并删除已有的null版本
+func (s *Service) CreateDeleteMarker(objectKey, status string) (*types.MetadataEntry, error) {
+	marker := &types.MetadataEntry{
+		ID:           uuid.New().String(),
+		Key:          objectKey,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		DeleteMarker: true,
+	}
+	marker.VersionID = marker.ID
+	if status == types.VersioningSuspended {
+		marker.VersionID = types.NullVersionID
+	}
+
+	txID, err := s.beginOperation(wal.OpDelete, objectKey, marker, map[string]string{
+		"mode":   deleteModeMarker,
+		"status": status,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.createDeleteMarker(marker, status)
+	s.finishOperation(txID, err)
+	if err != nil {
+		return nil, err
+	}
+	return marker, nil
+}
+
+// createDeleteMarker 写入删除标记，恢复未完成的删除时使用操作日志中记录的同一个标记
+func (s *Service) createDeleteMarker(marker *types.MetadataEntry, status string) error {
+	objectKey := marker.Key
+	var current, previous *types.MetadataEntry
+	err := s.runTransaction(objectKey, func(nodes *storage.NodeTransaction) error {
+		current = currentEntry(s.metadataService, objectKey)
+		if status != types.VersioningSuspended {
+			return nil
+		}
+
+		// 被替换的null版本的数据随之删除
+		previous = nullVersion(s.metadataService, objectKey, current)
+		if previous != nil && !previous.DeleteMarker {
+			return deleteVersionData(nodes, previous)
+		}
+		return nil
+	}, func(meta *metadata.MetaService) error {
+		if !sameEntry(currentEntry(meta, objectKey), current) {
+			return errConcurrentUpdate
+		}
+		if status == types.VersioningSuspended && !sameEntry(nullVersion(meta, objectKey, current), previous) {
+			return errConcurrentUpdate
+		}
+
+		if _, err := meta.ArchiveCurrentVersion(objectKey); err != nil {
+			return err
+		}
+		if err := meta.SaveVersion(marker); err != nil {
+			return err
+		}
+
+		if current != nil {
+			return meta.DeleteMetadata(objectKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.storageManager.InvalidateCache(objectKey)
+	fmt.Printf("Created delete marker %s for key: %s\n", marker.VersionID, objectKey)
+	return nil
+}
+
+// currentEntry 返回对象的当前版本，不存在时返回nil
+func currentEntry(meta *metadata.MetaService, objectKey string) *types.MetadataEntry {
+	current, err := meta.GetMetadata(objectKey)
+	if err != nil {
+		return nil
+	}
+	return current
+}
+
+// nullVersion 返回对象的null版本，当前版本是null版本时即为当前版本，不存在时返回nil
+func nullVersion(meta *metadata.MetaService, objectKey string, current *types.MetadataEntry) *types.MetadataEntry {
+	if current != nil && types.NormalizeVersionID(current.VersionID) == types.NullVersionID {
+		return current
+	}
+	previous, err := meta.GetVersion(objectKey, types.NullVersionID)
+	if err != nil {
+		return nil
+	}
+	return previous
+}
+
+// sameEntry 判断两次读取的元数据是否是同一次写入的对象（都不存在时也视为相同）
+func sameEntry(a, b *types.MetadataEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID && a.DeleteMarker == b.DeleteMarker
+}
+
+// DeleteObjectVersion 永久删除对象的指定版本（或删除标记）
+// 删除的是当前版本时，由剩余最新的非删除标记版本成为当前版本
+func (s *Service) DeleteObjectVersion(objectKey, versionID string) (*types.MetadataEntry, error) {
+	txID, err := s.beginOperation(wal.OpDelete, objectKey, nil, map[string]string{
+		"mode":       deleteModeVersion,
+		"version_id": versionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.deleteObjectVersion(objectKey, versionID)
+	s.finishOperation(txID, err)
+	return deleted, err
+}
+
+// deleteObjectVersion 删除版本在节点上的数据，再在事务中删除版本的元数据
+func (s *Service) deleteObjectVersion(objectKey, versionID string) (*types.MetadataEntry, error) {
+	var deleted *types.MetadataEntry
+	var isCurrent bool
+	err := s.runTransaction(objectKey, func(nodes *storage.NodeTransaction) error {
+		var err error
+		deleted, isCurrent, err = findVersion(s.metadataService, objectKey, versionID)
+		if err != nil {
+			return err
+		}
+		if deleted.DeleteMarker {
+			return nil
+		}
+		return deleteVersionData(nodes, deleted)
+	}, func(meta *metadata.MetaService) error {
+		entry, stillCurrent, err := findVersion(meta, objectKey, versionID)
+		if err != nil || !sameEntry(entry, deleted) || stillCurrent != isCurrent {
+			return errConcurrentUpdate
+		}
+
+		if isCurrent {
+			if err := meta.DeleteMetadata(objectKey); err != nil {
+				return err
+			}
+			// 当前版本可能同时存在于版本历史中
+			meta.DeleteVersion(objectKey, versionID)
+		} else if err := meta.DeleteVersion(objectKey, versionID); err != nil {
+			return err
+		}
+
+		if currentEntry(meta, objectKey) == nil {
+			return promoteLatestVersion(meta, objectKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.storageManager.InvalidateCache(objectKey)
+	return deleted, nil
+}
+
+// findVersion 查找对象的指定版本，isCurrent表示该版本是否为当前版本
+func findVersion(meta *metadata.MetaService, objectKey, versionID string) (entry *types.MetadataEntry, isCurrent bool, err error) {
+	current := currentEntry(meta, objectKey)
+	if current != nil && types.NormalizeVersionID(current.VersionID) == versionID {
+		return current, true, nil
+	}
+	entry, err = meta.GetVersion(objectKey, versionID)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", errObjectNotFound, err)
+	}
+	return entry, false, nil
+}
+
+// promoteLatestVersion 对象没有当前版本时，最新版本不是删除标记则将其恢复为当前版本
+func promoteLatestVersion(meta *metadata.MetaService, objectKey string) error {
+	latest, err := meta.GetLatestVersion(objectKey)
+	if err != nil || latest.DeleteMarker {
+		return nil
+	}
+	return meta.PromoteVersion(latest)
+}
+
+// deleteVersionData 删除对象版本在存储节点上的数据
+func deleteVersionData(nodes *storage.NodeTransaction, entry *types.MetadataEntry) error {
+	return nodes.DeleteFromNodes(types.StorageKey(entry.Key, entry.VersionID), entry.StorageNodes)
+}
+
+// DeleteObject 删除对象的当前版本：未启用版本控制时删除元数据和节点上的数据，
+// 否则写入删除标记并返回该标记
+func (s *Service) DeleteObject(objectKey string) (*types.MetadataEntry, error) {
+	if err := types.ValidateObjectKey(objectKey); err != nil {
+		return nil, err
+	}
+
+	status := s.bucketVersioning(objectKey)
+	if status != types.VersioningUnversioned {
+		return s.CreateDeleteMarker(objectKey, status)
+	}
+
+	current, err := s.metadataService.GetMetadata(objectKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errObjectNotFound, err)
+	}
+
+	txID, err := s.beginOperation(wal.OpDelete, objectKey, current, map[string]string{
+		"mode": deleteModeObject,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.deleteCurrentObject(objectKey)
+	s.finishOperation(txID, err)
+	return nil, err
+}
+
+// deleteCurrentObject 删除未启用版本控制的对象在节点上的数据，再在事务中删除其元数据
+func (s *Service) deleteCurrentObject(objectKey string) error {
+	var entry *types.MetadataEntry
+	err := s.runTransaction(objectKey, func(nodes *storage.NodeTransaction) error {
+		var err error
+		entry, err = s.metadataService.GetMetadata(objectKey)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errObjectNotFound, err)
+		}
+		return deleteVersionData(nodes, entry)
+	}, func(meta *metadata.MetaService) error {
+		if !sameEntry(currentEntry(meta, objectKey), entry) {
+			return errConcurrentUpdate
+		}
+		return meta.DeleteMetadata(objectKey)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.storageManager.InvalidateCache(objectKey)
+	return nil
+}
@@ -0,0 +1,77 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrBackupUnsupported 当前驱动不支持在线备份
+var ErrBackupUnsupported = errors.New("online backup is only supported for SQLite")
+
+// BackupTo 将数据库的一致快照写入path（VACUUM INTO）
+// 快照在一个读事务中生成，期间服务可以继续读写；path不能已存在
+func (dm *DatabaseManager) BackupTo(path string) error {
+	if dm.dialect.name != DriverSQLite {
+		return fmt.Errorf("%w, use pg_dump for PostgreSQL", ErrBackupUnsupported)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup target %s already exists", path)
+	}
+
+	if _, err := dm.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up database: %v", err)
+	}
+	return nil
+}
+
+// CheckIntegrity 检查SQLite数据库文件的完整性（PRAGMA integrity_check）
+func (dm *DatabaseManager) CheckIntegrity() error {
+	if dm.dialect.name != DriverSQLite {
+		return ErrBackupUnsupported
+	}
+
+	rows, err := dm.db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return fmt.Errorf("failed to check integrity: %v", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			return fmt.Errorf("failed to check integrity: %v", err)
+		}
+		if message != "ok" {
+			problems = append(problems, message)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check integrity: %v", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// SchemaVersion 获取已应用的最高迁移版本，只读取不创建迁移表，没有迁移记录时返回0
+func (dm *DatabaseManager) SchemaVersion() (int, error) {
+	var version int
+	err := dm.queryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	return version, nil
+}
+
+// SQLitePath 从SQLite连接串中取出数据库文件路径（去掉file:前缀和查询参数）
+func SQLitePath(dsn string) string {
+	path := strings.TrimPrefix(dsn, "file:")
+	if index := strings.IndexByte(path, '?'); index >= 0 {
+		path = path[:index]
+	}
+	return path
+}
@@ -0,0 +1,261 @@
+package metadata
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// ErrInvalidImport 导入选项无效
+var ErrInvalidImport = errors.New("invalid import options")
+
+// 导入时跳过和失败的原因
+const (
+	importReasonFiltered    = "outside filter"
+	importReasonExists      = "key exists"
+	importReasonNotNewer    = "existing entry is newer or equal"
+	importReasonInvalidJSON = "invalid json"
+	importReasonInvalid     = "invalid entry"
+	importReasonUnknownNode = "unknown storage node"
+	importReasonSave        = "save failed"
+)
+
+// ExportMetadata 将符合条件的所有当前对象元数据按key顺序以NDJSON（每行一条）写入w，返回导出的条数
+func (ms *MetaService) ExportMetadata(w io.Writer, filter types.CatalogFilter) (int, error) {
+	encoder := json.NewEncoder(w)
+	exported := 0
+	err := ms.WalkMetadata(filter, func(entry *types.MetadataEntry) error {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write metadata %s: %v", entry.Key, err)
+		}
+		exported++
+		return nil
+	})
+	return exported, err
+}
+
+// WalkMetadata 按key顺序遍历符合条件的所有当前对象元数据，fn返回错误时停止并返回该错误
+// 通过搜索游标分页读取，不会一次加载全部元数据
+func (ms *MetaService) WalkMetadata(filter types.CatalogFilter, fn func(entry *types.MetadataEntry) error) error {
+	query := &types.SearchQuery{
+		KeyPrefix:     catalogKeyPrefix(filter),
+		UpdatedAfter:  filter.UpdatedAfter,
+		UpdatedBefore: filter.UpdatedBefore,
+		SortBy:        types.SearchSortKey,
+		Limit:         maxSearchLimit,
+	}
+
+	for {
+		result, err := ms.SearchMetadata(query)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range result.Entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if result.NextCursor == "" {
+			return nil
+		}
+		query.Cursor = result.NextCursor
+	}
+}
+
+// ImportMetadata 从r逐行读取NDJSON格式的元数据并导入，单条记录的错误不会中止导入，
+// 而是计入结果；只有读取输入失败或选项无效时返回错误（此时结果包含已处理的部分）
+// 导入只写入元数据，存储节点上的数据需要已经存在
+func (ms *MetaService) ImportMetadata(r io.Reader, options types.ImportOptions) (*types.ImportReport, error) {
+	switch options.Conflict {
+	case "":
+		options.Conflict = types.ImportConflictSkip
+	case types.ImportConflictSkip, types.ImportConflictOverwrite, types.ImportConflictNewerWins:
+	default:
+		return nil, fmt.Errorf("%w: unsupported conflict policy %q", ErrInvalidImport, options.Conflict)
+	}
+
+	report := &types.ImportReport{
+		DryRun:   options.DryRun,
+		Conflict: options.Conflict,
+		Reasons:  make(map[string]int),
+		Issues:   []types.ImportIssue{},
+	}
+
+	reader := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return report, fmt.Errorf("failed to read line %d: %v", lineNumber, readErr)
+		}
+
+		if strings.TrimSpace(string(line)) != "" {
+			report.Total++
+			ms.importLine(line, lineNumber, options, report)
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	fmt.Printf("[META] Import finished (conflict=%s, dry_run=%v): %d imported, %d skipped, %d failed out of %d\n",
+		report.Conflict, report.DryRun, report.Imported, report.Skipped, report.Failed, report.Total)
+	return report, nil
+}
+
+// importLine 导入一行元数据并将结果计入report
+func (ms *MetaService) importLine(line []byte, lineNumber int, options types.ImportOptions, report *types.ImportReport) {
+	var entry types.MetadataEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		addImportIssue(report, lineNumber, "", "failed", importReasonInvalidJSON, err)
+		return
+	}
+
+	if err := ms.validateImportEntry(&entry); err != nil {
+		addImportIssue(report, lineNumber, entry.Key, "failed", importReasonInvalid, err)
+		return
+	}
+
+	if err := checkImportNodes(&entry, options.Nodes); err != nil {
+		addImportIssue(report, lineNumber, entry.Key, "failed", importReasonUnknownNode, err)
+		return
+	}
+
+	if !matchCatalogFilter(&entry, options.Filter) {
+		addImportIssue(report, lineNumber, entry.Key, "skipped", importReasonFiltered, nil)
+		return
+	}
+
+	existing, err := ms.db.GetMetadata(entry.Key)
+	if err == nil {
+		switch options.Conflict {
+		case types.ImportConflictSkip:
+			addImportIssue(report, lineNumber, entry.Key, "skipped", importReasonExists, nil)
+			return
+		case types.ImportConflictNewerWins:
+			if !entry.UpdatedAt.After(existing.UpdatedAt) {
+				addImportIssue(report, lineNumber, entry.Key, "skipped", importReasonNotNewer,
+					fmt.Errorf("existing updated at %s", existing.UpdatedAt.Format(time.RFC3339Nano)))
+				return
+			}
+		}
+	}
+
+	if !options.DryRun {
+		if err := ms.saveImportedEntry(&entry, existing); err != nil {
+			addImportIssue(report, lineNumber, entry.Key, "failed", importReasonSave, err)
+			return
+		}
+	}
+	report.Imported++
+}
+
+// saveImportedEntry 与上传相同地保存导入的元数据：版本控制bucket中被覆盖的当前版本写入版本历史，
+// 带版本ID的记录同时写入版本历史。导入的正是已有的同一个对象时不产生新的历史版本
+func (ms *MetaService) saveImportedEntry(entry, existing *types.MetadataEntry) error {
+	bucket, _, _ := strings.Cut(entry.Key, "/")
+	status, err := ms.db.GetBucketVersioning(bucket)
+	if err != nil {
+		return fmt.Errorf("failed to get bucket versioning: %v", err)
+	}
+
+	return ms.WithTransaction(func(tx *MetaService) error {
+		if status != types.VersioningUnversioned && existing != nil && existing.ID != entry.ID {
+			if _, err := tx.ArchiveCurrentVersion(entry.Key); err != nil {
+				return err
+			}
+		}
+		return tx.SaveEntry(entry)
+	})
+}
+
+// checkImportNodes 检查元数据引用的存储节点都已配置，nodes为空时不检查
+func checkImportNodes(entry *types.MetadataEntry, nodes []string) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	var unknown []string
+	for _, nodeID := range entry.StorageNodes {
+		if !slices.Contains(nodes, nodeID) {
+			unknown = append(unknown, nodeID)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%s not configured", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// validateImportEntry 校验导入的元数据：key必须为bucket/key形式，且是可读取的当前对象
+func (ms *MetaService) validateImportEntry(entry *types.MetadataEntry) error {
+	bucket, key, found := strings.Cut(entry.Key, "/")
+	if !found || bucket == "" || key == "" {
+		return fmt.Errorf("key %q is not in bucket/key form", entry.Key)
+	}
+	if err := types.ValidateObjectKey(entry.Key); err != nil {
+		return err
+	}
+	if entry.ID == "" {
+		return fmt.Errorf("id cannot be empty")
+	}
+	if entry.DeleteMarker {
+		return fmt.Errorf("delete markers only exist in version history")
+	}
+	return ms.ValidateMetadata(entry)
+}
+
+// addImportIssue 记录一条跳过或失败的导入记录，明细超过上限后只计数
+func addImportIssue(report *types.ImportReport, lineNumber int, key, result, reason string, detail error) {
+	if result == "skipped" {
+		report.Skipped++
+	} else {
+		report.Failed++
+	}
+	report.Reasons[reason]++
+
+	if len(report.Issues) >= types.MaxImportIssues {
+		return
+	}
+	message := reason
+	if detail != nil {
+		message += ": " + detail.Error()
+	}
+	report.Issues = append(report.Issues, types.ImportIssue{
+		Line:   lineNumber,
+		Key:    key,
+		Result: result,
+		Reason: message,
+	})
+}
+
+// catalogKeyPrefix 将过滤条件中的bucket和前缀转换为完整key前缀
+func catalogKeyPrefix(filter types.CatalogFilter) string {
+	if filter.Bucket != "" {
+		return filter.Bucket + "/" + filter.Prefix
+	}
+	return filter.Prefix
+}
+
+// matchCatalogFilter 判断元数据是否符合过滤条件
+func matchCatalogFilter(entry *types.MetadataEntry, filter types.CatalogFilter) bool {
+	if !strings.HasPrefix(entry.Key, catalogKeyPrefix(filter)) {
+		return false
+	}
+	if !filter.UpdatedAfter.IsZero() && entry.UpdatedAt.Before(filter.UpdatedAfter) {
+		return false
+	}
+	if !filter.UpdatedBefore.IsZero() && entry.UpdatedAt.After(filter.UpdatedBefore) {
+		return false
+	}
+	return true
+}
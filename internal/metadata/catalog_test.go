@@ -0,0 +1,48 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+
+	"mock-storage/internal/types"
+)
+
+func TestImportArchivesOverwrittenVersion(t *testing.T) {
+	ms := NewMetaService(NewMemoryStore())
+	if err := ms.SetBucketVersioning("bucket", types.VersioningEnabled); err != nil {
+		t.Fatalf("enable versioning: %v", err)
+	}
+	// 启用版本控制之前写入的对象，被覆盖后作为null版本保留
+	existing := &types.MetadataEntry{ID: "old", Key: "bucket/object", Size: 1, StorageNodes: []string{"stg1"}}
+	if err := ms.SaveEntry(existing); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	input := strings.Join([]string{
+		`{"id":"new","key":"bucket/object","size":2,"storage_nodes":["stg1"],"version_id":"new"}`,
+		`{"id":"lost","key":"bucket/lost","size":3,"storage_nodes":["stg9"]}`,
+	}, "\n")
+	report, err := ms.ImportMetadata(strings.NewReader(input), types.ImportOptions{
+		Conflict: types.ImportConflictOverwrite,
+		Nodes:    []string{"stg1", "stg2"},
+	})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if report.Imported != 1 || report.Failed != 1 || report.Reasons[importReasonUnknownNode] != 1 {
+		t.Fatalf("report = %+v, want one import and one unknown node failure", report)
+	}
+
+	current, err := ms.GetMetadata("bucket/object")
+	if err != nil || current.ID != "new" {
+		t.Fatalf("current = %+v, %v, want the imported entry", current, err)
+	}
+	for _, versionID := range []string{types.NullVersionID, "new"} {
+		if _, err := ms.GetVersion("bucket/object", versionID); err != nil {
+			t.Errorf("version %s missing from history: %v", versionID, err)
+		}
+	}
+	if _, err := ms.GetMetadata("bucket/lost"); err == nil {
+		t.Errorf("entry referencing an unknown node was imported")
+	}
+}
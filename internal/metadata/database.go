@@ -9,109 +9,137 @@ import (
 
 	"mock-storage/internal/types"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // DatabaseManager 数据库管理器
 type DatabaseManager struct {
-	db *sql.DB
+	db       *sql.DB
+	conn     sqlConn // 执行语句的连接，事务中为*sql.Tx
+	dialect  dialect
+	fullText bool // 已建立FTS5全文索引
 }
 
-// NewDatabaseManager 创建数据库管理器
+// sqlConn *sql.DB和*sql.Tx共同的语句执行接口
+type sqlConn interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// NewDatabaseManager 创建数据库管理器，并执行未应用的表结构迁移
 func NewDatabaseManager(driver, dsn string) (*DatabaseManager, error) {
-	db, err := sql.Open(driver, dsn)
+	manager, err := OpenDatabase(driver, dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %v", err)
+		return nil, err
 	}
 
-	// 测试连接
-	err = db.Ping()
+	applied, err := manager.Migrate()
 	if err != nil {
-		return nil, fmt.Errorf("failed to ping database: %v", err)
+		manager.Close()
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
 	}
 
-	manager := &DatabaseManager{db: db}
-
-	// 初始化表结构
-	err = manager.initTables()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize tables: %v", err)
+	if err := manager.ensureSearchIndex(); err != nil {
+		manager.Close()
+		return nil, err
 	}
 
-	fmt.Println("[DB] Database connected and initialized successfully")
+	fmt.Printf("[DB] Database connected and initialized successfully (schema version %d, %d migrations applied)\n",
+		LatestSchemaVersion(), applied)
 	return manager, nil
 }
 
-// initTables 初始化数据库表
-func (dm *DatabaseManager) initTables() error {
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS metadata (
-		id TEXT PRIMARY KEY,
-		key TEXT UNIQUE NOT NULL,
-		size INTEGER NOT NULL,
-		content_type TEXT NOT NULL,
-		md5_hash TEXT NOT NULL,
-		storage_nodes TEXT NOT NULL, -- JSON array
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_metadata_key ON metadata(key);
-	CREATE INDEX IF NOT EXISTS idx_metadata_created_at ON metadata(created_at);
-	CREATE INDEX IF NOT EXISTS idx_metadata_size ON metadata(size);
-	`
+// OpenDatabase 连接数据库但不执行迁移，用于迁移管理命令
+func OpenDatabase(driver, dsn string) (*DatabaseManager, error) {
+	if driver == DriverSQLite {
+		dsn = sqliteDSN(dsn)
+	}
 
-	_, err := dm.db.Exec(createTableSQL)
-	return err
-}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
 
-// SaveMetadata 保存元数据到数据库
-func (dm *DatabaseManager) SaveMetadata(entry *types.MetadataEntry) error {
-	// 将storage_nodes转换为JSON字符串
-	storageNodesJSON, err := json.Marshal(entry.StorageNodes)
+	// 测试连接
+	err = db.Ping()
 	if err != nil {
-		return fmt.Errorf("failed to marshal storage nodes: %v", err)
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	insertSQL := `
-	INSERT OR REPLACE INTO metadata 
-	(id, key, size, content_type, md5_hash, storage_nodes, created_at, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`
+	return &DatabaseManager{db: db, conn: db, dialect: dialectFor(driver)}, nil
+}
 
-	_, err = dm.db.Exec(insertSQL,
-		entry.ID,
-		entry.Key,
-		entry.Size,
-		entry.ContentType,
-		entry.MD5Hash,
-		string(storageNodesJSON),
-		entry.CreatedAt,
-		entry.UpdatedAt,
-	)
+// sqliteDSN 为SQLite连接加上事务参数（DSN中已指定时保留）：可写连接的事务开始时即获取写锁（BEGIN IMMEDIATE），
+// 避免两个先读后写的事务同时持有读锁、升级写锁时其中一个直接失败；锁被占用时最多等待5秒
+func sqliteDSN(dsn string) string {
+	var params []string
+	if !strings.Contains(dsn, "_txlock=") && !strings.Contains(dsn, "mode=ro") {
+		params = append(params, "_txlock=immediate")
+	}
+	if !strings.Contains(dsn, "_busy_timeout=") && !strings.Contains(dsn, "_timeout=") {
+		params = append(params, "_busy_timeout=5000")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
 
-	if err != nil {
-		return fmt.Errorf("failed to insert metadata: %v", err)
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
 	}
+	return dsn + separator + strings.Join(params, "&")
+}
 
-	fmt.Printf("[DB] Saved metadata for key: %s\n", entry.Key)
-	return nil
+// exec 执行语句，占位符按方言转换
+func (dm *DatabaseManager) exec(query string, args ...any) (sql.Result, error) {
+	return dm.conn.Exec(dm.dialect.rebind(query), args...)
 }
 
-// GetMetadata 从数据库获取元数据
-func (dm *DatabaseManager) GetMetadata(key string) (*types.MetadataEntry, error) {
-	querySQL := `
-	SELECT id, key, size, content_type, md5_hash, storage_nodes, created_at, updated_at
-	FROM metadata WHERE key = ?
-	`
+// query 执行查询，占位符按方言转换
+func (dm *DatabaseManager) query(query string, args ...any) (*sql.Rows, error) {
+	return dm.conn.Query(dm.dialect.rebind(query), args...)
+}
+
+// queryRow 执行单行查询，占位符按方言转换
+func (dm *DatabaseManager) queryRow(query string, args ...any) *sql.Row {
+	return dm.conn.QueryRow(dm.dialect.rebind(query), args...)
+}
+
+// metadataColumns 元数据表查询列，顺序需与scanMetadata保持一致
+const metadataColumns = `id, key, size, content_type, md5_hash, storage_nodes, created_at, updated_at,
+	encryption, encrypted_data_key, encryption_key_md5, etag, sync_status, sync_error,
+	storage_class, last_accessed_at, checksums, cache_control, content_disposition, content_encoding,
+	content_language, expires, user_metadata, version_id`
+
+// metadataUpsertSet 冲突时覆盖除key外的所有列
+var metadataUpsertSet = func() string {
+	var assignments []string
+	for _, column := range strings.Split(metadataColumns, ",") {
+		column = strings.TrimSpace(column)
+		if column != "key" {
+			assignments = append(assignments, column+" = excluded."+column)
+		}
+	}
+	return strings.Join(assignments, ", ")
+}()
 
-	row := dm.db.QueryRow(querySQL, key)
+// rowScanner 抽象*sql.Row与*sql.Rows的Scan方法
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
+// scanMetadata 从查询结果中解析一条元数据
+func scanMetadata(scanner rowScanner) (*types.MetadataEntry, error) {
 	var entry types.MetadataEntry
 	var storageNodesJSON string
 	var createdAt, updatedAt string
+	var lastAccessedAt sql.NullString
+	var checksumsJSON, userMetadataJSON string
 
-	err := row.Scan(
+	err := scanner.Scan(
 		&entry.ID,
 		&entry.Key,
 		&entry.Size,
@@ -120,13 +148,37 @@ func (dm *DatabaseManager) GetMetadata(key string) (*types.MetadataEntry, error)
 		&storageNodesJSON,
 		&createdAt,
 		&updatedAt,
+		&entry.Encryption,
+		&entry.EncryptedDataKey,
+		&entry.EncryptionKeyMD5,
+		&entry.ETag,
+		&entry.SyncStatus,
+		&entry.SyncError,
+		&entry.StorageClass,
+		&lastAccessedAt,
+		&checksumsJSON,
+		&entry.CacheControl,
+		&entry.ContentDisposition,
+		&entry.ContentEncoding,
+		&entry.ContentLanguage,
+		&entry.Expires,
+		&userMetadataJSON,
+		&entry.VersionID,
 	)
-
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("metadata not found for key: %s", key)
+		return nil, err
+	}
+
+	if checksumsJSON != "" {
+		if err := json.Unmarshal([]byte(checksumsJSON), &entry.Checksums); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checksums: %v", err)
+		}
+	}
+
+	if userMetadataJSON != "" {
+		if err := json.Unmarshal([]byte(userMetadataJSON), &entry.UserMetadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user metadata: %v", err)
 		}
-		return nil, fmt.Errorf("failed to query metadata: %v", err)
 	}
 
 	// 解析JSON字符串为storage_nodes数组
@@ -146,25 +198,146 @@ func (dm *DatabaseManager) GetMetadata(key string) (*types.MetadataEntry, error)
 		return nil, fmt.Errorf("failed to parse updated_at: %v", err)
 	}
 
+	if lastAccessedAt.Valid {
+		entry.LastAccessedAt, _ = time.Parse(time.RFC3339, lastAccessedAt.String)
+	}
+
 	return &entry, nil
 }
 
-// DeleteMetadata 从数据库删除元数据
-func (dm *DatabaseManager) DeleteMetadata(key string) error {
-	deleteSQL := `DELETE FROM metadata WHERE key = ?`
+// marshalStringMap 将校验和、用户元数据等序列化为JSON（键有序），为空时保存空字符串
+func marshalStringMap(values map[string]string) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %v: %v", values, err)
+	}
+	return string(data), nil
+}
 
-	result, err := dm.db.Exec(deleteSQL, key)
+// nullableTime 将零值时间转换为NULL
+func nullableTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// SaveMetadata 保存元数据到数据库
+func (dm *DatabaseManager) SaveMetadata(entry *types.MetadataEntry) error {
+	// 将storage_nodes转换为JSON字符串
+	storageNodesJSON, err := json.Marshal(entry.StorageNodes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal storage nodes: %v", err)
+	}
+
+	checksumsJSON, err := marshalStringMap(entry.Checksums)
 	if err != nil {
-		return fmt.Errorf("failed to delete metadata: %v", err)
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	userMetadataJSON, err := marshalStringMap(entry.UserMetadata)
 	if err != nil {
-		return fmt.Errorf("failed to get affected rows: %v", err)
+		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("metadata not found for key: %s", key)
+	// 同一key已存在时整行替换（SQLite 3.24+ 与 PostgreSQL 均支持该语法）
+	insertSQL := `
+	INSERT INTO metadata 
+	(` + metadataColumns + `)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (key) DO UPDATE SET ` + metadataUpsertSet + `
+	`
+
+	err = dm.WithTransaction(func(store MetadataStore) error {
+		tx := store.(*DatabaseManager)
+		if _, err := tx.exec(insertSQL,
+			entry.ID,
+			entry.Key,
+			entry.Size,
+			entry.ContentType,
+			entry.MD5Hash,
+			string(storageNodesJSON),
+			entry.CreatedAt,
+			entry.UpdatedAt,
+			entry.Encryption,
+			entry.EncryptedDataKey,
+			entry.EncryptionKeyMD5,
+			entry.ETag,
+			entry.SyncStatus,
+			entry.SyncError,
+			entry.StorageClass,
+			nullableTime(entry.LastAccessedAt),
+			checksumsJSON,
+			entry.CacheControl,
+			entry.ContentDisposition,
+			entry.ContentEncoding,
+			entry.ContentLanguage,
+			entry.Expires,
+			userMetadataJSON,
+			entry.VersionID,
+		); err != nil {
+			return fmt.Errorf("failed to insert metadata: %v", err)
+		}
+
+		// 标签随对象一起替换，覆盖写入的新对象不继承旧对象的标签
+		return tx.replaceTags(entry.Key, entry.Tags)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[DB] Saved metadata for key: %s\n", entry.Key)
+	return nil
+}
+
+// GetMetadata 从数据库获取元数据
+func (dm *DatabaseManager) GetMetadata(key string) (*types.MetadataEntry, error) {
+	querySQL := `SELECT ` + metadataColumns + ` FROM metadata WHERE key = ?`
+
+	entry, err := scanMetadata(dm.queryRow(querySQL, key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("metadata not found for key: %s", key)
+		}
+		return nil, fmt.Errorf("failed to query metadata: %v", err)
+	}
+
+	if err := dm.attachTags([]*types.MetadataEntry{entry}); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// DeleteMetadata 从数据库删除元数据
+func (dm *DatabaseManager) DeleteMetadata(key string) error {
+	deleteSQL := `DELETE FROM metadata WHERE key = ?`
+
+	err := dm.WithTransaction(func(store MetadataStore) error {
+		tx := store.(*DatabaseManager)
+		result, err := tx.exec(deleteSQL, key)
+		if err != nil {
+			return fmt.Errorf("failed to delete metadata: %v", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %v", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("metadata not found for key: %s", key)
+		}
+
+		if _, err := tx.exec(`DELETE FROM object_tags WHERE key = ?`, key); err != nil {
+			return fmt.Errorf("failed to delete tags: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("[DB] Deleted metadata for key: %s\n", key)
@@ -174,13 +347,13 @@ func (dm *DatabaseManager) DeleteMetadata(key string) error {
 // ListMetadata 列出元数据（分页）
 func (dm *DatabaseManager) ListMetadata(limit, offset int) ([]*types.MetadataEntry, error) {
 	querySQL := `
-	SELECT id, key, size, content_type, md5_hash, storage_nodes, created_at, updated_at
+	SELECT ` + metadataColumns + `
 	FROM metadata 
-	ORDER BY created_at DESC
+	ORDER BY created_at DESC, key ASC
 	LIMIT ? OFFSET ?
 	`
 
-	rows, err := dm.db.Query(querySQL, limit, offset)
+	rows, err := dm.query(querySQL, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query metadata list: %v", err)
 	}
@@ -189,41 +362,22 @@ func (dm *DatabaseManager) ListMetadata(limit, offset int) ([]*types.MetadataEnt
 	var entries []*types.MetadataEntry
 
 	for rows.Next() {
-		var entry types.MetadataEntry
-		var storageNodesJSON string
-		var createdAt, updatedAt string
-
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Key,
-			&entry.Size,
-			&entry.ContentType,
-			&entry.MD5Hash,
-			&storageNodesJSON,
-			&createdAt,
-			&updatedAt,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan metadata row: %v", err)
-		}
-
-		// 解析JSON和时间
-		err = json.Unmarshal([]byte(storageNodesJSON), &entry.StorageNodes)
+		entry, err := scanMetadata(rows)
 		if err != nil {
 			continue // 跳过损坏的记录
 		}
 
-		entry.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-		entry.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
-		entries = append(entries, &entry)
+		entries = append(entries, entry)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error during rows iteration: %v", err)
 	}
+	rows.Close()
 
+	if err := dm.attachTags(entries); err != nil {
+		return nil, err
+	}
 	return entries, nil
 }
 
@@ -234,18 +388,48 @@ func (dm *DatabaseManager) UpdateMetadata(entry *types.MetadataEntry) error {
 		return fmt.Errorf("failed to marshal storage nodes: %v", err)
 	}
 
+	checksumsJSON, err := marshalStringMap(entry.Checksums)
+	if err != nil {
+		return err
+	}
+
+	userMetadataJSON, err := marshalStringMap(entry.UserMetadata)
+	if err != nil {
+		return err
+	}
+
 	updateSQL := `
 	UPDATE metadata 
-	SET size = ?, content_type = ?, md5_hash = ?, storage_nodes = ?, updated_at = ?
+	SET size = ?, content_type = ?, md5_hash = ?, storage_nodes = ?, updated_at = ?,
+		encryption = ?, encrypted_data_key = ?, encryption_key_md5 = ?, etag = ?,
+		sync_status = ?, sync_error = ?, storage_class = ?, last_accessed_at = ?,
+		checksums = ?, cache_control = ?, content_disposition = ?, content_encoding = ?,
+		content_language = ?, expires = ?, user_metadata = ?, version_id = ?
 	WHERE key = ?
 	`
 
-	result, err := dm.db.Exec(updateSQL,
+	result, err := dm.exec(updateSQL,
 		entry.Size,
 		entry.ContentType,
 		entry.MD5Hash,
 		string(storageNodesJSON),
 		entry.UpdatedAt,
+		entry.Encryption,
+		entry.EncryptedDataKey,
+		entry.EncryptionKeyMD5,
+		entry.ETag,
+		entry.SyncStatus,
+		entry.SyncError,
+		entry.StorageClass,
+		nullableTime(entry.LastAccessedAt),
+		checksumsJSON,
+		entry.CacheControl,
+		entry.ContentDisposition,
+		entry.ContentEncoding,
+		entry.ContentLanguage,
+		entry.Expires,
+		userMetadataJSON,
+		entry.VersionID,
 		entry.Key,
 	)
 
@@ -265,6 +449,108 @@ func (dm *DatabaseManager) UpdateMetadata(entry *types.MetadataEntry) error {
 	return nil
 }
 
+// UpdateStorageClass 更新对象的存储类别及所在节点
+// 仅当对象ID未变化时更新，避免覆盖迁移期间重新上传的对象
+func (dm *DatabaseManager) UpdateStorageClass(key, objectID, storageClass string, storageNodes []string) error {
+	storageNodesJSON, err := json.Marshal(storageNodes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal storage nodes: %v", err)
+	}
+
+	result, err := dm.exec(`UPDATE metadata SET storage_class = ?, storage_nodes = ? WHERE key = ? AND id = ?`,
+		storageClass, string(storageNodesJSON), key, objectID)
+	if err != nil {
+		return fmt.Errorf("failed to update storage class: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("metadata for key %s changed or was deleted", key)
+	}
+
+	return nil
+}
+
+// TouchLastAccess 更新对象的最后访问时间
+func (dm *DatabaseManager) TouchLastAccess(key string, accessedAt time.Time) error {
+	_, err := dm.exec(`UPDATE metadata SET last_accessed_at = ? WHERE key = ?`, accessedAt, key)
+	if err != nil {
+		return fmt.Errorf("failed to update last access time: %v", err)
+	}
+	return nil
+}
+
+// UpdateSyncStatus 更新对象的源站同步状态
+func (dm *DatabaseManager) UpdateSyncStatus(key, status, syncError string) error {
+	result, err := dm.exec(`UPDATE metadata SET sync_status = ?, sync_error = ? WHERE key = ?`,
+		status, syncError, key)
+	if err != nil {
+		return fmt.Errorf("failed to update sync status: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("metadata not found for key: %s", key)
+	}
+
+	return nil
+}
+
+// ListMetadataBySyncStatus 按源站同步状态列出元数据（分页）
+func (dm *DatabaseManager) ListMetadataBySyncStatus(statuses []string, limit, offset int) ([]*types.MetadataEntry, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+	querySQL := `
+	SELECT ` + metadataColumns + `
+	FROM metadata
+	WHERE sync_status IN (` + placeholders + `)
+	ORDER BY updated_at ASC, key ASC
+	LIMIT ? OFFSET ?
+	`
+
+	args := make([]any, 0, len(statuses)+2)
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+	args = append(args, limit, offset)
+
+	rows, err := dm.query(querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metadata by sync status: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*types.MetadataEntry
+	for rows.Next() {
+		entry, err := scanMetadata(rows)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+	rows.Close()
+
+	if err := dm.attachTags(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
 // GetStats 获取统计信息
 func (dm *DatabaseManager) GetStats() (map[string]any, error) {
 	stats := make(map[string]any)
@@ -311,64 +597,56 @@ func (dm *DatabaseManager) GetStats() (map[string]any, error) {
 	}
 	stats["content_types"] = contentTypeStats
 
+	// 按存储类别统计
+	storageClassStats := make(map[string]int)
+	classRows, err := dm.db.Query("SELECT storage_class, COUNT(*) FROM metadata GROUP BY storage_class")
+	if err == nil {
+		defer classRows.Close()
+		for classRows.Next() {
+			var storageClass string
+			var count int
+			if classRows.Scan(&storageClass, &count) == nil {
+				storageClassStats[storageClass] = count
+			}
+		}
+	}
+	stats["storage_classes"] = storageClassStats
+
 	return stats, nil
 }
 
-// SearchMetadata 搜索元数据
-func (dm *DatabaseManager) SearchMetadata(query string, limit int) ([]*types.MetadataEntry, error) {
-	searchSQL := `
-	SELECT id, key, size, content_type, md5_hash, storage_nodes, created_at, updated_at
-	FROM metadata 
-	WHERE key LIKE ? OR content_type LIKE ?
-	ORDER BY created_at DESC
-	LIMIT ?
-	`
+// WithTransaction 在数据库事务中执行fn，fn返回错误或提交失败时回滚
+// 传给fn的存储上的所有操作都在同一事务中执行
+func (dm *DatabaseManager) WithTransaction(fn func(store MetadataStore) error) error {
+	if _, ok := dm.conn.(*sql.Tx); ok {
+		// 已在事务中，直接加入当前事务
+		return fn(dm)
+	}
 
-	searchPattern := "%" + strings.ToLower(query) + "%"
-	rows, err := dm.db.Query(searchSQL, searchPattern, searchPattern, limit)
+	tx, err := dm.db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("failed to search metadata: %v", err)
+		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
-	defer rows.Close()
 
-	var entries []*types.MetadataEntry
-
-	for rows.Next() {
-		var entry types.MetadataEntry
-		var storageNodesJSON string
-		var createdAt, updatedAt string
-
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Key,
-			&entry.Size,
-			&entry.ContentType,
-			&entry.MD5Hash,
-			&storageNodesJSON,
-			&createdAt,
-			&updatedAt,
-		)
-
-		if err != nil {
-			continue
-		}
-
-		err = json.Unmarshal([]byte(storageNodesJSON), &entry.StorageNodes)
-		if err != nil {
-			continue
+	err = fn(&DatabaseManager{db: dm.db, conn: tx, dialect: dm.dialect, fullText: dm.fullText})
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			fmt.Printf("[DB] Warning: failed to roll back transaction: %v\n", rollbackErr)
 		}
-
-		entry.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-		entry.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
-		entries = append(entries, &entry)
+		return err
 	}
 
-	return entries, nil
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
 }
 
-// Close 关闭数据库连接
+// Close 关闭数据库连接，事务中的管理器不能关闭连接
 func (dm *DatabaseManager) Close() error {
+	if _, ok := dm.conn.(*sql.Tx); ok {
+		return fmt.Errorf("cannot close database inside a transaction")
+	}
 	if dm.db != nil {
 		return dm.db.Close()
 	}
@@ -0,0 +1,89 @@
+package metadata
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DriverPostgres PostgreSQL驱动（lib/pq）
+const DriverPostgres = "postgres"
+
+// dialect SQL方言差异
+type dialect struct {
+	name          string
+	timestampType string // 时间列类型
+	bigintType    string // 大小列类型
+	numbered      bool   // 占位符使用$1、$2……
+	containsFunc  string // 子串查找函数，返回位置（从1开始），未找到返回0
+	timeFunc      string // 比较时间前对时间列和参数应用的函数，为空时直接比较
+	globOperator  string // key与glob模式匹配的运算符，~ 时参数为转换后的正则表达式
+}
+
+var (
+	sqliteDialect = dialect{
+		name:          DriverSQLite,
+		timestampType: "DATETIME",
+		bigintType:    "INTEGER",
+		containsFunc:  "instr",
+		timeFunc:      "julianday", // 时间以文本保存，时区和小数位数可能不同，不能按字符串比较
+		globOperator:  "GLOB",
+	}
+	postgresDialect = dialect{
+		name:          DriverPostgres,
+		timestampType: "TIMESTAMPTZ",
+		bigintType:    "BIGINT",
+		numbered:      true,
+		containsFunc:  "strpos",
+		globOperator:  "~",
+	}
+)
+
+// dialectFor 根据驱动名称选择方言
+func dialectFor(driver string) dialect {
+	switch driver {
+	case DriverPostgres:
+		return postgresDialect
+	default:
+		return sqliteDialect
+	}
+}
+
+// rebind 将?占位符转换为方言的占位符格式
+// 语句中的字符串字面量不能包含?
+func (d dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(query) + 16)
+
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			builder.WriteByte('$')
+			builder.WriteString(strconv.Itoa(n))
+			continue
+		}
+		builder.WriteByte(query[i])
+	}
+	return builder.String()
+}
+
+// timeExpr 返回可直接比较和排序的时间表达式
+func (d dialect) timeExpr(expr string) string {
+	if d.timeFunc == "" {
+		return expr
+	}
+	return d.timeFunc + "(" + expr + ")"
+}
+
+// globCondition 返回key与glob模式匹配的条件和参数
+// SQLite的GLOB与glob语法一致，PostgreSQL转换为锚定的正则表达式
+func (d dialect) globCondition(column, pattern string) (string, any) {
+	if d.globOperator == "GLOB" {
+		return column + " GLOB ?", pattern
+	}
+	return column + " " + d.globOperator + " ?", globToRegexp(pattern)
+}
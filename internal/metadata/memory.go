@@ -0,0 +1,631 @@
+package metadata
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// MemoryStore 内存元数据存储，进程退出后数据丢失，用于测试和临时环境
+type MemoryStore struct {
+	txMu     sync.Mutex // 串行执行事务
+	mu       sync.RWMutex
+	entries  map[string]*types.MetadataEntry
+	versions map[string]map[string]*types.MetadataEntry // key -> 版本ID -> 版本
+	buckets  map[string]string                          // bucket -> 版本控制状态
+}
+
+// NewMemoryStore 创建内存元数据存储
+func NewMemoryStore() *MemoryStore {
+	fmt.Println("[DB] Using in-memory metadata store")
+	return &MemoryStore{
+		entries:  make(map[string]*types.MetadataEntry),
+		versions: make(map[string]map[string]*types.MetadataEntry),
+		buckets:  make(map[string]string),
+	}
+}
+
+// SaveMetadata 保存元数据，已存在的key被替换
+func (ms *MemoryStore) SaveMetadata(entry *types.MetadataEntry) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.entries[entry.Key] = copyEntry(entry)
+	return nil
+}
+
+// GetMetadata 获取元数据
+func (ms *MemoryStore) GetMetadata(key string) (*types.MetadataEntry, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	entry, exists := ms.entries[key]
+	if !exists {
+		return nil, fmt.Errorf("metadata not found for key: %s", key)
+	}
+	return copyEntry(entry), nil
+}
+
+// DeleteMetadata 删除元数据
+func (ms *MemoryStore) DeleteMetadata(key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, exists := ms.entries[key]; !exists {
+		return fmt.Errorf("metadata not found for key: %s", key)
+	}
+	delete(ms.entries, key)
+	return nil
+}
+
+// ListMetadata 列出元数据（分页），按创建时间倒序
+func (ms *MemoryStore) ListMetadata(limit, offset int) ([]*types.MetadataEntry, error) {
+	entries := ms.filter(func(*types.MetadataEntry) bool { return true })
+	sortByCreatedDesc(entries)
+	return paginate(entries, limit, offset), nil
+}
+
+// UpdateMetadata 更新元数据
+func (ms *MemoryStore) UpdateMetadata(entry *types.MetadataEntry) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	existing, exists := ms.entries[entry.Key]
+	if !exists {
+		return fmt.Errorf("metadata not found for key: %s", entry.Key)
+	}
+
+	updated := copyEntry(entry)
+	updated.ID = existing.ID
+	updated.CreatedAt = existing.CreatedAt
+	updated.Tags = existing.Tags
+	ms.entries[entry.Key] = updated
+	return nil
+}
+
+// UpdateStorageClass 更新对象的存储类别及所在节点，对象ID变化时拒绝更新
+func (ms *MemoryStore) UpdateStorageClass(key, objectID, storageClass string, storageNodes []string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry, exists := ms.entries[key]
+	if !exists || entry.ID != objectID {
+		return fmt.Errorf("metadata for key %s changed or was deleted", key)
+	}
+
+	entry.StorageClass = storageClass
+	entry.StorageNodes = append([]string(nil), storageNodes...)
+	return nil
+}
+
+// TouchLastAccess 更新对象的最后访问时间
+func (ms *MemoryStore) TouchLastAccess(key string, accessedAt time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if entry, exists := ms.entries[key]; exists {
+		entry.LastAccessedAt = accessedAt
+	}
+	return nil
+}
+
+// UpdateSyncStatus 更新对象的源站同步状态
+func (ms *MemoryStore) UpdateSyncStatus(key, status, syncError string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry, exists := ms.entries[key]
+	if !exists {
+		return fmt.Errorf("metadata not found for key: %s", key)
+	}
+
+	entry.SyncStatus = status
+	entry.SyncError = syncError
+	return nil
+}
+
+// ListMetadataBySyncStatus 按源站同步状态列出元数据（分页），按更新时间正序
+func (ms *MemoryStore) ListMetadataBySyncStatus(statuses []string, limit, offset int) ([]*types.MetadataEntry, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	entries := ms.filter(func(entry *types.MetadataEntry) bool {
+		for _, status := range statuses {
+			if entry.SyncStatus == status {
+				return true
+			}
+		}
+		return false
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].UpdatedAt.Equal(entries[j].UpdatedAt) {
+			return entries[i].UpdatedAt.Before(entries[j].UpdatedAt)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return paginate(entries, limit, offset), nil
+}
+
+// GetStats 获取统计信息
+func (ms *MemoryStore) GetStats() (map[string]any, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var totalSize int64
+	contentTypeStats := make(map[string]int)
+	storageClassStats := make(map[string]int)
+	for _, entry := range ms.entries {
+		totalSize += entry.Size
+		contentTypeStats[entry.ContentType]++
+		storageClassStats[entry.StorageClass]++
+	}
+
+	totalFiles := int64(len(ms.entries))
+	var averageSize int64
+	if totalFiles > 0 {
+		averageSize = totalSize / totalFiles
+	}
+
+	return map[string]any{
+		"total_files":     totalFiles,
+		"total_size":      totalSize,
+		"average_size":    averageSize,
+		"content_types":   contentTypeStats,
+		"storage_classes": storageClassStats,
+	}, nil
+}
+
+// SearchMetadata 按条件搜索元数据，文本按不区分大小写的子串匹配（与没有全文索引时的SQL实现一致）
+func (ms *MemoryStore) SearchMetadata(query *types.SearchQuery) (*types.SearchResult, error) {
+	cursor, err := decodeSearchCursor(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var pattern *regexp.Regexp
+	if query.KeyPattern != "" {
+		pattern, err = regexp.Compile("(?s)" + globToRegexp(query.KeyPattern))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid key pattern: %v", ErrInvalidSearch, err)
+		}
+	}
+
+	// 游标位置：上一页最后一条结果
+	var after *types.MetadataEntry
+	if cursor != nil {
+		after = &types.MetadataEntry{Key: cursor.Key}
+		value, _ := cursor.sortArg()
+		switch query.SortBy {
+		case types.SearchSortSize:
+			after.Size = value.(int64)
+		case types.SearchSortCreated:
+			after.CreatedAt = value.(time.Time)
+		case types.SearchSortUpdated:
+			after.UpdatedAt = value.(time.Time)
+		}
+	}
+
+	compare := func(a, b *types.MetadataEntry) int {
+		result := compareSearchOrder(a, b, query.SortBy)
+		if query.Descending {
+			return -result
+		}
+		return result
+	}
+
+	entries := ms.filter(func(entry *types.MetadataEntry) bool {
+		if after != nil && compare(entry, after) <= 0 {
+			return false
+		}
+		if pattern != nil && !pattern.MatchString(entry.Key) {
+			return false
+		}
+		return matchSearch(entry, query)
+	})
+
+	sort.Slice(entries, func(i, j int) bool { return compare(entries[i], entries[j]) < 0 })
+	return newSearchResult(query, paginate(entries, query.Limit+1, 0)), nil
+}
+
+// matchSearch 判断元数据是否满足除key模式和游标外的搜索条件
+func matchSearch(entry *types.MetadataEntry, query *types.SearchQuery) bool {
+	for _, term := range strings.Fields(strings.ToLower(query.Text)) {
+		if !matchText(entry, term) {
+			return false
+		}
+	}
+
+	if query.KeyPrefix != "" && !strings.HasPrefix(entry.Key, query.KeyPrefix) {
+		return false
+	}
+
+	if contentType := strings.ToLower(query.ContentType); contentType != "" {
+		actual := strings.ToLower(entry.ContentType)
+		if major, ok := strings.CutSuffix(contentType, "/*"); ok {
+			if !strings.HasPrefix(actual, major+"/") {
+				return false
+			}
+		} else if actual != contentType {
+			return false
+		}
+	}
+	if query.StorageClass != "" && entry.StorageClass != query.StorageClass {
+		return false
+	}
+
+	if query.MinSize != nil && entry.Size < *query.MinSize {
+		return false
+	}
+	if query.MaxSize != nil && entry.Size > *query.MaxSize {
+		return false
+	}
+
+	if !query.CreatedAfter.IsZero() && entry.CreatedAt.Before(query.CreatedAfter) {
+		return false
+	}
+	if !query.CreatedBefore.IsZero() && entry.CreatedAt.After(query.CreatedBefore) {
+		return false
+	}
+	if !query.UpdatedAfter.IsZero() && entry.UpdatedAt.Before(query.UpdatedAfter) {
+		return false
+	}
+	if !query.UpdatedBefore.IsZero() && entry.UpdatedAt.After(query.UpdatedBefore) {
+		return false
+	}
+
+	for name, value := range query.UserMetadata {
+		if actual, ok := entry.UserMetadata[name]; !ok || actual != value {
+			return false
+		}
+	}
+	return types.MatchTags(entry.Tags, query.Tags)
+}
+
+// matchText 判断key、内容类型或用户元数据是否包含小写的词
+func matchText(entry *types.MetadataEntry, term string) bool {
+	if strings.Contains(strings.ToLower(entry.Key), term) ||
+		strings.Contains(strings.ToLower(entry.ContentType), term) {
+		return true
+	}
+	for name, value := range entry.UserMetadata {
+		if strings.Contains(strings.ToLower(name), term) || strings.Contains(strings.ToLower(value), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareSearchOrder 按排序字段升序比较，值相同时按key比较
+func compareSearchOrder(a, b *types.MetadataEntry, sortBy string) int {
+	result := 0
+	switch sortBy {
+	case types.SearchSortSize:
+		result = cmp.Compare(a.Size, b.Size)
+	case types.SearchSortCreated:
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	case types.SearchSortUpdated:
+		result = a.UpdatedAt.Compare(b.UpdatedAt)
+	}
+	if result != 0 {
+		return result
+	}
+	return strings.Compare(a.Key, b.Key)
+}
+
+// SaveVersion 保存对象版本到版本历史，同一key和版本ID已存在时覆盖
+func (ms *MemoryStore) SaveVersion(entry *types.MetadataEntry) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	versions, exists := ms.versions[entry.Key]
+	if !exists {
+		versions = make(map[string]*types.MetadataEntry)
+		ms.versions[entry.Key] = versions
+	}
+	versions[entry.VersionID] = copyEntry(entry)
+	return nil
+}
+
+// GetVersion 获取对象的指定版本
+func (ms *MemoryStore) GetVersion(key, versionID string) (*types.MetadataEntry, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	entry, exists := ms.versions[key][versionID]
+	if !exists {
+		return nil, fmt.Errorf("version %s not found for key: %s", versionID, key)
+	}
+	return copyEntry(entry), nil
+}
+
+// DeleteVersion 从版本历史中删除对象的指定版本
+func (ms *MemoryStore) DeleteVersion(key, versionID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, exists := ms.versions[key][versionID]; !exists {
+		return fmt.Errorf("version %s not found for key: %s", versionID, key)
+	}
+	delete(ms.versions[key], versionID)
+	if len(ms.versions[key]) == 0 {
+		delete(ms.versions, key)
+	}
+	return nil
+}
+
+// GetLatestVersion 获取版本历史中最新的版本（可能是删除标记）
+func (ms *MemoryStore) GetLatestVersion(key string) (*types.MetadataEntry, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var entries []*types.MetadataEntry
+	for _, entry := range ms.versions[key] {
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no versions found for key: %s", key)
+	}
+	sortVersions(entries)
+	return copyEntry(entries[0]), nil
+}
+
+// ListVersions 列出key以prefix开头的所有版本，包括启用版本控制之前写入的对象
+func (ms *MemoryStore) ListVersions(prefix string, limit int) ([]*types.MetadataEntry, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var entries []*types.MetadataEntry
+	for key, versions := range ms.versions {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for _, entry := range versions {
+			entries = append(entries, copyEntry(entry))
+		}
+	}
+	for key, entry := range ms.entries {
+		if entry.VersionID == "" && strings.HasPrefix(key, prefix) {
+			entries = append(entries, copyEntry(entry))
+		}
+	}
+
+	sortVersions(entries)
+	return paginate(entries, limit, 0), nil
+}
+
+// GetBucketVersioning 获取bucket的版本控制状态，从未配置时返回空字符串
+func (ms *MemoryStore) GetBucketVersioning(bucket string) (string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return ms.buckets[bucket], nil
+}
+
+// SetBucketVersioning 设置bucket的版本控制状态
+func (ms *MemoryStore) SetBucketVersioning(bucket, status string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.buckets[bucket] = status
+	return nil
+}
+
+// WithTransaction 串行执行fn，fn返回错误时撤销fn修改过的key和bucket
+// 回滚只恢复事务修改过的数据，其他goroutine在事务期间对其他key的写入不受影响
+func (ms *MemoryStore) WithTransaction(fn func(store MetadataStore) error) error {
+	ms.txMu.Lock()
+	defer ms.txMu.Unlock()
+
+	tx := &memoryTx{
+		MemoryStore: ms,
+		entries:     make(map[string]*types.MetadataEntry),
+		versions:    make(map[string]map[string]*types.MetadataEntry),
+		buckets:     make(map[string]*string),
+	}
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	return nil
+}
+
+// memoryTx 内存存储的事务视图，第一次修改某个key或bucket前记录其原始状态用于回滚
+type memoryTx struct {
+	*MemoryStore
+	entries  map[string]*types.MetadataEntry            // key -> 事务开始前的当前版本，nil表示不存在
+	versions map[string]map[string]*types.MetadataEntry // key -> 事务开始前的历史版本
+	buckets  map[string]*string                         // bucket -> 事务开始前的版本控制状态，nil表示未配置
+}
+
+// record 记录key在事务开始前的当前版本和历史版本
+func (tx *memoryTx) record(key string) {
+	if _, recorded := tx.versions[key]; recorded {
+		return
+	}
+
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+
+	if entry, exists := tx.MemoryStore.entries[key]; exists {
+		tx.entries[key] = copyEntry(entry)
+	} else {
+		tx.entries[key] = nil
+	}
+
+	keyVersions := make(map[string]*types.MetadataEntry, len(tx.MemoryStore.versions[key]))
+	for versionID, entry := range tx.MemoryStore.versions[key] {
+		keyVersions[versionID] = copyEntry(entry)
+	}
+	tx.versions[key] = keyVersions
+}
+
+// rollback 将事务修改过的key和bucket恢复到原始状态
+func (tx *memoryTx) rollback() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	for key, entry := range tx.entries {
+		if entry == nil {
+			delete(tx.MemoryStore.entries, key)
+		} else {
+			tx.MemoryStore.entries[key] = entry
+		}
+
+		if len(tx.versions[key]) == 0 {
+			delete(tx.MemoryStore.versions, key)
+		} else {
+			tx.MemoryStore.versions[key] = tx.versions[key]
+		}
+	}
+
+	for bucket, status := range tx.buckets {
+		if status == nil {
+			delete(tx.MemoryStore.buckets, bucket)
+		} else {
+			tx.MemoryStore.buckets[bucket] = *status
+		}
+	}
+}
+
+// SaveMetadata 记录key的原始状态后保存元数据
+func (tx *memoryTx) SaveMetadata(entry *types.MetadataEntry) error {
+	tx.record(entry.Key)
+	return tx.MemoryStore.SaveMetadata(entry)
+}
+
+// DeleteMetadata 记录key的原始状态后删除元数据
+func (tx *memoryTx) DeleteMetadata(key string) error {
+	tx.record(key)
+	return tx.MemoryStore.DeleteMetadata(key)
+}
+
+// UpdateMetadata 记录key的原始状态后更新元数据
+func (tx *memoryTx) UpdateMetadata(entry *types.MetadataEntry) error {
+	tx.record(entry.Key)
+	return tx.MemoryStore.UpdateMetadata(entry)
+}
+
+// UpdateStorageClass 记录key的原始状态后更新存储类别
+func (tx *memoryTx) UpdateStorageClass(key, objectID, storageClass string, storageNodes []string) error {
+	tx.record(key)
+	return tx.MemoryStore.UpdateStorageClass(key, objectID, storageClass, storageNodes)
+}
+
+// SetTags 记录key的原始状态后设置标签
+func (tx *memoryTx) SetTags(key, objectID string, tags map[string]string) error {
+	tx.record(key)
+	return tx.MemoryStore.SetTags(key, objectID, tags)
+}
+
+// TouchLastAccess 记录key的原始状态后更新最后访问时间
+func (tx *memoryTx) TouchLastAccess(key string, accessedAt time.Time) error {
+	tx.record(key)
+	return tx.MemoryStore.TouchLastAccess(key, accessedAt)
+}
+
+// UpdateSyncStatus 记录key的原始状态后更新同步状态
+func (tx *memoryTx) UpdateSyncStatus(key, status, syncError string) error {
+	tx.record(key)
+	return tx.MemoryStore.UpdateSyncStatus(key, status, syncError)
+}
+
+// SaveVersion 记录key的原始状态后保存历史版本
+func (tx *memoryTx) SaveVersion(entry *types.MetadataEntry) error {
+	tx.record(entry.Key)
+	return tx.MemoryStore.SaveVersion(entry)
+}
+
+// DeleteVersion 记录key的原始状态后删除历史版本
+func (tx *memoryTx) DeleteVersion(key, versionID string) error {
+	tx.record(key)
+	return tx.MemoryStore.DeleteVersion(key, versionID)
+}
+
+// SetBucketVersioning 记录bucket的原始状态后设置版本控制状态
+func (tx *memoryTx) SetBucketVersioning(bucket, status string) error {
+	if _, recorded := tx.buckets[bucket]; !recorded {
+		tx.mu.RLock()
+		if previous, exists := tx.MemoryStore.buckets[bucket]; exists {
+			tx.buckets[bucket] = &previous
+		} else {
+			tx.buckets[bucket] = nil
+		}
+		tx.mu.RUnlock()
+	}
+	return tx.MemoryStore.SetBucketVersioning(bucket, status)
+}
+
+// WithTransaction 嵌套事务并入外层事务
+func (tx *memoryTx) WithTransaction(fn func(store MetadataStore) error) error {
+	return fn(tx)
+}
+
+// Close 内存存储无需关闭
+func (ms *MemoryStore) Close() error {
+	return nil
+}
+
+// filter 返回满足条件的元数据副本
+func (ms *MemoryStore) filter(match func(*types.MetadataEntry) bool) []*types.MetadataEntry {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var entries []*types.MetadataEntry
+	for _, entry := range ms.entries {
+		if match(entry) {
+			entries = append(entries, copyEntry(entry))
+		}
+	}
+	return entries
+}
+
+// sortByCreatedDesc 按创建时间倒序排列，时间相同时按key排列，与SQL实现一致
+func sortByCreatedDesc(entries []*types.MetadataEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(entries[j].CreatedAt)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+}
+
+// paginate 截取分页结果
+func paginate(entries []*types.MetadataEntry, limit, offset int) []*types.MetadataEntry {
+	if offset >= len(entries) {
+		return nil
+	}
+	entries = entries[offset:]
+	if limit >= 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// copyEntry 复制元数据，避免调用方修改存储中的记录
+func copyEntry(entry *types.MetadataEntry) *types.MetadataEntry {
+	copied := *entry
+	copied.StorageNodes = append([]string(nil), entry.StorageNodes...)
+	copied.Checksums = copyStringMap(entry.Checksums)
+	copied.UserMetadata = copyStringMap(entry.UserMetadata)
+	copied.Tags = copyStringMap(entry.Tags)
+	return &copied
+}
+
+// copyStringMap 复制map，nil保持为nil
+func copyStringMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
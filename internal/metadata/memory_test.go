@@ -0,0 +1,46 @@
+package metadata
+
+import (
+	"errors"
+	"testing"
+
+	"mock-storage/internal/types"
+)
+
+func TestMemoryTransactionRollbackKeepsConcurrentWrites(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.SaveMetadata(&types.MetadataEntry{Key: "bucket/existing", Size: 1}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err := store.WithTransaction(func(tx MetadataStore) error {
+		if err := tx.SaveMetadata(&types.MetadataEntry{Key: "bucket/in-tx", Size: 2}); err != nil {
+			return err
+		}
+		if err := tx.SaveMetadata(&types.MetadataEntry{Key: "bucket/existing", Size: 3}); err != nil {
+			return err
+		}
+
+		// 事务期间另一个goroutine提交的写入
+		done := make(chan error)
+		go func() { done <- store.SaveMetadata(&types.MetadataEntry{Key: "bucket/concurrent", Size: 4}) }()
+		if err := <-done; err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithTransaction error = %v, want %v", err, errAbort)
+	}
+
+	if _, err := store.GetMetadata("bucket/in-tx"); err == nil {
+		t.Errorf("entry written in the rolled back transaction still exists")
+	}
+	if entry, err := store.GetMetadata("bucket/existing"); err != nil || entry.Size != 1 {
+		t.Errorf("existing entry not restored: %+v, %v", entry, err)
+	}
+	if _, err := store.GetMetadata("bucket/concurrent"); err != nil {
+		t.Errorf("concurrent write lost on rollback: %v", err)
+	}
+}
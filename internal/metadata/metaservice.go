@@ -1,9 +1,8 @@
 package metadata
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"mock-storage/internal/types"
@@ -11,11 +10,11 @@ import (
 
 // MetaService 元数据服务
 type MetaService struct {
-	db *DatabaseManager
+	db MetadataStore
 }
 
 // NewMetaService 创建元数据服务
-func NewMetaService(db *DatabaseManager) *MetaService {
+func NewMetaService(db MetadataStore) *MetaService {
 	return &MetaService{
 		db: db,
 	}
@@ -23,6 +22,11 @@ func NewMetaService(db *DatabaseManager) *MetaService {
 
 // SaveMetadata 保存元数据
 func (ms *MetaService) SaveMetadata(obj *types.FileObject, storageNodes []string) error {
+	return ms.SaveEntry(NewEntry(obj, storageNodes))
+}
+
+// NewEntry 根据上传的对象和写入的节点构建元数据
+func NewEntry(obj *types.FileObject, storageNodes []string) *types.MetadataEntry {
 	entry := &types.MetadataEntry{
 		ID:           obj.ID,
 		Key:          obj.Key,
@@ -32,17 +36,146 @@ func (ms *MetaService) SaveMetadata(obj *types.FileObject, storageNodes []string
 		StorageNodes: storageNodes,
 		CreatedAt:    obj.CreatedAt,
 		UpdatedAt:    time.Now(),
+		ETag:         obj.ETag,
+
+		Encryption:       obj.Encryption,
+		EncryptedDataKey: obj.EncryptedDataKey,
+		EncryptionKeyMD5: obj.EncryptionKeyMD5,
+
+		SyncStatus: obj.SyncStatus,
+
+		StorageClass: obj.StorageClass,
+
+		Checksums: obj.Checksums,
+
+		ObjectHeaders: obj.ObjectHeaders,
+
+		VersionID: obj.VersionID,
+
+		Tags: obj.Tags,
 	}
+	if entry.StorageClass == "" {
+		entry.StorageClass = types.StorageClassStandard
+	}
+
+	// 来自源站的对象保留源站的最后修改时间
+	if !obj.LastModified.IsZero() {
+		entry.UpdatedAt = obj.LastModified
+	}
+	return entry
+}
 
+// SaveEntry 保存已构建的元数据，版本控制bucket中的对象同时写入版本历史
+func (ms *MetaService) SaveEntry(entry *types.MetadataEntry) error {
 	err := ms.db.SaveMetadata(entry)
 	if err != nil {
 		return fmt.Errorf("failed to save metadata: %v", err)
 	}
 
-	fmt.Printf("[META] Successfully saved metadata for key: %s\n", obj.Key)
+	// 版本控制bucket中的对象同时写入版本历史
+	if entry.VersionID != "" {
+		if err := ms.db.SaveVersion(entry); err != nil {
+			return fmt.Errorf("failed to save version: %v", err)
+		}
+	}
+
+	fmt.Printf("[META] Successfully saved metadata for key: %s\n", entry.Key)
+	return nil
+}
+
+// ArchiveCurrentVersion 在当前版本被覆盖或删除前将其写入版本历史，返回被写入的当前版本（不存在时为nil）
+// 启用版本控制之前写入的对象在历史中作为null版本保存
+func (ms *MetaService) ArchiveCurrentVersion(key string) (*types.MetadataEntry, error) {
+	current, err := ms.db.GetMetadata(key)
+	if err != nil {
+		return nil, nil
+	}
+
+	archived := *current
+	archived.VersionID = types.NormalizeVersionID(current.VersionID)
+	if err := ms.db.SaveVersion(&archived); err != nil {
+		return nil, fmt.Errorf("failed to archive version: %v", err)
+	}
+	return current, nil
+}
+
+// PromoteVersion 将版本历史中的版本恢复为对象的当前版本
+func (ms *MetaService) PromoteVersion(entry *types.MetadataEntry) error {
+	err := ms.db.SaveMetadata(entry)
+	if err != nil {
+		return fmt.Errorf("failed to promote version: %v", err)
+	}
+
+	fmt.Printf("[META] Promoted version %s of key: %s\n", entry.VersionID, entry.Key)
+	return nil
+}
+
+// SaveVersion 保存对象版本或删除标记到版本历史
+func (ms *MetaService) SaveVersion(entry *types.MetadataEntry) error {
+	err := ms.db.SaveVersion(entry)
+	if err != nil {
+		return fmt.Errorf("failed to save version: %v", err)
+	}
+	return nil
+}
+
+// GetVersion 获取对象的指定版本
+func (ms *MetaService) GetVersion(key, versionID string) (*types.MetadataEntry, error) {
+	return ms.db.GetVersion(key, versionID)
+}
+
+// DeleteVersion 从版本历史中删除对象的指定版本
+func (ms *MetaService) DeleteVersion(key, versionID string) error {
+	err := ms.db.DeleteVersion(key, versionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete version: %v", err)
+	}
+
+	fmt.Printf("[META] Deleted version %s of key: %s\n", versionID, key)
+	return nil
+}
+
+// GetLatestVersion 获取版本历史中最新的版本
+func (ms *MetaService) GetLatestVersion(key string) (*types.MetadataEntry, error) {
+	return ms.db.GetLatestVersion(key)
+}
+
+// ListVersions 列出key以prefix开头的所有版本
+func (ms *MetaService) ListVersions(prefix string, limit int) ([]*types.MetadataEntry, error) {
+	entries, err := ms.db.ListVersions(prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %v", err)
+	}
+	return entries, nil
+}
+
+// GetBucketVersioning 获取bucket的版本控制状态
+func (ms *MetaService) GetBucketVersioning(bucket string) (string, error) {
+	return ms.db.GetBucketVersioning(bucket)
+}
+
+// SetBucketVersioning 设置bucket的版本控制状态
+func (ms *MetaService) SetBucketVersioning(bucket, status string) error {
+	if status != types.VersioningEnabled && status != types.VersioningSuspended {
+		return fmt.Errorf("invalid versioning status: %s", status)
+	}
+
+	err := ms.db.SetBucketVersioning(bucket, status)
+	if err != nil {
+		return fmt.Errorf("failed to set bucket versioning: %v", err)
+	}
+
+	fmt.Printf("[META] Bucket %s versioning set to %s\n", bucket, status)
 	return nil
 }
 
+// WithTransaction 在元数据事务中执行fn，fn中通过tx进行的所有写入一起提交或回滚
+func (ms *MetaService) WithTransaction(fn func(tx *MetaService) error) error {
+	return ms.db.WithTransaction(func(store MetadataStore) error {
+		return fn(NewMetaService(store))
+	})
+}
+
 // GetMetadata 获取元数据
 func (ms *MetaService) GetMetadata(key string) (*types.MetadataEntry, error) {
 	entry, err := ms.db.GetMetadata(key)
@@ -101,6 +234,70 @@ func (ms *MetaService) UpdateMetadata(key string, updates map[string]any) error
 	return nil
 }
 
+// UpdateStorageClass 更新对象的存储类别及所在节点
+// 当前版本同时保存在版本历史中时一并更新，版本列表返回的节点和类别与当前版本一致
+func (ms *MetaService) UpdateStorageClass(key, objectID, storageClass string, storageNodes []string) error {
+	err := ms.db.UpdateStorageClass(key, objectID, storageClass, storageNodes)
+	if err != nil {
+		return fmt.Errorf("failed to update storage class: %v", err)
+	}
+
+	current, err := ms.db.GetMetadata(key)
+	if err != nil {
+		return fmt.Errorf("failed to read moved metadata: %v", err)
+	}
+	if current.VersionID != "" {
+		version, err := ms.db.GetVersion(key, current.VersionID)
+		if err == nil && version.ID == objectID {
+			version.StorageClass = storageClass
+			version.StorageNodes = append([]string(nil), storageNodes...)
+			if err := ms.db.SaveVersion(version); err != nil {
+				return fmt.Errorf("failed to update version storage class: %v", err)
+			}
+		}
+	}
+
+	fmt.Printf("[META] Moved %s to storage class %s\n", key, storageClass)
+	return nil
+}
+
+// SetTags 替换对象当前版本的标签，对象在读取后被重新上传或删除时返回错误
+func (ms *MetaService) SetTags(key, objectID string, tags map[string]string) error {
+	err := ms.db.SetTags(key, objectID, tags)
+	if err != nil {
+		return fmt.Errorf("failed to set tags: %v", err)
+	}
+
+	fmt.Printf("[META] Updated %d tags for key: %s\n", len(tags), key)
+	return nil
+}
+
+// TouchLastAccess 记录对象的访问时间
+func (ms *MetaService) TouchLastAccess(key string) error {
+	return ms.db.TouchLastAccess(key, time.Now())
+}
+
+// UpdateSyncStatus 更新对象的源站同步状态
+func (ms *MetaService) UpdateSyncStatus(key, status, syncError string) error {
+	err := ms.db.UpdateSyncStatus(key, status, syncError)
+	if err != nil {
+		return fmt.Errorf("failed to update sync status: %v", err)
+	}
+
+	return nil
+}
+
+// ListUnsynced 列出尚未同步到源站的元数据
+func (ms *MetaService) ListUnsynced(limit, offset int) ([]*types.MetadataEntry, error) {
+	entries, err := ms.db.ListMetadataBySyncStatus(
+		[]string{types.SyncStatusPending, types.SyncStatusFailed}, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unsynced metadata: %v", err)
+	}
+
+	return entries, nil
+}
+
 // GetStats 获取统计信息
 func (ms *MetaService) GetStats() (map[string]any, error) {
 	stats, err := ms.db.GetStats()
@@ -111,15 +308,21 @@ func (ms *MetaService) GetStats() (map[string]any, error) {
 	return stats, nil
 }
 
-// SearchMetadata 搜索元数据
-func (ms *MetaService) SearchMetadata(query string, limit int) ([]*types.MetadataEntry, error) {
-	// 简单的关键字搜索实现
-	entries, err := ms.db.SearchMetadata(query, limit)
+// SearchMetadata 按结构化条件搜索元数据，支持排序和游标分页
+func (ms *MetaService) SearchMetadata(query *types.SearchQuery) (*types.SearchResult, error) {
+	if err := NormalizeSearchQuery(query); err != nil {
+		return nil, err
+	}
+
+	result, err := ms.db.SearchMetadata(query)
 	if err != nil {
+		if errors.Is(err, ErrInvalidSearch) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to search metadata: %v", err)
 	}
 
-	return entries, nil
+	return result, nil
 }
 
 // ValidateMetadata 验证元数据
@@ -141,109 +344,28 @@ func (ms *MetaService) ValidateMetadata(entry *types.MetadataEntry) error {
 		return fmt.Errorf("invalid MD5 hash format")
 	}
 
-	return nil
-}
-
-// ExportMetadata 导出元数据到JSON
-func (ms *MetaService) ExportMetadata(keys []string) ([]byte, error) {
-	var entries []*types.MetadataEntry
-
-	if len(keys) == 0 {
-		// 导出所有元数据
-		allEntries, err := ms.ListMetadata(1000, 0) // 限制1000条
-		if err != nil {
-			return nil, err
-		}
-		entries = allEntries
-	} else {
-		// 导出指定的元数据
-		for _, key := range keys {
-			entry, err := ms.GetMetadata(key)
-			if err != nil {
-				continue // 跳过不存在的key
-			}
-			entries = append(entries, entry)
-		}
-	}
-
-	data, err := json.MarshalIndent(entries, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal metadata: %v", err)
-	}
-
-	return data, nil
-}
-
-// ImportMetadata 从JSON导入元数据
-func (ms *MetaService) ImportMetadata(data []byte) error {
-	var entries []*types.MetadataEntry
-
-	err := json.Unmarshal(data, &entries)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal metadata: %v", err)
-	}
-
-	successCount := 0
-	for _, entry := range entries {
-		err := ms.ValidateMetadata(entry)
-		if err != nil {
-			fmt.Printf("Warning: invalid metadata entry %s: %v\n", entry.Key, err)
-			continue
-		}
-
-		err = ms.db.SaveMetadata(entry)
-		if err != nil {
-			fmt.Printf("Warning: failed to import metadata %s: %v\n", entry.Key, err)
-			continue
-		}
-
-		successCount++
+	if len(entry.Tags) > types.MaxObjectTags {
+		return fmt.Errorf("object tags cannot be greater than %d", types.MaxObjectTags)
 	}
 
-	fmt.Printf("[META] Successfully imported %d out of %d metadata entries\n", successCount, len(entries))
 	return nil
 }
 
-// GetMetadataByPattern 根据模式获取元数据
+// GetMetadataByPattern 获取key匹配glob模式（* ? [abc]）的所有元数据，匹配在存储中完成
 func (ms *MetaService) GetMetadataByPattern(pattern string) ([]*types.MetadataEntry, error) {
-	// 简单的通配符匹配实现
-	allEntries, err := ms.ListMetadata(1000, 0)
-	if err != nil {
-		return nil, err
-	}
+	query := &types.SearchQuery{KeyPattern: pattern, SortBy: types.SearchSortKey, Limit: maxSearchLimit}
 
 	var matchedEntries []*types.MetadataEntry
-	for _, entry := range allEntries {
-		if matchPattern(entry.Key, pattern) {
-			matchedEntries = append(matchedEntries, entry)
+	for {
+		result, err := ms.SearchMetadata(query)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	return matchedEntries, nil
-}
-
-// matchPattern 简单的通配符匹配
-func matchPattern(text, pattern string) bool {
-	if pattern == "*" {
-		return true
-	}
+		matchedEntries = append(matchedEntries, result.Entries...)
 
-	if strings.Contains(pattern, "*") {
-		// 简单的前缀/后缀匹配
-		if strings.HasPrefix(pattern, "*") && strings.HasSuffix(pattern, "*") {
-			// *something*
-			middle := pattern[1 : len(pattern)-1]
-			return strings.Contains(text, middle)
-		} else if strings.HasPrefix(pattern, "*") {
-			// *suffix
-			suffix := pattern[1:]
-			return strings.HasSuffix(text, suffix)
-		} else if strings.HasSuffix(pattern, "*") {
-			// prefix*
-			prefix := pattern[:len(pattern)-1]
-			return strings.HasPrefix(text, prefix)
+		if result.NextCursor == "" {
+			return matchedEntries, nil
 		}
+		query.Cursor = result.NextCursor
 	}
-
-	return text == pattern
 }
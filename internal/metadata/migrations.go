@@ -0,0 +1,565 @@
+package metadata
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 迁移锁参数
+const (
+	migrationLockTimeout = 30 * time.Second // 等待其他实例完成迁移的最长时间
+	migrationLockStale   = 10 * time.Minute // 超过该时间的锁视为持有者已异常退出
+	migrationLockPoll    = 500 * time.Millisecond
+)
+
+// migration 一次版本化的表结构变更，up与down在同一事务中执行
+type migration struct {
+	version int
+	name    string
+	up      func(tx *migrationTx) error
+	down    func(tx *migrationTx) error
+}
+
+// column 列定义
+type column struct {
+	name       string
+	definition string
+}
+
+// migrations 所有迁移，按版本号递增排列，已发布的迁移不能修改，只能追加
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_metadata",
+		up: func(tx *migrationTx) error {
+			return tx.exec(`
+			CREATE TABLE IF NOT EXISTS metadata (
+				id TEXT PRIMARY KEY,
+				key TEXT UNIQUE NOT NULL,
+				size `+tx.dialect.bigintType+` NOT NULL,
+				content_type TEXT NOT NULL,
+				md5_hash TEXT NOT NULL,
+				storage_nodes TEXT NOT NULL, -- JSON array
+				created_at `+tx.dialect.timestampType+` NOT NULL,
+				updated_at `+tx.dialect.timestampType+` NOT NULL
+			)`,
+				`CREATE INDEX IF NOT EXISTS idx_metadata_key ON metadata(key)`,
+				`CREATE INDEX IF NOT EXISTS idx_metadata_created_at ON metadata(created_at)`,
+				`CREATE INDEX IF NOT EXISTS idx_metadata_size ON metadata(size)`,
+			)
+		},
+		down: func(tx *migrationTx) error {
+			return tx.exec(`DROP TABLE IF EXISTS metadata`)
+		},
+	},
+	{
+		version: 2,
+		name:    "add_encryption",
+		up: func(tx *migrationTx) error {
+			return tx.addColumns("metadata",
+				column{"encryption", "TEXT NOT NULL DEFAULT ''"},
+				column{"encrypted_data_key", "TEXT NOT NULL DEFAULT ''"},
+				column{"encryption_key_md5", "TEXT NOT NULL DEFAULT ''"},
+			)
+		},
+		down: func(tx *migrationTx) error {
+			return tx.dropColumns("metadata", "encryption", "encrypted_data_key", "encryption_key_md5")
+		},
+	},
+	{
+		version: 3,
+		name:    "add_etag",
+		up: func(tx *migrationTx) error {
+			return tx.addColumns("metadata", column{"etag", "TEXT NOT NULL DEFAULT ''"})
+		},
+		down: func(tx *migrationTx) error {
+			return tx.dropColumns("metadata", "etag")
+		},
+	},
+	{
+		version: 4,
+		name:    "add_sync_status",
+		up: func(tx *migrationTx) error {
+			err := tx.addColumns("metadata",
+				column{"sync_status", "TEXT NOT NULL DEFAULT ''"},
+				column{"sync_error", "TEXT NOT NULL DEFAULT ''"},
+			)
+			if err != nil {
+				return err
+			}
+			return tx.exec(`CREATE INDEX IF NOT EXISTS idx_metadata_sync_status ON metadata(sync_status)`)
+		},
+		down: func(tx *migrationTx) error {
+			if err := tx.exec(`DROP INDEX IF EXISTS idx_metadata_sync_status`); err != nil {
+				return err
+			}
+			return tx.dropColumns("metadata", "sync_status", "sync_error")
+		},
+	},
+	{
+		version: 5,
+		name:    "add_storage_class",
+		up: func(tx *migrationTx) error {
+			err := tx.addColumns("metadata",
+				column{"storage_class", "TEXT NOT NULL DEFAULT 'STANDARD'"},
+				column{"last_accessed_at", tx.dialect.timestampType},
+			)
+			if err != nil {
+				return err
+			}
+			return tx.exec(`CREATE INDEX IF NOT EXISTS idx_metadata_storage_class ON metadata(storage_class)`)
+		},
+		down: func(tx *migrationTx) error {
+			if err := tx.exec(`DROP INDEX IF EXISTS idx_metadata_storage_class`); err != nil {
+				return err
+			}
+			return tx.dropColumns("metadata", "storage_class", "last_accessed_at")
+		},
+	},
+	{
+		version: 6,
+		name:    "add_checksums",
+		up: func(tx *migrationTx) error {
+			return tx.addColumns("metadata", column{"checksums", "TEXT NOT NULL DEFAULT ''"}) // JSON object
+		},
+		down: func(tx *migrationTx) error {
+			return tx.dropColumns("metadata", "checksums")
+		},
+	},
+	{
+		version: 7,
+		name:    "add_object_headers",
+		up: func(tx *migrationTx) error {
+			return tx.addColumns("metadata",
+				column{"cache_control", "TEXT NOT NULL DEFAULT ''"},
+				column{"content_disposition", "TEXT NOT NULL DEFAULT ''"},
+				column{"content_encoding", "TEXT NOT NULL DEFAULT ''"},
+				column{"content_language", "TEXT NOT NULL DEFAULT ''"},
+				column{"expires", "TEXT NOT NULL DEFAULT ''"},
+				column{"user_metadata", "TEXT NOT NULL DEFAULT ''"}, // JSON object
+			)
+		},
+		down: func(tx *migrationTx) error {
+			return tx.dropColumns("metadata", "cache_control", "content_disposition", "content_encoding",
+				"content_language", "expires", "user_metadata")
+		},
+	},
+	{
+		version: 8,
+		name:    "add_versioning",
+		up: func(tx *migrationTx) error {
+			err := tx.addColumns("metadata", column{"version_id", "TEXT NOT NULL DEFAULT ''"})
+			if err != nil {
+				return err
+			}
+			return tx.exec(`
+			CREATE TABLE IF NOT EXISTS object_versions (
+				key TEXT NOT NULL,
+				version_id TEXT NOT NULL,
+				delete_marker INTEGER NOT NULL DEFAULT 0,
+				created_ns `+tx.dialect.bigintType+` NOT NULL, -- 版本创建时间（纳秒），用于排序
+				entry TEXT NOT NULL, -- JSON格式的MetadataEntry
+				PRIMARY KEY (key, version_id)
+			)`,
+				`CREATE INDEX IF NOT EXISTS idx_object_versions_key_created ON object_versions(key, created_ns)`,
+				`
+			CREATE TABLE IF NOT EXISTS bucket_versioning (
+				bucket TEXT PRIMARY KEY,
+				status TEXT NOT NULL,
+				updated_at `+tx.dialect.timestampType+` NOT NULL
+			)`,
+			)
+		},
+		down: func(tx *migrationTx) error {
+			err := tx.exec(
+				`DROP TABLE IF EXISTS bucket_versioning`,
+				`DROP TABLE IF EXISTS object_versions`,
+			)
+			if err != nil {
+				return err
+			}
+			return tx.dropColumns("metadata", "version_id")
+		},
+	},
+	{
+		version: 9,
+		name:    "add_object_tags",
+		up: func(tx *migrationTx) error {
+			return tx.exec(`
+			CREATE TABLE IF NOT EXISTS object_tags (
+				key TEXT NOT NULL,
+				tag_key TEXT NOT NULL,
+				tag_value TEXT NOT NULL,
+				PRIMARY KEY (key, tag_key)
+			)`,
+				`CREATE INDEX IF NOT EXISTS idx_object_tags_tag ON object_tags(tag_key, tag_value)`,
+			)
+		},
+		down: func(tx *migrationTx) error {
+			return tx.exec(`DROP TABLE IF EXISTS object_tags`)
+		},
+	},
+}
+
+// LatestSchemaVersion 当前程序支持的最新表结构版本
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// MigrationStatus 单个迁移的状态
+type MigrationStatus struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+	Unknown   bool      `json:"unknown,omitempty"` // 数据库中存在但当前程序不认识（由更新版本的程序执行）
+}
+
+// migrationTx 迁移事务，语句中的占位符按方言转换
+type migrationTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+// exec 依次执行语句
+func (tx *migrationTx) exec(statements ...string) error {
+	for _, statement := range statements {
+		if _, err := tx.Exec(tx.dialect.rebind(statement)); err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(statement))
+		}
+	}
+	return nil
+}
+
+// addColumns 添加表中缺失的列
+// 迁移体系引入之前创建的表可能已经包含部分列，已存在的列直接跳过
+func (tx *migrationTx) addColumns(table string, columns ...column) error {
+	existing, err := tx.existingColumns(table)
+	if err != nil {
+		return err
+	}
+
+	for _, col := range columns {
+		if existing[col.name] {
+			continue
+		}
+		if err := tx.exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropColumns 删除列（SQLite需要3.35+），列上的索引需要先删除
+func (tx *migrationTx) dropColumns(table string, names ...string) error {
+	existing, err := tx.existingColumns(table)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if !existing[name] {
+			continue
+		}
+		if err := tx.exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// existingColumns 获取表中已有的列
+func (tx *migrationTx) existingColumns(table string) (map[string]bool, error) {
+	var rows *sql.Rows
+	var err error
+	if tx.dialect.name == DriverPostgres {
+		rows, err = tx.Query(tx.dialect.rebind(`SELECT column_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ?`), table)
+	} else {
+		rows, err = tx.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s table: %v", table, err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table info: %v", err)
+		}
+		existing[name] = true
+	}
+	return existing, rows.Err()
+}
+
+// Migrate 执行所有未应用的迁移，返回本次应用的迁移数量
+// 多个实例同时启动时通过迁移锁保证只有一个实例执行迁移
+func (dm *DatabaseManager) Migrate() (int, error) {
+	release, err := dm.lockMigrations()
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	applied, err := dm.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		if err := dm.runMigration(m, true); err != nil {
+			return count, err
+		}
+		fmt.Printf("[DB] Applied migration %d_%s\n", m.version, m.name)
+		count++
+	}
+
+	for version := range applied {
+		if version > LatestSchemaVersion() {
+			fmt.Printf("[DB] Warning: database schema version %d is newer than supported version %d\n",
+				version, LatestSchemaVersion())
+			break
+		}
+	}
+
+	return count, nil
+}
+
+// Rollback 按版本从新到旧回滚最近应用的steps个迁移，返回回滚的迁移数量
+func (dm *DatabaseManager) Rollback(steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("steps must be positive")
+	}
+
+	release, err := dm.lockMigrations()
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	applied, err := dm.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	if err := dm.dropSearchIndexTriggers(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, version := range versions {
+		if count == steps {
+			break
+		}
+
+		m, ok := findMigration(version)
+		if !ok {
+			return count, fmt.Errorf("migration %d is unknown to this binary and cannot be rolled back", version)
+		}
+		if err := dm.runMigration(m, false); err != nil {
+			return count, err
+		}
+		fmt.Printf("[DB] Rolled back migration %d_%s\n", m.version, m.name)
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrationStatus 列出所有迁移及其应用状态
+func (dm *DatabaseManager) MigrationStatus() ([]MigrationStatus, error) {
+	if err := dm.ensureMigrationTables(); err != nil {
+		return nil, err
+	}
+
+	applied, err := dm.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.version, Name: m.name}
+		if appliedAt, ok := applied[m.version]; ok {
+			status.Applied = true
+			status.AppliedAt = appliedAt
+			delete(applied, m.version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	// 数据库中由更新版本程序执行的迁移
+	for version, appliedAt := range applied {
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Applied:   true,
+			AppliedAt: appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// ForceUnlockMigrations 强制释放迁移锁，仅用于持有锁的进程异常退出后
+func (dm *DatabaseManager) ForceUnlockMigrations() error {
+	if err := dm.ensureMigrationTables(); err != nil {
+		return err
+	}
+	_, err := dm.exec(`DELETE FROM schema_migrations_lock WHERE id = 1`)
+	if err != nil {
+		return fmt.Errorf("failed to release migration lock: %v", err)
+	}
+	return nil
+}
+
+// ensureMigrationTables 创建迁移记录表和迁移锁表
+func (dm *DatabaseManager) ensureMigrationTables() error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at ` + dm.dialect.timestampType + ` NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id INTEGER PRIMARY KEY,
+			owner TEXT NOT NULL,
+			locked_at ` + dm.dialect.bigintType + ` NOT NULL -- Unix时间戳（秒）
+		)`,
+	}
+
+	for _, statement := range statements {
+		if _, err := dm.db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create migration tables: %v", err)
+		}
+	}
+	return nil
+}
+
+// lockMigrations 获取迁移锁，返回释放函数
+// 锁为schema_migrations_lock表中id=1的行，插入成功即获得锁
+func (dm *DatabaseManager) lockMigrations() (func(), error) {
+	if err := dm.ensureMigrationTables(); err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	deadline := time.Now().Add(migrationLockTimeout)
+
+	for {
+		_, err := dm.exec(`INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, ?, ?)`,
+			owner, time.Now().Unix())
+		if err == nil {
+			break
+		}
+
+		// 持有者异常退出时锁不会被释放，超时后由其他实例清除
+		result, delErr := dm.exec(`DELETE FROM schema_migrations_lock WHERE id = 1 AND locked_at < ?`,
+			time.Now().Add(-migrationLockStale).Unix())
+		if delErr == nil {
+			if rows, _ := result.RowsAffected(); rows > 0 {
+				fmt.Println("[DB] Removed stale migration lock")
+				continue
+			}
+		}
+
+		if time.Now().After(deadline) {
+			var holder string
+			dm.queryRow(`SELECT owner FROM schema_migrations_lock WHERE id = 1`).Scan(&holder)
+			return nil, fmt.Errorf("timed out waiting for migration lock held by %q", holder)
+		}
+		time.Sleep(migrationLockPoll)
+	}
+
+	return func() {
+		if _, err := dm.exec(`DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = ?`, owner); err != nil {
+			fmt.Printf("[DB] Failed to release migration lock: %v\n", err)
+		}
+	}, nil
+}
+
+// appliedMigrations 获取已应用的迁移版本及应用时间
+func (dm *DatabaseManager) appliedMigrations() (map[int]time.Time, error) {
+	rows, err := dm.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema migration: %v", err)
+		}
+		applied[version], _ = time.Parse(time.RFC3339, appliedAt)
+	}
+	return applied, rows.Err()
+}
+
+// runMigration 在事务中执行迁移并更新迁移记录
+func (dm *DatabaseManager) runMigration(m migration, up bool) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+
+	sqlTx, err := dm.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration transaction: %v", err)
+	}
+	tx := &migrationTx{Tx: sqlTx, dialect: dm.dialect}
+
+	if up {
+		err = m.up(tx)
+		if err == nil {
+			_, err = tx.Exec(dm.dialect.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+				m.version, m.name, time.Now().UTC())
+		}
+	} else {
+		err = m.down(tx)
+		if err == nil {
+			_, err = tx.Exec(dm.dialect.rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.version)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s (%s) failed: %v", m.version, m.name, direction, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %v", m.version, m.name, err)
+	}
+	return nil
+}
+
+// findMigration 按版本查找迁移
+func findMigration(version int) (migration, bool) {
+	for _, m := range migrations {
+		if m.version == version {
+			return m, true
+		}
+	}
+	return migration{}, false
+}
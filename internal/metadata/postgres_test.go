@@ -0,0 +1,220 @@
+package metadata
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// newPostgresStore 连接POSTGRES_DSN指定的数据库，未设置时跳过测试
+// 测试在独立的schema中执行，结束后删除，不影响库中已有的表
+func newPostgresStore(t *testing.T) *DatabaseManager {
+	t.Helper()
+
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN not set")
+	}
+
+	admin, err := sql.Open(DriverPostgres, dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("metadata_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	// lib/pq将不认识的DSN参数作为会话参数发送
+	schemaDSN, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("POSTGRES_DSN must be a URL: %v", err)
+	}
+	query := schemaDSN.Query()
+	query.Set("search_path", schema)
+	schemaDSN.RawQuery = query.Encode()
+
+	store, err := NewDatabaseManager(DriverPostgres, schemaDSN.String())
+	if err != nil {
+		t.Fatalf("open postgres store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// newSQLiteStore 在临时目录中创建SQLite元数据存储
+func newSQLiteStore(t *testing.T) *DatabaseManager {
+	t.Helper()
+
+	store, err := NewDatabaseManager(DriverSQLite, filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// seedStore 写入相同的测试数据，部分对象的创建时间相同以检查排序的稳定性
+func seedStore(t *testing.T, store MetadataStore) {
+	t.Helper()
+
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	objects := []struct {
+		key         string
+		size        int64
+		contentType string
+		created     time.Time
+		meta        map[string]string
+		tags        map[string]string
+	}{
+		{"photos/b.jpg", 300, "image/jpeg", base, map[string]string{"project": "apollo"}, map[string]string{"env": "prod"}},
+		{"photos/a.jpg", 100, "image/png", base, nil, map[string]string{"env": "dev"}},
+		{"docs/Report-2026.txt", 50, "text/plain", base.Add(time.Minute), map[string]string{"project": "gemini"}, nil},
+		{"docs/notes.md", 75, "text/markdown", base.Add(2 * time.Minute), map[string]string{"owner": "REPORT team"}, nil},
+		{"docs/archive/old.txt", 10, "text/plain", base.Add(-time.Hour), nil, map[string]string{"env": "prod"}},
+		{"photos/c.gif", 200, "image/gif", base.Add(time.Hour), nil, nil},
+	}
+
+	for _, object := range objects {
+		entry := &types.MetadataEntry{
+			ID:           "id-" + object.key,
+			Key:          object.key,
+			Size:         object.size,
+			ContentType:  object.contentType,
+			MD5Hash:      "md5",
+			StorageNodes: []string{"stg1"},
+			CreatedAt:    object.created,
+			UpdatedAt:    object.created,
+			StorageClass: types.StorageClassStandard,
+		}
+		entry.UserMetadata = object.meta
+		if err := store.SaveMetadata(entry); err != nil {
+			t.Fatalf("save %s: %v", object.key, err)
+		}
+		if object.tags != nil {
+			if err := store.SetTags(object.key, entry.ID, object.tags); err != nil {
+				t.Fatalf("tag %s: %v", object.key, err)
+			}
+		}
+	}
+
+	for i, versionID := range []string{"v1", "v2"} {
+		version := &types.MetadataEntry{
+			ID:        "version-" + versionID,
+			Key:       "docs/notes.md",
+			Size:      int64(i + 1),
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+			UpdatedAt: base.Add(time.Duration(i) * time.Second),
+			VersionID: versionID,
+		}
+		if err := store.SaveVersion(version); err != nil {
+			t.Fatalf("save version %s: %v", versionID, err)
+		}
+	}
+}
+
+// entryKeys 提取元数据的key，比较两个后端返回的顺序
+func entryKeys(entries []*types.MetadataEntry) []string {
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+		if entry.VersionID != "" {
+			keys[i] += "@" + entry.VersionID
+		}
+	}
+	return keys
+}
+
+// searchAll 填充默认值后按游标翻页取出全部搜索结果
+func searchAll(t *testing.T, store MetadataStore, query types.SearchQuery) []string {
+	t.Helper()
+
+	if err := NormalizeSearchQuery(&query); err != nil {
+		t.Fatalf("normalize %+v: %v", query, err)
+	}
+
+	var keys []string
+	for page := 0; page < 20; page++ {
+		result, err := store.SearchMetadata(&query)
+		if err != nil {
+			t.Fatalf("search %+v: %v", query, err)
+		}
+		keys = append(keys, entryKeys(result.Entries)...)
+		if result.NextCursor == "" {
+			return keys
+		}
+		query.Cursor = result.NextCursor
+	}
+	t.Fatalf("search %+v did not finish paging", query)
+	return nil
+}
+
+func TestPostgresMatchesSQLite(t *testing.T) {
+	postgres := newPostgresStore(t)
+	sqlite := newSQLiteStore(t)
+	seedStore(t, postgres)
+	seedStore(t, sqlite)
+
+	for _, page := range []struct{ limit, offset int }{{100, 0}, {2, 0}, {2, 2}, {3, 4}} {
+		want, err := sqlite.ListMetadata(page.limit, page.offset)
+		if err != nil {
+			t.Fatalf("sqlite list: %v", err)
+		}
+		got, err := postgres.ListMetadata(page.limit, page.offset)
+		if err != nil {
+			t.Fatalf("postgres list: %v", err)
+		}
+		if !reflect.DeepEqual(entryKeys(got), entryKeys(want)) {
+			t.Errorf("ListMetadata(%d, %d) = %v, sqlite returned %v", page.limit, page.offset, entryKeys(got), entryKeys(want))
+		}
+	}
+
+	minSize, maxSize := int64(50), int64(200)
+	queries := []types.SearchQuery{
+		{Text: "report"},
+		{Text: "REPORT", Limit: 1},
+		{KeyPrefix: "docs/"},
+		{KeyPattern: "photos/[ab].jpg"},
+		{KeyPattern: "docs/*.txt"},
+		{ContentType: "image/*", SortBy: "size", Descending: true},
+		{ContentType: "TEXT/PLAIN"},
+		{MinSize: &minSize, MaxSize: &maxSize, SortBy: "key", Limit: 2},
+		{UserMetadata: map[string]string{"project": "apollo"}},
+		{Tags: map[string]string{"env": "prod"}, SortBy: "updated"},
+		{CreatedAfter: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Limit: 2},
+		{SortBy: "created", Limit: 1},
+	}
+	for _, query := range queries {
+		want := searchAll(t, sqlite, query)
+		got := searchAll(t, postgres, query)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchMetadata(%+v) = %v, sqlite returned %v", query, got, want)
+		}
+	}
+
+	wantVersions, err := sqlite.ListVersions("docs/", 100)
+	if err != nil {
+		t.Fatalf("sqlite versions: %v", err)
+	}
+	gotVersions, err := postgres.ListVersions("docs/", 100)
+	if err != nil {
+		t.Fatalf("postgres versions: %v", err)
+	}
+	if !reflect.DeepEqual(entryKeys(gotVersions), entryKeys(wantVersions)) {
+		t.Errorf("ListVersions = %v, sqlite returned %v", entryKeys(gotVersions), entryKeys(wantVersions))
+	}
+}
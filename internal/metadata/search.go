@@ -0,0 +1,441 @@
+package metadata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mock-storage/internal/types"
+)
+
+// ErrInvalidSearch 搜索条件无效（排序字段、游标等）
+var ErrInvalidSearch = errors.New("invalid search query")
+
+// 搜索结果数量
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 1000
+)
+
+// NormalizeSearchQuery 校验搜索条件并填充默认值
+func NormalizeSearchQuery(query *types.SearchQuery) error {
+	switch query.SortBy {
+	case "":
+		query.SortBy = types.SearchSortCreated
+	case types.SearchSortKey, types.SearchSortSize, types.SearchSortCreated, types.SearchSortUpdated:
+	default:
+		return fmt.Errorf("%w: unsupported sort field %q", ErrInvalidSearch, query.SortBy)
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	}
+	if query.Limit > maxSearchLimit {
+		query.Limit = maxSearchLimit
+	}
+
+	if query.MinSize != nil && query.MaxSize != nil && *query.MinSize > *query.MaxSize {
+		return fmt.Errorf("%w: min size is greater than max size", ErrInvalidSearch)
+	}
+	if _, err := decodeSearchCursor(query); err != nil {
+		return err
+	}
+	return nil
+}
+
+// searchCursor 分页游标：上一页最后一条结果的排序值和key，与排序方式绑定
+type searchCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	Key        string `json:"k"`
+}
+
+// encodeSearchCursor 生成entry之后的下一页游标
+func encodeSearchCursor(query *types.SearchQuery, entry *types.MetadataEntry) string {
+	data, _ := json.Marshal(searchCursor{
+		Sort:       query.SortBy,
+		Descending: query.Descending,
+		Value:      searchSortValue(entry, query.SortBy),
+		Key:        entry.Key,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSearchCursor 解析游标，没有游标时返回nil
+func decodeSearchCursor(query *types.SearchQuery) (*searchCursor, error) {
+	if query.Cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidSearch)
+	}
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidSearch)
+	}
+	if cursor.Sort != query.SortBy || cursor.Descending != query.Descending {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidSearch)
+	}
+	if _, err := cursor.sortArg(); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidSearch)
+	}
+	return &cursor, nil
+}
+
+// sortArg 将游标中的排序值转换为查询参数
+func (c *searchCursor) sortArg() (any, error) {
+	switch c.Sort {
+	case types.SearchSortSize:
+		return strconv.ParseInt(c.Value, 10, 64)
+	case types.SearchSortCreated, types.SearchSortUpdated:
+		return time.Parse(time.RFC3339Nano, c.Value)
+	default:
+		return c.Value, nil
+	}
+}
+
+// searchSortValue 获取元数据在排序字段上的值
+func searchSortValue(entry *types.MetadataEntry, sortBy string) string {
+	switch sortBy {
+	case types.SearchSortSize:
+		return strconv.FormatInt(entry.Size, 10)
+	case types.SearchSortCreated:
+		return entry.CreatedAt.Format(time.RFC3339Nano)
+	case types.SearchSortUpdated:
+		return entry.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		return entry.Key
+	}
+}
+
+// globToRegexp 将glob模式转换为锚定的正则表达式（语义与SQLite GLOB一致，区分大小写）
+func globToRegexp(pattern string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+
+	for i := 0; i < len(pattern); {
+		r, size := utf8.DecodeRuneInString(pattern[i:])
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '[':
+			if class, n, ok := globClass(pattern[i:]); ok {
+				builder.WriteString(class)
+				i += n
+				continue
+			}
+			builder.WriteString(`\[`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+		i += size
+	}
+
+	builder.WriteString("$")
+	return builder.String()
+}
+
+// globClass 转换以[开头的字符集，返回正则表达式、消耗的字节数，没有闭合的]时返回false
+// 紧跟在[或[^之后的]是普通字符
+func globClass(pattern string) (string, int, bool) {
+	i := 1
+	negate := false
+	if i < len(pattern) && pattern[i] == '^' {
+		negate = true
+		i++
+	}
+	start := i
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+	for i < len(pattern) && pattern[i] != ']' {
+		i++
+	}
+	if i >= len(pattern) {
+		return "", 0, false
+	}
+
+	var builder strings.Builder
+	builder.WriteString("[")
+	if negate {
+		builder.WriteString("^")
+	}
+	for _, r := range pattern[start:i] {
+		switch r {
+		case '\\', '[', ']', '^':
+			builder.WriteString(`\`)
+		}
+		builder.WriteRune(r)
+	}
+	builder.WriteString("]")
+	return builder.String(), i + 1, true
+}
+
+// escapeLike 转义LIKE模式中的通配符，配合ESCAPE '\'使用
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
+
+// ftsQuery 将搜索文本转换为FTS5查询：每个词作为带前缀匹配的短语，所有词都需要匹配
+func ftsQuery(text string) string {
+	var terms []string
+	for _, term := range strings.Fields(text) {
+		terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchIndexTriggers 保持全文索引与元数据表同步的触发器（外部内容表需要显式写入删除记录）
+var searchIndexTriggers = map[string]string{
+	"metadata_fts_insert": `CREATE TRIGGER IF NOT EXISTS metadata_fts_insert AFTER INSERT ON metadata BEGIN
+		INSERT INTO metadata_fts(rowid, key, content_type, user_metadata)
+		VALUES (new.rowid, new.key, new.content_type, new.user_metadata);
+	END`,
+	"metadata_fts_delete": `CREATE TRIGGER IF NOT EXISTS metadata_fts_delete AFTER DELETE ON metadata BEGIN
+		INSERT INTO metadata_fts(metadata_fts, rowid, key, content_type, user_metadata)
+		VALUES ('delete', old.rowid, old.key, old.content_type, old.user_metadata);
+	END`,
+	"metadata_fts_update": `CREATE TRIGGER IF NOT EXISTS metadata_fts_update AFTER UPDATE ON metadata BEGIN
+		INSERT INTO metadata_fts(metadata_fts, rowid, key, content_type, user_metadata)
+		VALUES ('delete', old.rowid, old.key, old.content_type, old.user_metadata);
+		INSERT INTO metadata_fts(rowid, key, content_type, user_metadata)
+		VALUES (new.rowid, new.key, new.content_type, new.user_metadata);
+	END`,
+}
+
+// ensureSearchIndex 在SQLite编译了FTS5时（-tags sqlite_fts5）建立key、内容类型和用户元数据的全文索引
+// 索引是元数据表的派生数据，不通过迁移管理：每次启动时检查，触发器缺失（首次启用或
+// 曾用不支持FTS5的程序运行过）时重建索引。不支持FTS5时删除触发器，搜索退回LIKE匹配
+func (dm *DatabaseManager) ensureSearchIndex() error {
+	if dm.dialect.name != DriverSQLite {
+		return nil
+	}
+
+	var enabled int
+	if err := dm.db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&enabled); err != nil || enabled == 0 {
+		if err := dm.dropSearchIndexTriggers(); err != nil {
+			return err
+		}
+		fmt.Println("[DB] SQLite built without FTS5, full-text search falls back to LIKE matching")
+		return nil
+	}
+
+	var existing int
+	err := dm.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'metadata_fts_%'`).Scan(&existing)
+	if err != nil {
+		return fmt.Errorf("failed to check search index: %v", err)
+	}
+
+	if existing < len(searchIndexTriggers) {
+		err = dm.WithTransaction(func(store MetadataStore) error {
+			tx := store.(*DatabaseManager)
+			statements := []string{
+				`CREATE VIRTUAL TABLE IF NOT EXISTS metadata_fts USING fts5(
+					key, content_type, user_metadata, content='metadata', tokenize='unicode61')`,
+			}
+			for _, trigger := range searchIndexTriggers {
+				statements = append(statements, trigger)
+			}
+			statements = append(statements, `INSERT INTO metadata_fts(metadata_fts) VALUES ('rebuild')`)
+
+			for _, statement := range statements {
+				if _, err := tx.exec(statement); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to build search index: %v", err)
+		}
+		fmt.Println("[DB] Built full-text search index")
+	}
+
+	dm.fullText = true
+	return nil
+}
+
+// dropSearchIndexTriggers 删除全文索引触发器，下次启动时重建索引
+// 触发器引用了元数据表的列，回滚迁移删除列之前也需要先删除
+func (dm *DatabaseManager) dropSearchIndexTriggers() error {
+	if dm.dialect.name != DriverSQLite {
+		return nil
+	}
+	for name := range searchIndexTriggers {
+		if _, err := dm.db.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+			return fmt.Errorf("failed to drop search index trigger: %v", err)
+		}
+	}
+	return nil
+}
+
+// SearchMetadata 按条件搜索当前版本的元数据，按排序字段和key排序，使用游标分页
+func (dm *DatabaseManager) SearchMetadata(query *types.SearchQuery) (*types.SearchResult, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, values ...any) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	if query.Text != "" {
+		if dm.fullText {
+			where("rowid IN (SELECT rowid FROM metadata_fts WHERE metadata_fts MATCH ?)", ftsQuery(query.Text))
+		} else {
+			for _, term := range strings.Fields(query.Text) {
+				pattern := "%" + escapeLike(strings.ToLower(term)) + "%"
+				where(`(LOWER(key) LIKE ? ESCAPE '\' OR LOWER(content_type) LIKE ? ESCAPE '\'
+					OR LOWER(user_metadata) LIKE ? ESCAPE '\')`, pattern, pattern, pattern)
+			}
+		}
+	}
+
+	if query.KeyPrefix != "" {
+		where("substr(key, 1, ?) = ?", utf8.RuneCountInString(query.KeyPrefix), query.KeyPrefix)
+	}
+	if query.KeyPattern != "" {
+		condition, arg := dm.dialect.globCondition("key", query.KeyPattern)
+		where(condition, arg)
+	}
+
+	if contentType := strings.ToLower(query.ContentType); contentType != "" {
+		if major, ok := strings.CutSuffix(contentType, "/*"); ok {
+			where("LOWER(substr(content_type, 1, ?)) = ?", utf8.RuneCountInString(major)+1, major+"/")
+		} else {
+			where("LOWER(content_type) = ?", contentType)
+		}
+	}
+	if query.StorageClass != "" {
+		where("storage_class = ?", query.StorageClass)
+	}
+
+	if query.MinSize != nil {
+		where("size >= ?", *query.MinSize)
+	}
+	if query.MaxSize != nil {
+		where("size <= ?", *query.MaxSize)
+	}
+
+	timeRanges := []struct {
+		column   string
+		operator string
+		bound    time.Time
+	}{
+		{"created_at", ">=", query.CreatedAfter},
+		{"created_at", "<=", query.CreatedBefore},
+		{"updated_at", ">=", query.UpdatedAfter},
+		{"updated_at", "<=", query.UpdatedBefore},
+	}
+	for _, r := range timeRanges {
+		if !r.bound.IsZero() {
+			where(dm.dialect.timeExpr(r.column)+" "+r.operator+" "+dm.dialect.timeExpr("?"), r.bound)
+		}
+	}
+
+	// 用户元数据以JSON保存，按 "名称":"值" 片段匹配
+	for name, value := range query.UserMetadata {
+		fragment, err := marshalStringMap(map[string]string{name: value})
+		if err != nil {
+			return nil, err
+		}
+		where(dm.dialect.containsFunc+"(user_metadata, ?) > 0", strings.TrimSuffix(strings.TrimPrefix(fragment, "{"), "}"))
+	}
+
+	for name, value := range query.Tags {
+		where("EXISTS (SELECT 1 FROM object_tags t WHERE t.key = metadata.key AND t.tag_key = ? AND t.tag_value = ?)", name, value)
+	}
+
+	// 时间排序时游标参数同样需要经过时间函数
+	sortExpr, placeholder := "key", "?"
+	switch query.SortBy {
+	case types.SearchSortSize:
+		sortExpr = "size"
+	case types.SearchSortCreated:
+		sortExpr, placeholder = dm.dialect.timeExpr("created_at"), dm.dialect.timeExpr("?")
+	case types.SearchSortUpdated:
+		sortExpr, placeholder = dm.dialect.timeExpr("updated_at"), dm.dialect.timeExpr("?")
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	cursor, err := decodeSearchCursor(query)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		if query.SortBy == types.SearchSortKey {
+			where("key "+comparison+" ?", cursor.Key)
+		} else {
+			value, _ := cursor.sortArg()
+			where(fmt.Sprintf("(%s %s %s OR (%s = %s AND key %s ?))",
+				sortExpr, comparison, placeholder, sortExpr, placeholder, comparison), value, value, cursor.Key)
+		}
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	orderBy := "key " + direction
+	if query.SortBy != types.SearchSortKey {
+		orderBy = sortExpr + " " + direction + ", " + orderBy
+	}
+
+	// 多取一条判断是否还有下一页
+	searchSQL := `SELECT ` + metadataColumns + ` FROM metadata ` + whereClause + `
+	ORDER BY ` + orderBy + ` LIMIT ?`
+	args = append(args, query.Limit+1)
+
+	rows, err := dm.query(searchSQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search metadata: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*types.MetadataEntry
+	for rows.Next() {
+		entry, err := scanMetadata(rows)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+	rows.Close()
+
+	if err := dm.attachTags(entries); err != nil {
+		return nil, err
+	}
+	return newSearchResult(query, entries), nil
+}
+
+// newSearchResult 截取一页结果，多取的一条存在时生成下一页游标
+func newSearchResult(query *types.SearchQuery, entries []*types.MetadataEntry) *types.SearchResult {
+	result := &types.SearchResult{Entries: entries}
+	if len(entries) > query.Limit {
+		result.Entries = entries[:query.Limit]
+		result.NextCursor = encodeSearchCursor(query, result.Entries[query.Limit-1])
+	}
+	if result.Entries == nil {
+		result.Entries = []*types.MetadataEntry{}
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package metadata
+
+import (
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// 元数据存储驱动
+const (
+	DriverSQLite = "sqlite3"
+	DriverMemory = "memory"
+)
+
+// MetadataStore 元数据存储接口
+type MetadataStore interface {
+	SaveMetadata(entry *types.MetadataEntry) error
+	GetMetadata(key string) (*types.MetadataEntry, error)
+	DeleteMetadata(key string) error
+	ListMetadata(limit, offset int) ([]*types.MetadataEntry, error)
+	UpdateMetadata(entry *types.MetadataEntry) error
+	UpdateStorageClass(key, objectID, storageClass string, storageNodes []string) error
+	SetTags(key, objectID string, tags map[string]string) error
+	TouchLastAccess(key string, accessedAt time.Time) error
+	UpdateSyncStatus(key, status, syncError string) error
+	ListMetadataBySyncStatus(statuses []string, limit, offset int) ([]*types.MetadataEntry, error)
+	GetStats() (map[string]any, error)
+	SearchMetadata(query *types.SearchQuery) (*types.SearchResult, error)
+	SaveVersion(entry *types.MetadataEntry) error
+	GetVersion(key, versionID string) (*types.MetadataEntry, error)
+	DeleteVersion(key, versionID string) error
+	GetLatestVersion(key string) (*types.MetadataEntry, error)
+	ListVersions(prefix string, limit int) ([]*types.MetadataEntry, error)
+	GetBucketVersioning(bucket string) (string, error)
+	SetBucketVersioning(bucket, status string) error
+	WithTransaction(fn func(store MetadataStore) error) error
+	Close() error
+}
+
+// NewMetadataStore 根据驱动创建元数据存储
+func NewMetadataStore(driver, dsn string) (MetadataStore, error) {
+	if driver == DriverMemory {
+		return NewMemoryStore(), nil
+	}
+	return NewDatabaseManager(driver, dsn)
+}
@@ -0,0 +1,98 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+
+	"mock-storage/internal/types"
+)
+
+// attachTagsBatch 批量加载标签时每条查询的key数量
+const attachTagsBatch = 500
+
+// SetTags 替换对象当前版本的标签，仅当对象ID未变化时更新，避免覆盖期间重新上传的对象
+func (dm *DatabaseManager) SetTags(key, objectID string, tags map[string]string) error {
+	return dm.WithTransaction(func(store MetadataStore) error {
+		tx := store.(*DatabaseManager)
+
+		var count int
+		if err := tx.queryRow(`SELECT COUNT(*) FROM metadata WHERE key = ? AND id = ?`, key, objectID).Scan(&count); err != nil {
+			return fmt.Errorf("failed to query metadata: %v", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("metadata for key %s changed or was deleted", key)
+		}
+
+		return tx.replaceTags(key, tags)
+	})
+}
+
+// replaceTags 用tags替换key的全部标签，调用方需要在事务中执行
+func (dm *DatabaseManager) replaceTags(key string, tags map[string]string) error {
+	if _, err := dm.exec(`DELETE FROM object_tags WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("failed to delete tags: %v", err)
+	}
+
+	for tagKey, tagValue := range tags {
+		_, err := dm.exec(`INSERT INTO object_tags (key, tag_key, tag_value) VALUES (?, ?, ?)`, key, tagKey, tagValue)
+		if err != nil {
+			return fmt.Errorf("failed to save tag %s: %v", tagKey, err)
+		}
+	}
+	return nil
+}
+
+// attachTags 为查询得到的当前版本元数据批量加载标签
+func (dm *DatabaseManager) attachTags(entries []*types.MetadataEntry) error {
+	byKey := make(map[string]*types.MetadataEntry, len(entries))
+	keys := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		if entry.DeleteMarker {
+			continue
+		}
+		byKey[entry.Key] = entry
+		keys = append(keys, entry.Key)
+	}
+
+	for start := 0; start < len(keys); start += attachTagsBatch {
+		batch := keys[start:min(start+attachTagsBatch, len(keys))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+
+		rows, err := dm.query(`SELECT key, tag_key, tag_value FROM object_tags WHERE key IN (`+placeholders+`)`, batch...)
+		if err != nil {
+			return fmt.Errorf("failed to query tags: %v", err)
+		}
+		for rows.Next() {
+			var key, tagKey, tagValue string
+			if err := rows.Scan(&key, &tagKey, &tagValue); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan tag: %v", err)
+			}
+			entry := byKey[key]
+			if entry.Tags == nil {
+				entry.Tags = make(map[string]string)
+			}
+			entry.Tags[tagKey] = tagValue
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("error during rows iteration: %v", err)
+		}
+	}
+	return nil
+}
+
+// SetTags 替换对象当前版本的标签，对象ID变化时拒绝更新
+func (ms *MemoryStore) SetTags(key, objectID string, tags map[string]string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry, exists := ms.entries[key]
+	if !exists || entry.ID != objectID {
+		return fmt.Errorf("metadata for key %s changed or was deleted", key)
+	}
+
+	entry.Tags = copyStringMap(tags)
+	return nil
+}
@@ -0,0 +1,187 @@
+package metadata
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"mock-storage/internal/types"
+)
+
+// SaveVersion 保存对象版本（包括删除标记）到版本历史，同一key和版本ID已存在时覆盖
+func (dm *DatabaseManager) SaveVersion(entry *types.MetadataEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal version: %v", err)
+	}
+
+	deleteMarker := 0
+	if entry.DeleteMarker {
+		deleteMarker = 1
+	}
+
+	_, err = dm.exec(`
+	INSERT INTO object_versions (key, version_id, delete_marker, created_ns, entry)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (key, version_id) DO UPDATE SET
+		delete_marker = excluded.delete_marker, created_ns = excluded.created_ns, entry = excluded.entry
+	`, entry.Key, entry.VersionID, deleteMarker, entry.CreatedAt.UnixNano(), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save version: %v", err)
+	}
+	return nil
+}
+
+// GetVersion 获取对象的指定版本
+func (dm *DatabaseManager) GetVersion(key, versionID string) (*types.MetadataEntry, error) {
+	var data string
+	err := dm.queryRow(`SELECT entry FROM object_versions WHERE key = ? AND version_id = ?`,
+		key, versionID).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("version %s not found for key: %s", versionID, key)
+		}
+		return nil, fmt.Errorf("failed to query version: %v", err)
+	}
+	return unmarshalVersion(data)
+}
+
+// DeleteVersion 从版本历史中删除对象的指定版本
+func (dm *DatabaseManager) DeleteVersion(key, versionID string) error {
+	result, err := dm.exec(`DELETE FROM object_versions WHERE key = ? AND version_id = ?`, key, versionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete version: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("version %s not found for key: %s", versionID, key)
+	}
+	return nil
+}
+
+// GetLatestVersion 获取版本历史中最新的版本（可能是删除标记）
+func (dm *DatabaseManager) GetLatestVersion(key string) (*types.MetadataEntry, error) {
+	var data string
+	err := dm.queryRow(`SELECT entry FROM object_versions WHERE key = ?
+		ORDER BY created_ns DESC, version_id DESC LIMIT 1`, key).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no versions found for key: %s", key)
+		}
+		return nil, fmt.Errorf("failed to query latest version: %v", err)
+	}
+	return unmarshalVersion(data)
+}
+
+// ListVersions 列出key以prefix开头的所有版本，按key正序、创建时间倒序排列
+// 启用版本控制之前写入、尚未被覆盖的对象不在版本历史中，以空版本ID一并返回
+func (dm *DatabaseManager) ListVersions(prefix string, limit int) ([]*types.MetadataEntry, error) {
+	prefixCondition, args := "1 = 1", []any{}
+	if prefix != "" {
+		prefixCondition = "substr(key, 1, ?) = ?"
+		args = append(args, utf8.RuneCountInString(prefix), prefix)
+	}
+
+	rows, err := dm.query(`SELECT entry FROM object_versions WHERE `+prefixCondition+`
+		ORDER BY key ASC, created_ns DESC, version_id DESC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query versions: %v", err)
+	}
+
+	var entries []*types.MetadataEntry
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan version: %v", err)
+		}
+		entry, err := unmarshalVersion(data)
+		if err != nil {
+			continue // 跳过损坏的记录
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+
+	rows, err = dm.query(`SELECT `+metadataColumns+` FROM metadata WHERE version_id = '' AND `+prefixCondition+`
+		ORDER BY key ASC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unversioned metadata: %v", err)
+	}
+	defer rows.Close()
+
+	var unversioned []*types.MetadataEntry
+	for rows.Next() {
+		entry, err := scanMetadata(rows)
+		if err != nil {
+			continue
+		}
+		unversioned = append(unversioned, entry)
+	}
+	rows.Close()
+
+	if err := dm.attachTags(unversioned); err != nil {
+		return nil, err
+	}
+	entries = append(entries, unversioned...)
+
+	sortVersions(entries)
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// GetBucketVersioning 获取bucket的版本控制状态，从未配置时返回空字符串
+func (dm *DatabaseManager) GetBucketVersioning(bucket string) (string, error) {
+	var status string
+	err := dm.queryRow(`SELECT status FROM bucket_versioning WHERE bucket = ?`, bucket).Scan(&status)
+	if err == sql.ErrNoRows {
+		return types.VersioningUnversioned, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query bucket versioning: %v", err)
+	}
+	return status, nil
+}
+
+// SetBucketVersioning 设置bucket的版本控制状态
+func (dm *DatabaseManager) SetBucketVersioning(bucket, status string) error {
+	_, err := dm.exec(`
+	INSERT INTO bucket_versioning (bucket, status, updated_at) VALUES (?, ?, ?)
+	ON CONFLICT (bucket) DO UPDATE SET status = excluded.status, updated_at = excluded.updated_at
+	`, bucket, status, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save bucket versioning: %v", err)
+	}
+	return nil
+}
+
+// unmarshalVersion 解析版本历史中保存的元数据
+func unmarshalVersion(data string) (*types.MetadataEntry, error) {
+	var entry types.MetadataEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal version: %v", err)
+	}
+	return &entry, nil
+}
+
+// sortVersions 按key正序、创建时间倒序排列版本，与SQL实现一致
+func sortVersions(entries []*types.MetadataEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Key != entries[j].Key {
+			return entries[i].Key < entries[j].Key
+		}
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(entries[j].CreatedAt)
+		}
+		return entries[i].VersionID > entries[j].VersionID
+	})
+}
@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// StorageManager 存储管理器接口（避免循环依赖）
+type StorageManager interface {
+	GetNodes() []types.StorageNode
+}
+
+// tempSweeper 可以清理残留临时文件的存储节点（本地文件节点）
+type tempSweeper interface {
+	SweepTempFiles(olderThan time.Duration) (int, error)
+}
+
+// nodeUnwrapper 包装其他存储节点的装饰器（如故障注入）
+type nodeUnwrapper interface {
+	Unwrap() types.StorageNode
+}
+
+// defaultTempFileAge 清理任务未指定older_than时，临时文件残留多久后被删除
+const defaultTempFileAge = time.Hour
+
+// storageHandlers 直接操作存储节点的任务处理器
+type storageHandlers struct {
+	storageManager StorageManager
+}
+
+// RegisterStorageHandlers 注册直接操作存储节点的任务处理器：upload_completed、cleanup、delete_from_storage
+func RegisterStorageHandlers(registry *Registry, storageManager StorageManager) error {
+	h := &storageHandlers{storageManager: storageManager}
+
+	if err := registry.Register(types.TaskTypeUploadCompleted, h.processUploadCompleted, HandlerOptions{}); err != nil {
+		return err
+	}
+	// 清理任务遍历所有节点的目录，同一时间只执行一个
+	if err := RegisterTyped(registry, types.TaskTypeCleanup, h.processCleanup, HandlerOptions{Concurrency: 1}); err != nil {
+		return err
+	}
+	return RegisterTyped(registry, types.TaskTypeDeleteFromStorage, h.processDeleteFromStorage, HandlerOptions{})
+}
+
+// processUploadCompleted 处理上传完成任务
+func (h *storageHandlers) processUploadCompleted(ctx context.Context, task *types.TaskMessage) error {
+	fmt.Printf("[WORKER] Processing upload completed for object: %s\n", task.ObjectID)
+
+	// 这里可以添加上传后的处理逻辑，比如：
+	// - 发送通知
+	// - 触发第三方服务
+	// - 生成缩略图
+	// - 病毒扫描等
+
+	// 模拟处理时间
+	time.Sleep(100 * time.Millisecond)
+
+	return nil
+}
+
+// processCleanup 处理清理任务，未指定对象时清理各存储节点上残留的临时文件
+func (h *storageHandlers) processCleanup(ctx context.Context, task *types.TaskMessage, payload *types.CleanupPayload) error {
+	if task.ObjectID == "" {
+		olderThan := defaultTempFileAge
+		if payload.OlderThan > 0 {
+			olderThan = time.Duration(payload.OlderThan) * time.Second
+		}
+		return h.sweepTempFiles(olderThan)
+	}
+
+	fmt.Printf("[WORKER] Processing cleanup for object: %s\n", task.ObjectID)
+
+	// 这里可以添加清理逻辑，比如：
+	// - 清理临时文件
+	// - 清理过期数据
+	// - 垃圾回收等
+
+	time.Sleep(50 * time.Millisecond)
+
+	return nil
+}
+
+// sweepTempFiles 删除各本地存储节点上残留超过olderThan的上传临时文件
+func (h *storageHandlers) sweepTempFiles(olderThan time.Duration) error {
+	removed := 0
+	var lastError error
+	for _, node := range h.storageManager.GetNodes() {
+		for {
+			wrapper, ok := node.(nodeUnwrapper)
+			if !ok {
+				break
+			}
+			node = wrapper.Unwrap()
+		}
+
+		sweeper, ok := node.(tempSweeper)
+		if !ok {
+			continue
+		}
+		count, err := sweeper.SweepTempFiles(olderThan)
+		removed += count
+		if err != nil {
+			lastError = err
+		}
+	}
+
+	fmt.Printf("[WORKER] Swept %d leftover temp files (older than %v)\n", removed, olderThan)
+	return lastError
+}
+
+// processDeleteFromStorage 处理从存储节点删除任务
+func (h *storageHandlers) processDeleteFromStorage(ctx context.Context, task *types.TaskMessage, payload *types.ObjectTaskPayload) error {
+	fmt.Printf("[WORKER] Processing delete from storage for object: %s\n", task.ObjectID)
+
+	key := payload.Key
+
+	// 从所有存储节点删除文件
+	fmt.Printf("[WORKER] Deleting file %s from all storage nodes\n", key)
+
+	var lastError error
+	deletedCount := 0
+
+	for _, node := range h.storageManager.GetNodes() {
+		err := node.Delete(key)
+		if err != nil {
+			fmt.Printf("[WORKER] Warning: failed to delete from node %s: %v\n", node.GetNodeID(), err)
+			lastError = err
+		} else {
+			fmt.Printf("[WORKER] Successfully deleted from node %s: %s\n", node.GetNodeID(), key)
+			deletedCount++
+		}
+	}
+
+	// 如果至少有一个节点删除成功，认为任务成功
+	if deletedCount > 0 {
+		fmt.Printf("[WORKER] Delete task completed: %d nodes processed, %d successful\n", len(h.storageManager.GetNodes()), deletedCount)
+		return nil
+	}
+
+	// 如果所有节点都删除失败，返回最后一个错误
+	if lastError != nil {
+		return fmt.Errorf("failed to delete from all storage nodes: %v", lastError)
+	}
+
+	return nil
+}
@@ -1,33 +1,103 @@
 package queue
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"mock-storage/internal/types"
+
+	"github.com/google/uuid"
 )
 
+// pollInterval 没有可领取的任务时工作节点重新检查队列的间隔（入队时会立即唤醒）
+const pollInterval = time.Second
+
+// purgeInterval 删除超过保留时间的成功任务的间隔
+const purgeInterval = time.Minute
+
+// DefaultRetention 成功任务默认保留的时间，超过后从队列存储中删除
+const DefaultRetention = 24 * time.Hour
+
 // Manager 队列管理器
 type Manager struct {
-	queue     chan *types.TaskMessage
-	workers   []*Worker
-	maxSize   int
-	mutex     sync.RWMutex
-	running   bool
-	waitGroup sync.WaitGroup
+	store         Store
+	registry      *Registry
+	workers       []*Worker
+	maxSize       int
+	leaseTimeout  time.Duration
+	policies      map[string]RetryPolicy // 按任务类型配置的重试策略
+	defaultPolicy RetryPolicy
+	retention     time.Duration
+	mutex         sync.RWMutex
+	claimMu       sync.Mutex // 串行领取任务，保证按并发上限跳过的类型与领取后占用的名额一致
+	running       bool
+	stopCh        chan struct{}
+	wakeCh        chan struct{}
+	waitGroup     sync.WaitGroup
+
+	changeMu sync.Mutex
+	changeCh chan struct{} // 任务处理结束（成功、失败或进入死信队列）时关闭并替换，用于等待任务
 }
 
-// NewManager 创建队列管理器
-func NewManager(maxSize int) *Manager {
-	return &Manager{
-		queue:   make(chan *types.TaskMessage, maxSize),
-		maxSize: maxSize,
-		workers: make([]*Worker, 0),
-		running: false,
+// NewManager 创建队列管理器，任务保存在store中，工作节点每次领取任务的租约为leaseTimeout
+func NewManager(store Store, maxSize int, leaseTimeout time.Duration) *Manager {
+	qm := &Manager{
+		store:         store,
+		maxSize:       maxSize,
+		leaseTimeout:  leaseTimeout,
+		policies:      make(map[string]RetryPolicy),
+		defaultPolicy: DefaultRetryPolicy,
+		retention:     DefaultRetention,
+		changeCh:      make(chan struct{}),
+		workers:       make([]*Worker, 0),
+		running:       false,
+		wakeCh:        make(chan struct{}, 1),
+		registry:      NewRegistry(),
 	}
+	// 达到并发上限的类型有名额释放时，唤醒空闲的工作节点领取该类型的任务
+	qm.registry.released = qm.wake
+	return qm
+}
+
+// Registry 获取任务处理器注册表，各模块在启动前向其注册自己的任务类型
+func (qm *Manager) Registry() *Registry {
+	return qm.registry
+}
+
+// SetRetention 设置成功任务的保留时间
+func (qm *Manager) SetRetention(retention time.Duration) {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+	qm.retention = retention
+}
+
+// SetRetryPolicy 设置指定任务类型的重试策略
+func (qm *Manager) SetRetryPolicy(taskType string, policy RetryPolicy) {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+	qm.policies[taskType] = policy
 }
 
-// Start 启动队列管理器
+// SetDefaultRetryPolicy 设置未单独配置的任务类型使用的重试策略
+func (qm *Manager) SetDefaultRetryPolicy(policy RetryPolicy) {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+	qm.defaultPolicy = policy
+}
+
+// retryPolicy 获取任务类型的重试策略
+func (qm *Manager) retryPolicy(taskType string) RetryPolicy {
+	qm.mutex.RLock()
+	defer qm.mutex.RUnlock()
+	if policy, ok := qm.policies[taskType]; ok {
+		return policy
+	}
+	return qm.defaultPolicy
+}
+
+// Start 启动队列管理器，上次退出时已领取但未确认的任务重新变为等待中
 func (qm *Manager) Start() error {
 	qm.mutex.Lock()
 	defer qm.mutex.Unlock()
@@ -36,24 +106,36 @@ func (qm *Manager) Start() error {
 		return fmt.Errorf("queue manager is already running")
 	}
 
+	recovered, err := qm.store.RecoverLeases()
+	if err != nil {
+		return err
+	}
+	if recovered > 0 {
+		fmt.Printf("[QUEUE] Recovered %d unfinished tasks\n", recovered)
+	}
+
 	qm.running = true
+	qm.stopCh = make(chan struct{})
 
 	// 为所有已添加的Worker启动处理循环
 	for _, worker := range qm.workers {
 		qm.waitGroup.Add(1)
-		go qm.runWorker(worker)
+		go qm.runWorker(worker, qm.stopCh)
 	}
 
+	qm.waitGroup.Add(1)
+	go qm.purgeLoop(qm.stopCh, qm.retention)
+
 	fmt.Printf("[QUEUE] Queue manager started with %d workers\n", len(qm.workers))
 	return nil
 }
 
-// Stop 停止队列管理器
+// Stop 停止队列管理器，等待正在处理的任务完成，未领取的任务保留在存储中
 func (qm *Manager) Stop() error {
 	qm.mutex.Lock()
-	defer qm.mutex.Unlock()
 
 	if !qm.running {
+		qm.mutex.Unlock()
 		return fmt.Errorf("queue manager is not running")
 	}
 
@@ -64,8 +146,8 @@ func (qm *Manager) Stop() error {
 		worker.Stop()
 	}
 
-	// 关闭队列通道
-	close(qm.queue)
+	close(qm.stopCh)
+	qm.mutex.Unlock()
 
 	// 等待所有工作节点完成
 	qm.waitGroup.Wait()
@@ -74,7 +156,12 @@ func (qm *Manager) Stop() error {
 	return nil
 }
 
-// Enqueue 将任务加入队列
+// Close 关闭队列存储
+func (qm *Manager) Close() error {
+	return qm.store.Close()
+}
+
+// Enqueue 将任务加入队列，设置了NotBefore的任务延迟到该时间之后处理
 func (qm *Manager) Enqueue(task *types.TaskMessage) error {
 	qm.mutex.RLock()
 	defer qm.mutex.RUnlock()
@@ -83,12 +170,45 @@ func (qm *Manager) Enqueue(task *types.TaskMessage) error {
 		return fmt.Errorf("queue manager is not running")
 	}
 
-	select {
-	case qm.queue <- task:
-		fmt.Printf("[QUEUE] Task enqueued: %s (ID: %s)\n", task.Type, task.ObjectID)
+	if !qm.registry.Has(task.Type) {
+		return fmt.Errorf("%w: %s", ErrUnknownTaskType, task.Type)
+	}
+
+	counts, err := qm.store.Counts()
+	if err != nil {
+		return err
+	}
+	if counts[types.TaskStatusQueued]+counts[types.TaskStatusFailed] >= qm.maxSize {
+		return fmt.Errorf("queue is full")
+	}
+
+	if task.ID == "" {
+		task.ID = uuid.New().String()
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+
+	if err := qm.store.Push(task); err != nil {
+		return err
+	}
+
+	if task.NotBefore != nil && task.NotBefore.After(time.Now()) {
+		fmt.Printf("[QUEUE] Task enqueued: %s (ID: %s), not before %s\n",
+			task.Type, task.ObjectID, task.NotBefore.Format(time.RFC3339))
 		return nil
+	}
+
+	fmt.Printf("[QUEUE] Task enqueued: %s (ID: %s)\n", task.Type, task.ObjectID)
+	qm.wake()
+	return nil
+}
+
+// wake 唤醒一个空闲的工作节点
+func (qm *Manager) wake() {
+	select {
+	case qm.wakeCh <- struct{}{}:
 	default:
-		return fmt.Errorf("queue is full")
 	}
 }
 
@@ -97,43 +217,196 @@ func (qm *Manager) AddWorker(worker *Worker) {
 	qm.mutex.Lock()
 	defer qm.mutex.Unlock()
 
+	worker.setRegistry(qm.registry)
 	qm.workers = append(qm.workers, worker)
 
 	if qm.running {
 		qm.waitGroup.Add(1)
-		go qm.runWorker(worker)
+		go qm.runWorker(worker, qm.stopCh)
 	}
 }
 
-// runWorker 运行单个工作节点
-func (qm *Manager) runWorker(worker *Worker) {
+// runWorker 运行单个工作节点：领取任务 -> 处理（期间续租） -> 确认
+func (qm *Manager) runWorker(worker *Worker, stopCh chan struct{}) {
 	defer qm.waitGroup.Done()
 
 	fmt.Printf("[QUEUE] Worker %s started\n", worker.ID)
 
 	for {
 		select {
-		case task, ok := <-qm.queue:
-			if !ok {
-				// 队列已关闭
-				fmt.Printf("[QUEUE] Worker %s stopped (queue closed)\n", worker.ID)
-				return
-			}
+		case <-stopCh:
+			fmt.Printf("[QUEUE] Worker %s stopped (queue closed)\n", worker.ID)
+			return
+		default:
+		}
+
+		if !worker.IsRunning() {
+			// 工作节点已停止
+			fmt.Printf("[QUEUE] Worker %s stopped\n", worker.ID)
+			return
+		}
 
-			if !worker.IsRunning() {
-				// 工作节点已停止
-				fmt.Printf("[QUEUE] Worker %s stopped\n", worker.ID)
-				return
+		task, err := qm.claim(worker.ID)
+		if err != nil {
+			fmt.Printf("[QUEUE] Worker %s failed to claim task: %v\n", worker.ID, err)
+		}
+		if task == nil {
+			select {
+			case <-stopCh:
+			case <-qm.wakeCh:
+			case <-time.After(pollInterval):
 			}
+			continue
+		}
+
+		// 可能还有其他等待中的任务，唤醒下一个空闲的工作节点
+		qm.wake()
+		qm.processTask(worker, task)
+	}
+}
 
-			// 处理任务
-			err := worker.ProcessTask(task)
+// claim 领取一个任务并占用其类型的并发名额，跳过已达到并发上限的类型
+func (qm *Manager) claim(owner string) (*types.TaskMessage, error) {
+	qm.claimMu.Lock()
+	defer qm.claimMu.Unlock()
+
+	task, err := qm.store.Claim(owner, time.Now().Add(qm.leaseTimeout), qm.registry.saturated())
+	if task != nil {
+		qm.registry.acquire(task.Type)
+	}
+	return task, err
+}
+
+// processTask 处理已领取的任务，处理期间定期续租，成功后确认
+// 失败的任务按重试策略延迟后重新处理，次数用尽或无法处理（未知类型、任务数据无效）时移入死信队列
+func (qm *Manager) processTask(worker *Worker, task *types.TaskMessage) {
+	done := make(chan struct{})
+	go qm.keepLease(worker.ID, task.ID, done)
+
+	defer qm.changed()
+
+	// 租约中断（工作节点卡住或进程退出）也计为处理次数，反复中断的任务不会无限重试
+	policy := qm.retryPolicy(task.Type)
+	if task.Attempts >= policy.MaxAttempts {
+		close(done)
+		fmt.Printf("[QUEUE] Task %s was interrupted %d times, moved to dead letter queue: %s\n",
+			task.ID, task.Attempts, task.LastError)
+		if err := qm.store.Bury(task.ID, worker.ID, task.Attempts, task.LastError); err != nil {
+			fmt.Printf("[QUEUE] Warning: failed to bury task %s: %v\n", task.ID, err)
+		}
+		return
+	}
+
+	err := worker.ProcessTask(context.Background(), task)
+	close(done)
+
+	if err == nil {
+		fmt.Printf("[QUEUE] Worker %s completed task %s\n",
+			worker.ID, task.ObjectID)
+		if err := qm.store.Ack(task.ID, worker.ID); err != nil {
+			fmt.Printf("[QUEUE] Warning: failed to ack task %s: %v\n", task.ID, err)
+		}
+		return
+	}
+
+	attempts := task.Attempts + 1
+	if isPermanent(err) {
+		fmt.Printf("[QUEUE] Worker %s cannot process task %s, moved to dead letter queue: %v\n",
+			worker.ID, task.ID, err)
+		if err := qm.store.Bury(task.ID, worker.ID, attempts, err.Error()); err != nil {
+			fmt.Printf("[QUEUE] Warning: failed to bury task %s: %v\n", task.ID, err)
+		}
+		return
+	}
+	if attempts >= policy.MaxAttempts {
+		fmt.Printf("[QUEUE] Worker %s failed to process task %s after %d attempts, moved to dead letter queue: %v\n",
+			worker.ID, task.ObjectID, attempts, err)
+		if err := qm.store.Bury(task.ID, worker.ID, attempts, err.Error()); err != nil {
+			fmt.Printf("[QUEUE] Warning: failed to bury task %s: %v\n", task.ID, err)
+		}
+		return
+	}
+
+	backoff := policy.Backoff(attempts)
+	fmt.Printf("[QUEUE] Worker %s failed to process task %s (attempt %d/%d), retrying in %v: %v\n",
+		worker.ID, task.ObjectID, attempts, policy.MaxAttempts, backoff.Round(time.Millisecond), err)
+	if err := qm.store.Retry(task.ID, worker.ID, attempts, err.Error(), time.Now().Add(backoff)); err != nil {
+		fmt.Printf("[QUEUE] Warning: failed to schedule retry of task %s: %v\n", task.ID, err)
+	}
+}
+
+// changed 通知等待任务的调用方重新检查任务状态
+func (qm *Manager) changed() {
+	qm.changeMu.Lock()
+	defer qm.changeMu.Unlock()
+
+	close(qm.changeCh)
+	qm.changeCh = make(chan struct{})
+}
+
+// changes 获取下一次任务处理结束时关闭的通道
+func (qm *Manager) changes() chan struct{} {
+	qm.changeMu.Lock()
+	defer qm.changeMu.Unlock()
+	return qm.changeCh
+}
+
+// WaitTask 等待任务成功或进入死信队列，ctx取消时返回任务当前的状态和ctx的错误
+func (qm *Manager) WaitTask(ctx context.Context, id string) (*types.TaskMessage, error) {
+	for {
+		// 先获取通知通道再查询，避免错过查询之后、等待之前结束的处理
+		changeCh := qm.changes()
+
+		task, err := qm.store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if types.TaskFinished(task.Status) {
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return task, ctx.Err()
+		case <-changeCh:
+		}
+	}
+}
+
+// purgeLoop 定期删除完成时间超过保留时间的成功任务
+func (qm *Manager) purgeLoop(stopCh chan struct{}, retention time.Duration) {
+	defer qm.waitGroup.Done()
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			purged, err := qm.store.PurgeFinished(time.Now().Add(-retention))
 			if err != nil {
-				fmt.Printf("[QUEUE] Worker %s failed to process task %s: %v\n",
-					worker.ID, task.ObjectID, err)
-			} else {
-				fmt.Printf("[QUEUE] Worker %s completed task %s\n",
-					worker.ID, task.ObjectID)
+				fmt.Printf("[QUEUE] Warning: failed to purge finished tasks: %v\n", err)
+			} else if purged > 0 {
+				fmt.Printf("[QUEUE] Purged %d succeeded tasks older than %v\n", purged, retention)
+			}
+		}
+	}
+}
+
+// keepLease 在任务处理完成前每隔租约时长的三分之一续租一次
+func (qm *Manager) keepLease(owner, taskID string, done chan struct{}) {
+	ticker := time.NewTicker(qm.leaseTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := qm.store.Extend(taskID, owner, time.Now().Add(qm.leaseTimeout)); err != nil {
+				fmt.Printf("[QUEUE] Warning: %v\n", err)
 			}
 		}
 	}
@@ -144,12 +417,24 @@ func (qm *Manager) GetStats() map[string]any {
 	qm.mutex.RLock()
 	defer qm.mutex.RUnlock()
 
+	counts, err := qm.store.Counts()
+	if err != nil {
+		fmt.Printf("[QUEUE] Warning: failed to count tasks: %v\n", err)
+	}
+	queued := counts[types.TaskStatusQueued] + counts[types.TaskStatusFailed]
+
 	stats := map[string]any{
 		"running":       qm.running,
-		"queue_size":    len(qm.queue),
+		"queue_size":    queued,
+		"leased":        counts[types.TaskStatusRunning],
+		"failed":        counts[types.TaskStatusFailed],
+		"succeeded":     counts[types.TaskStatusSucceeded],
+		"dead":          counts[types.TaskStatusDead],
+		"retention":     qm.retention.Seconds(),
 		"max_size":      qm.maxSize,
+		"lease_timeout": qm.leaseTimeout.Seconds(),
 		"worker_count":  len(qm.workers),
-		"capacity_used": float64(len(qm.queue)) / float64(qm.maxSize) * 100,
+		"capacity_used": float64(queued) / float64(qm.maxSize) * 100,
 	}
 
 	// 工作节点状态
@@ -162,6 +447,39 @@ func (qm *Manager) GetStats() map[string]any {
 		}
 	}
 	stats["workers"] = workerStats
+	stats["handlers"] = qm.registry.GetStatus()
 
 	return stats
 }
+
+// GetTask 获取任务
+func (qm *Manager) GetTask(id string) (*types.TaskMessage, error) {
+	return qm.store.Get(id)
+}
+
+// ListTasks 按入队顺序（NewestFirst时倒序）列出满足条件的任务
+func (qm *Manager) ListTasks(filter types.TaskFilter) ([]*types.TaskMessage, error) {
+	return qm.store.List(filter)
+}
+
+// RetryDeadTask 将死信队列中的任务重新放回队列
+func (qm *Manager) RetryDeadTask(id string) error {
+	if err := qm.store.Requeue(id); err != nil {
+		return err
+	}
+
+	fmt.Printf("[QUEUE] Dead task requeued: %s\n", id)
+	qm.wake()
+	return nil
+}
+
+// PurgeDeadTasks 删除死信队列中的任务，id为空时删除全部
+func (qm *Manager) PurgeDeadTasks(id string) (int, error) {
+	purged, err := qm.store.PurgeDead(id)
+	if err != nil {
+		return 0, err
+	}
+
+	fmt.Printf("[QUEUE] Purged %d dead tasks\n", purged)
+	return purged, nil
+}
@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+var (
+	// ErrUnknownTaskType 任务类型没有注册处理器
+	ErrUnknownTaskType = errors.New("unknown task type")
+	// ErrInvalidPayload 任务数据无法解码或校验失败
+	ErrInvalidPayload = errors.New("invalid task payload")
+	// ErrTaskTimeout 任务处理超时
+	ErrTaskTimeout = errors.New("task timed out")
+)
+
+// Handler 任务处理器，ctx在处理超时时取消
+type Handler func(ctx context.Context, task *types.TaskMessage) error
+
+// HandlerOptions 任务类型的处理选项
+type HandlerOptions struct {
+	Concurrency int           // 同时处理该类型任务的最大数量，0表示只受工作节点数量限制
+	Timeout     time.Duration // 单次处理的超时时间，0表示不限制
+}
+
+// Validator 任务数据可以实现该接口，解码后校验必填字段
+type Validator interface {
+	Validate() error
+}
+
+// registeredHandler 已注册的处理器及该类型正在处理的任务数
+type registeredHandler struct {
+	handler Handler
+	options HandlerOptions
+	active  int
+}
+
+// Registry 任务处理器注册表，按任务类型分发任务并限制每种类型的并发数
+type Registry struct {
+	mutex    sync.Mutex
+	handlers map[string]*registeredHandler
+	released func() // 任务处理完成、释放并发名额后调用，用于唤醒等待的工作节点
+}
+
+// NewRegistry 创建处理器注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[string]*registeredHandler),
+	}
+}
+
+// Register 注册任务类型的处理器，同一类型只能注册一次
+func (r *Registry) Register(taskType string, handler Handler, options HandlerOptions) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.handlers[taskType]; exists {
+		return fmt.Errorf("handler for task type %s is already registered", taskType)
+	}
+	r.handlers[taskType] = &registeredHandler{handler: handler, options: options}
+	return nil
+}
+
+// RegisterTyped 注册带类型的处理器，任务数据解码为P（实现Validator时同时校验）后传给handle
+func RegisterTyped[P any](r *Registry, taskType string, handle func(ctx context.Context, task *types.TaskMessage, payload *P) error, options HandlerOptions) error {
+	return r.Register(taskType, func(ctx context.Context, task *types.TaskMessage) error {
+		payload, err := DecodePayload[P](task)
+		if err != nil {
+			return err
+		}
+		return handle(ctx, task, payload)
+	}, options)
+}
+
+// DecodePayload 将任务数据解码为P，数据中多余的字段（如定时任务名）被忽略
+func DecodePayload[P any](task *types.TaskMessage) (*P, error) {
+	payload := new(P)
+
+	data, err := json.Marshal(task.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w for %s: %v", ErrInvalidPayload, task.Type, err)
+	}
+	if err := json.Unmarshal(data, payload); err != nil {
+		return nil, fmt.Errorf("%w for %s: %v", ErrInvalidPayload, task.Type, err)
+	}
+
+	if validator, ok := any(payload).(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", ErrInvalidPayload, task.Type, err)
+		}
+	}
+	return payload, nil
+}
+
+// Options 获取已注册类型的处理选项
+func (r *Registry) Options(taskType string) (HandlerOptions, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	registered, ok := r.handlers[taskType]
+	if !ok {
+		return HandlerOptions{}, false
+	}
+	return registered.options, true
+}
+
+// SetOptions 修改已注册类型的处理选项
+func (r *Registry) SetOptions(taskType string, options HandlerOptions) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	registered, ok := r.handlers[taskType]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTaskType, taskType)
+	}
+	registered.options = options
+	return nil
+}
+
+// Has 检查任务类型是否已注册处理器
+func (r *Registry) Has(taskType string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, ok := r.handlers[taskType]
+	return ok
+}
+
+// saturated 获取正在处理的任务数已达到并发上限的类型，领取任务时跳过这些类型
+func (r *Registry) saturated() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var taskTypes []string
+	for taskType, registered := range r.handlers {
+		if registered.options.Concurrency > 0 && registered.active >= registered.options.Concurrency {
+			taskTypes = append(taskTypes, taskType)
+		}
+	}
+	return taskTypes
+}
+
+// acquire 占用一个并发名额，领取任务后调用
+func (r *Registry) acquire(taskType string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if registered, ok := r.handlers[taskType]; ok {
+		registered.active++
+	}
+}
+
+// release 释放并发名额
+func (r *Registry) release(taskType string) {
+	r.mutex.Lock()
+	if registered, ok := r.handlers[taskType]; ok && registered.active > 0 {
+		registered.active--
+	}
+	released := r.released
+	r.mutex.Unlock()
+
+	if released != nil {
+		released()
+	}
+}
+
+// Process 调用任务类型的处理器，处理器返回后释放领取时占用的并发名额
+// 超时时处理器收到ctx取消，Process仍等待处理器实际返回后才返回ErrTaskTimeout：
+// 在此之前任务的租约继续续期，不会被重试或被其他工作节点领取，避免同一任务被并发执行
+func (r *Registry) Process(ctx context.Context, task *types.TaskMessage) error {
+	r.mutex.Lock()
+	registered, ok := r.handlers[task.Type]
+	var handler Handler
+	var options HandlerOptions
+	if ok {
+		handler, options = registered.handler, registered.options
+	}
+	r.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTaskType, task.Type)
+	}
+	defer r.release(task.Type)
+
+	if options.Timeout <= 0 {
+		return handler(ctx, task)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		fmt.Printf("[QUEUE] Task %s (%s) exceeded its %v timeout, waiting for the handler to return\n",
+			task.ID, task.Type, options.Timeout)
+	})
+
+	err := handler(ctx, task)
+	stop()
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v: %v", ErrTaskTimeout, options.Timeout, err)
+	}
+	return err
+}
+
+// GetStatus 获取各任务类型的处理选项和正在处理的任务数
+func (r *Registry) GetStatus() []map[string]any {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	status := make([]map[string]any, 0, len(r.handlers))
+	for taskType, registered := range r.handlers {
+		status = append(status, map[string]any{
+			"type":        taskType,
+			"concurrency": registered.options.Concurrency,
+			"timeout":     registered.options.Timeout.Seconds(),
+			"active":      registered.active,
+		})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i]["type"].(string) < status[j]["type"].(string) })
+	return status
+}
+
+// isPermanent 重试也不会成功的错误，任务直接进入死信队列
+func isPermanent(err error) bool {
+	return errors.Is(err, ErrUnknownTaskType) || errors.Is(err, ErrInvalidPayload)
+}
@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+func TestProcessWaitsForTimedOutHandler(t *testing.T) {
+	registry := NewRegistry()
+	var finished atomic.Bool
+	handler := func(ctx context.Context, task *types.TaskMessage) error {
+		<-ctx.Done()
+		// 处理器在超时后仍运行一段时间才返回
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+		return ctx.Err()
+	}
+	if err := registry.Register("slow", handler, HandlerOptions{Concurrency: 1, Timeout: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	registry.acquire("slow")
+	err := registry.Process(context.Background(), &types.TaskMessage{ID: "task-1", Type: "slow"})
+	if !errors.Is(err, ErrTaskTimeout) {
+		t.Fatalf("Process() = %v, want ErrTaskTimeout", err)
+	}
+	if !finished.Load() {
+		t.Fatal("Process returned before the handler finished")
+	}
+	if saturated := registry.saturated(); len(saturated) != 0 {
+		t.Fatalf("concurrency slot still held after Process returned: %v", saturated)
+	}
+}
@@ -0,0 +1,43 @@
+package queue
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy 任务失败后的重试策略，重试间隔按指数增长并加入随机抖动
+type RetryPolicy struct {
+	MaxAttempts    int           // 最多处理次数（包括第一次），用尽后进入死信队列
+	InitialBackoff time.Duration // 第一次重试前的等待时间
+	MaxBackoff     time.Duration // 等待时间上限
+	Multiplier     float64       // 每次重试等待时间的增长倍数
+	Jitter         float64       // 随机抖动比例（0-1），等待时间在 [d*(1-Jitter), d] 之间
+}
+
+// DefaultRetryPolicy 未单独配置的任务类型使用的重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     5 * time.Minute,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// Backoff 计算第attempts次失败后到下一次重试的等待时间
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempts-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		delay -= delay * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay)
+}
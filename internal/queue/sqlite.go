@@ -0,0 +1,423 @@
+package queue
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mock-storage/internal/types"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchema 队列表结构，按顺序执行，已执行的数量记录在PRAGMA user_version中
+var sqliteSchema = []string{
+	`CREATE TABLE tasks (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		id TEXT NOT NULL UNIQUE,
+		type TEXT NOT NULL,
+		object_id TEXT NOT NULL DEFAULT '',
+		data TEXT NOT NULL DEFAULT '{}',
+		created_at INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		lease_owner TEXT NOT NULL DEFAULT '',
+		lease_until INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX idx_tasks_status ON tasks (status, seq)`,
+	`ALTER TABLE tasks ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE tasks ADD COLUMN last_error TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE tasks ADD COLUMN available_at INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE tasks ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE tasks ADD COLUMN started_at INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE tasks ADD COLUMN finished_at INTEGER NOT NULL DEFAULT 0`,
+	`CREATE INDEX idx_tasks_object ON tasks (object_id, seq)`,
+}
+
+// taskColumns 读取任务时查询的列，与scanTask的顺序一致
+const taskColumns = `id, type, object_id, data, created_at, available_at, status, attempts, last_error,
+	lease_owner, started_at, finished_at, updated_at`
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask 扫描一行任务
+func scanTask(row rowScanner) (*types.TaskMessage, error) {
+	var data string
+	var createdAt, availableAt, startedAt, finishedAt, updatedAt int64
+	task := &types.TaskMessage{}
+	err := row.Scan(&task.ID, &task.Type, &task.ObjectID, &data, &createdAt, &availableAt,
+		&task.Status, &task.Attempts, &task.LastError, &task.Worker, &startedAt, &finishedAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &task.Data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data of task %s: %v", task.ID, err)
+	}
+	task.CreatedAt = time.Unix(0, createdAt)
+	task.NotBefore = unixTime(availableAt)
+	task.StartedAt = unixTime(startedAt)
+	task.FinishedAt = unixTime(finishedAt)
+	if updatedAt > 0 {
+		task.UpdatedAt = time.Unix(0, updatedAt)
+	}
+	return task, nil
+}
+
+// unixTime 将纳秒时间戳转换为时间，0表示未设置
+func unixTime(nanos int64) *time.Time {
+	if nanos <= 0 {
+		return nil
+	}
+	t := time.Unix(0, nanos)
+	return &t
+}
+
+// SQLiteStore 持久化队列存储，任务保存在SQLite的tasks表中，重启后继续处理
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore 打开（不存在时创建）队列数据库
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %v", err)
+	}
+
+	db, err := sql.Open(DriverSQLite, path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue database: %v", err)
+	}
+	// 单连接串行执行，领取任务的查询和更新不会与其他工作节点交错
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteStore{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// migrate 执行尚未执行的表结构语句
+func (ss *SQLiteStore) migrate() error {
+	var version int
+	if err := ss.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read queue schema version: %v", err)
+	}
+
+	for i := version; i < len(sqliteSchema); i++ {
+		tx, err := ss.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin queue migration: %v", err)
+		}
+		if _, err := tx.Exec(sqliteSchema[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply queue schema %d: %v", i+1, err)
+		}
+		// PRAGMA不支持参数绑定
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update queue schema version: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit queue migration: %v", err)
+		}
+	}
+	return nil
+}
+
+// Push 保存新任务，设置了NotBefore的任务在该时间之后才能被领取
+func (ss *SQLiteStore) Push(task *types.TaskMessage) error {
+	data, err := json.Marshal(task.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task data: %v", err)
+	}
+
+	var availableAt int64
+	if task.NotBefore != nil {
+		availableAt = task.NotBefore.UnixNano()
+	}
+
+	_, err = ss.db.Exec(`INSERT INTO tasks (id, type, object_id, data, created_at, available_at, status, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		task.ID, task.Type, task.ObjectID, string(data), task.CreatedAt.UnixNano(), availableAt, types.TaskStatusQueued, time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to save task: %v", err)
+	}
+	return nil
+}
+
+// Claim 领取最早入队且已到重试时间的等待中或失败任务，或租约已过期的任务，跳过excludeTypes中的类型
+// 租约已过期的任务的失败次数加一
+func (ss *SQLiteStore) Claim(owner string, leaseUntil time.Time, excludeTypes []string) (*types.TaskMessage, error) {
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin claim: %v", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	args := []any{types.TaskStatusQueued, types.TaskStatusFailed, now.UnixNano(), types.TaskStatusRunning, now.UnixNano()}
+
+	typeClause := ""
+	if len(excludeTypes) > 0 {
+		typeClause = ` AND type NOT IN (?` + strings.Repeat(`, ?`, len(excludeTypes)-1) + `)`
+		for _, taskType := range excludeTypes {
+			args = append(args, taskType)
+		}
+	}
+
+	task, err := scanTask(tx.QueryRow(`SELECT `+taskColumns+` FROM tasks
+		WHERE ((status IN (?, ?) AND available_at <= ?) OR (status = ? AND lease_until < ?))`+typeClause+`
+		ORDER BY seq LIMIT 1`, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %v", err)
+	}
+
+	if task.Status == types.TaskStatusRunning {
+		task.Attempts++
+		task.LastError = fmt.Sprintf(errLeaseExpired, task.Worker)
+	}
+
+	_, err = tx.Exec(`UPDATE tasks SET status = ?, lease_owner = ?, lease_until = ?, attempts = ?, last_error = ?, started_at = ?, updated_at = ? WHERE id = ?`,
+		types.TaskStatusRunning, owner, leaseUntil.UnixNano(), task.Attempts, task.LastError, now.UnixNano(), now.UnixNano(), task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease task %s: %v", task.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit claim: %v", err)
+	}
+	task.Status, task.Worker, task.StartedAt, task.UpdatedAt = types.TaskStatusRunning, owner, &now, now
+	return task, nil
+}
+
+// Extend 延长任务租约
+func (ss *SQLiteStore) Extend(id, owner string, leaseUntil time.Time) error {
+	result, err := ss.db.Exec(`UPDATE tasks SET lease_until = ? WHERE id = ? AND status = ? AND lease_owner = ?`,
+		leaseUntil.UnixNano(), id, types.TaskStatusRunning, owner)
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("lease of task %s is no longer held by %s", id, owner)
+	}
+	return nil
+}
+
+// Ack 确认任务处理成功
+func (ss *SQLiteStore) Ack(id, owner string) error {
+	now := time.Now().UnixNano()
+	err := ss.update(`UPDATE tasks SET status = ?, available_at = 0, lease_until = 0, finished_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND lease_owner = ?`,
+		types.TaskStatusSucceeded, now, now, id, types.TaskStatusRunning, owner)
+	if err == ErrTaskNotFound {
+		return fmt.Errorf("%w: task %s is no longer held by %s", ErrLeaseLost, id, owner)
+	}
+	return err
+}
+
+// Retry 记录失败并在availableAt之后重新领取
+func (ss *SQLiteStore) Retry(id, owner string, attempts int, lastError string, availableAt time.Time) error {
+	return ss.fail(id, owner, types.TaskStatusFailed, attempts, lastError, availableAt.UnixNano(), 0)
+}
+
+// Bury 记录失败并移入死信队列
+func (ss *SQLiteStore) Bury(id, owner string, attempts int, lastError string) error {
+	return ss.fail(id, owner, types.TaskStatusDead, attempts, lastError, 0, time.Now().UnixNano())
+}
+
+// fail 记录owner处理任务失败并修改状态
+func (ss *SQLiteStore) fail(id, owner, status string, attempts int, lastError string, availableAt, finishedAt int64) error {
+	err := ss.update(`UPDATE tasks SET status = ?, attempts = ?, last_error = ?, available_at = ?,
+		lease_until = 0, finished_at = ?, updated_at = ? WHERE id = ? AND status = ? AND lease_owner = ?`,
+		status, attempts, lastError, availableAt, finishedAt, time.Now().UnixNano(), id, types.TaskStatusRunning, owner)
+	if err == ErrTaskNotFound {
+		return fmt.Errorf("%w: task %s is no longer held by %s", ErrLeaseLost, id, owner)
+	}
+	return err
+}
+
+// update 执行只修改一个任务的语句，没有修改任何行时返回ErrTaskNotFound
+func (ss *SQLiteStore) update(query string, args ...any) error {
+	result, err := ss.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update task: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// RecoverLeases 将已租用的任务恢复为等待中
+func (ss *SQLiteStore) RecoverLeases() (int, error) {
+	// 错误信息中的工作节点为更新前的lease_owner
+	result, err := ss.db.Exec(`UPDATE tasks SET status = ?, attempts = attempts + 1, last_error = printf(?, lease_owner),
+		lease_owner = '', lease_until = 0, updated_at = ? WHERE status = ?`,
+		types.TaskStatusQueued, errLeaseRecovered, time.Now().UnixNano(), types.TaskStatusRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover leased tasks: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %v", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// Get 获取任务
+func (ss *SQLiteStore) Get(id string) (*types.TaskMessage, error) {
+	task, err := scanTask(ss.db.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task: %v", err)
+	}
+	return task, nil
+}
+
+// List 按入队顺序列出满足条件的任务
+func (ss *SQLiteStore) List(filter types.TaskFilter) ([]*types.TaskMessage, error) {
+	var conditions []string
+	var args []any
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.ObjectID != "" {
+		conditions = append(conditions, "object_id = ?")
+		args = append(args, filter.ObjectID)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// SQLite中LIMIT -1表示不限制
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit, filter.Offset)
+
+	order := "seq"
+	if filter.NewestFirst {
+		order = "seq DESC"
+	}
+
+	rows, err := ss.db.Query(`SELECT `+taskColumns+` FROM tasks `+whereClause+` ORDER BY `+order+` LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []*types.TaskMessage
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// Requeue 将死信任务重新放回队列
+func (ss *SQLiteStore) Requeue(id string) error {
+	err := ss.update(`UPDATE tasks SET status = ?, attempts = 0, available_at = 0, finished_at = 0, updated_at = ? WHERE id = ? AND status = ?`,
+		types.TaskStatusQueued, time.Now().UnixNano(), id, types.TaskStatusDead)
+	if err == ErrTaskNotFound {
+		return fmt.Errorf("%w: no dead task %s", ErrTaskNotFound, id)
+	}
+	return err
+}
+
+// PurgeDead 删除死信任务
+func (ss *SQLiteStore) PurgeDead(id string) (int, error) {
+	query, args := `DELETE FROM tasks WHERE status = ?`, []any{types.TaskStatusDead}
+	if id != "" {
+		query += ` AND id = ?`
+		args = append(args, id)
+	}
+
+	result, err := ss.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead tasks: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %v", err)
+	}
+	if id != "" && rowsAffected == 0 {
+		return 0, fmt.Errorf("%w: no dead task %s", ErrTaskNotFound, id)
+	}
+	return int(rowsAffected), nil
+}
+
+// PurgeFinished 删除在before之前处理成功的任务
+func (ss *SQLiteStore) PurgeFinished(before time.Time) (int, error) {
+	result, err := ss.db.Exec(`DELETE FROM tasks WHERE status = ? AND finished_at < ?`,
+		types.TaskStatusSucceeded, before.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge finished tasks: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %v", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// Counts 按状态统计任务数量
+func (ss *SQLiteStore) Counts() (map[string]int, error) {
+	rows, err := ss.db.Query(`SELECT status, COUNT(*) FROM tasks GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %v", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan task count: %v", err)
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// Close 关闭队列数据库
+func (ss *SQLiteStore) Close() error {
+	return ss.db.Close()
+}
@@ -0,0 +1,384 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// 队列存储驱动
+const (
+	DriverSQLite = "sqlite3"
+	DriverMemory = "memory"
+)
+
+// ErrTaskNotFound 任务不存在或不处于要求的状态
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrLeaseLost 工作节点不再持有任务的租约（租约过期后被其他节点领取，或任务已不在处理中）
+var ErrLeaseLost = errors.New("task lease lost")
+
+// 租约中断时记录在任务上的错误，中断计为一次处理
+const (
+	errLeaseExpired   = "lease of worker %s expired before the task finished"
+	errLeaseRecovered = "worker %s exited before the task finished"
+)
+
+// Store 队列存储后端，工作节点通过租约领取任务，处理结束后记录结果
+type Store interface {
+	// Push 保存新任务
+	Push(task *types.TaskMessage) error
+	// Claim 领取最早入队且已到重试时间的等待中或失败任务（或租约已过期的任务），跳过excludeTypes中的类型，
+	// 租约到leaseUntil为止，没有任务时返回nil；重新领取租约已过期的任务时，中断的处理计为一次失败
+	Claim(owner string, leaseUntil time.Time, excludeTypes []string) (*types.TaskMessage, error)
+	// Extend 延长owner持有的任务租约，租约已被其他节点领取时返回错误
+	Extend(id, owner string, leaseUntil time.Time) error
+	// Ack 确认owner处理任务成功，任务保留为succeeded直到被PurgeFinished删除
+	// owner不再持有租约时返回ErrLeaseLost，任务状态不变
+	Ack(id, owner string) error
+	// Retry 记录owner处理失败的次数和错误，任务变为failed并在availableAt之后重新被领取
+	// owner不再持有租约时返回ErrLeaseLost，不覆盖新持有者的处理结果
+	Retry(id, owner string, attempts int, lastError string, availableAt time.Time) error
+	// Bury 记录owner处理失败的次数和错误，将任务移入死信队列，owner不再持有租约时返回ErrLeaseLost
+	Bury(id, owner string, attempts int, lastError string) error
+	// RecoverLeases 将所有已租用的任务恢复为等待中，用于启动时恢复崩溃前未完成的任务，中断的处理计为一次失败
+	RecoverLeases() (int, error)
+	// Get 获取任务，不存在时返回ErrTaskNotFound
+	Get(id string) (*types.TaskMessage, error)
+	// List 按入队顺序列出满足条件的任务
+	List(filter types.TaskFilter) ([]*types.TaskMessage, error)
+	// Requeue 将死信队列中的任务重新放回队列并清零失败次数，任务不在死信队列中时返回ErrTaskNotFound
+	Requeue(id string) error
+	// PurgeDead 删除死信队列中的任务，id为空时删除全部，返回删除的数量
+	PurgeDead(id string) (int, error)
+	// PurgeFinished 删除在before之前处理成功的任务，返回删除的数量
+	PurgeFinished(before time.Time) (int, error)
+	// Counts 按状态统计任务数量
+	Counts() (map[string]int, error)
+	Close() error
+}
+
+// memoryTask 内存队列中的任务及其租约，状态和重试时间保存在message.Status、message.NotBefore中
+type memoryTask struct {
+	message    *types.TaskMessage
+	leaseUntil time.Time
+}
+
+// MemoryStore 内存队列存储，进程退出后任务丢失，用于内存模式
+type MemoryStore struct {
+	mu    sync.Mutex
+	tasks []*memoryTask // 按入队顺序排列
+}
+
+// NewMemoryStore 创建内存队列存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Push 保存新任务，设置了NotBefore的任务在该时间之后才能被领取
+func (ms *MemoryStore) Push(task *types.TaskMessage) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	stored := copyTask(task)
+	stored.Status = types.TaskStatusQueued
+	stored.UpdatedAt = time.Now()
+	ms.tasks = append(ms.tasks, &memoryTask{message: stored})
+	return nil
+}
+
+// Claim 领取最早入队的可用任务
+func (ms *MemoryStore) Claim(owner string, leaseUntil time.Time, excludeTypes []string) (*types.TaskMessage, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	for _, task := range ms.tasks {
+		if slices.Contains(excludeTypes, task.message.Type) {
+			continue
+		}
+		switch task.message.Status {
+		case types.TaskStatusQueued, types.TaskStatusFailed:
+			if task.message.NotBefore != nil && task.message.NotBefore.After(now) {
+				continue
+			}
+		case types.TaskStatusRunning:
+			if task.leaseUntil.After(now) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if task.message.Status == types.TaskStatusRunning {
+			task.message.Attempts++
+			task.message.LastError = fmt.Sprintf(errLeaseExpired, task.message.Worker)
+		}
+
+		startedAt := now
+		task.message.Status = types.TaskStatusRunning
+		task.message.Worker = owner
+		task.message.StartedAt = &startedAt
+		task.message.UpdatedAt = now
+		task.leaseUntil = leaseUntil
+		return copyTask(task.message), nil
+	}
+	return nil, nil
+}
+
+// Extend 延长任务租约
+func (ms *MemoryStore) Extend(id, owner string, leaseUntil time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	task := ms.find(id)
+	if task == nil || task.message.Status != types.TaskStatusRunning || task.message.Worker != owner {
+		return fmt.Errorf("lease of task %s is no longer held by %s", id, owner)
+	}
+	task.leaseUntil = leaseUntil
+	return nil
+}
+
+// Ack 确认任务处理成功
+func (ms *MemoryStore) Ack(id, owner string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	task, err := ms.leased(id, owner)
+	if err != nil {
+		return err
+	}
+	ms.finish(task, types.TaskStatusSucceeded)
+	return nil
+}
+
+// leased 查找owner持有租约的任务，调用方需要持有锁
+func (ms *MemoryStore) leased(id, owner string) (*memoryTask, error) {
+	task := ms.find(id)
+	if task == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	if task.message.Status != types.TaskStatusRunning || task.message.Worker != owner {
+		return nil, fmt.Errorf("%w: task %s is no longer held by %s", ErrLeaseLost, id, owner)
+	}
+	return task, nil
+}
+
+// Retry 记录失败并在availableAt之后重新领取
+func (ms *MemoryStore) Retry(id, owner string, attempts int, lastError string, availableAt time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	task, err := ms.leased(id, owner)
+	if err != nil {
+		return err
+	}
+
+	task.message.Status = types.TaskStatusFailed
+	task.message.Attempts = attempts
+	task.message.LastError = lastError
+	task.message.NotBefore = &availableAt
+	task.message.UpdatedAt = time.Now()
+	task.leaseUntil = time.Time{}
+	return nil
+}
+
+// Bury 记录失败并移入死信队列
+func (ms *MemoryStore) Bury(id, owner string, attempts int, lastError string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	task, err := ms.leased(id, owner)
+	if err != nil {
+		return err
+	}
+
+	task.message.Attempts = attempts
+	task.message.LastError = lastError
+	ms.finish(task, types.TaskStatusDead)
+	return nil
+}
+
+// finish 将任务标记为已结束，调用方需要持有锁
+func (ms *MemoryStore) finish(task *memoryTask, status string) {
+	now := time.Now()
+	task.message.Status = status
+	task.message.NotBefore = nil
+	task.message.FinishedAt = &now
+	task.message.UpdatedAt = now
+	task.leaseUntil = time.Time{}
+}
+
+// RecoverLeases 将已租用的任务恢复为等待中
+func (ms *MemoryStore) RecoverLeases() (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	recovered := 0
+	for _, task := range ms.tasks {
+		if task.message.Status == types.TaskStatusRunning {
+			task.message.Status = types.TaskStatusQueued
+			task.message.Attempts++
+			task.message.LastError = fmt.Sprintf(errLeaseRecovered, task.message.Worker)
+			task.message.Worker = ""
+			task.message.UpdatedAt = time.Now()
+			task.leaseUntil = time.Time{}
+			recovered++
+		}
+	}
+	return recovered, nil
+}
+
+// Get 获取任务
+func (ms *MemoryStore) Get(id string) (*types.TaskMessage, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	task := ms.find(id)
+	if task == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	return copyTask(task.message), nil
+}
+
+// List 按入队顺序列出满足条件的任务
+func (ms *MemoryStore) List(filter types.TaskFilter) ([]*types.TaskMessage, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var tasks []*types.TaskMessage
+	skipped := 0
+	for i := range ms.tasks {
+		task := ms.tasks[i]
+		if filter.NewestFirst {
+			task = ms.tasks[len(ms.tasks)-1-i]
+		}
+
+		if filter.Limit > 0 && len(tasks) >= filter.Limit {
+			break
+		}
+		if !matchTaskFilter(task.message, filter) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		tasks = append(tasks, copyTask(task.message))
+	}
+	return tasks, nil
+}
+
+// Requeue 将死信任务重新放回队列
+func (ms *MemoryStore) Requeue(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	task := ms.find(id)
+	if task == nil || task.message.Status != types.TaskStatusDead {
+		return fmt.Errorf("%w: no dead task %s", ErrTaskNotFound, id)
+	}
+
+	task.message.Status = types.TaskStatusQueued
+	task.message.Attempts = 0
+	task.message.NotBefore = nil
+	task.message.FinishedAt = nil
+	task.message.UpdatedAt = time.Now()
+	return nil
+}
+
+// PurgeDead 删除死信任务
+func (ms *MemoryStore) PurgeDead(id string) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	purged := ms.remove(func(task *types.TaskMessage) bool {
+		return task.Status == types.TaskStatusDead && (id == "" || task.ID == id)
+	})
+	if id != "" && purged == 0 {
+		return 0, fmt.Errorf("%w: no dead task %s", ErrTaskNotFound, id)
+	}
+	return purged, nil
+}
+
+// PurgeFinished 删除在before之前处理成功的任务
+func (ms *MemoryStore) PurgeFinished(before time.Time) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return ms.remove(func(task *types.TaskMessage) bool {
+		return task.Status == types.TaskStatusSucceeded && task.FinishedAt.Before(before)
+	}), nil
+}
+
+// remove 删除满足条件的任务，返回删除的数量，调用方需要持有锁
+func (ms *MemoryStore) remove(match func(task *types.TaskMessage) bool) int {
+	remaining := ms.tasks[:0]
+	removed := 0
+	for _, task := range ms.tasks {
+		if match(task.message) {
+			removed++
+			continue
+		}
+		remaining = append(remaining, task)
+	}
+	ms.tasks = remaining
+	return removed
+}
+
+// Counts 按状态统计任务数量
+func (ms *MemoryStore) Counts() (map[string]int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, task := range ms.tasks {
+		counts[task.message.Status]++
+	}
+	return counts, nil
+}
+
+// Close 内存存储无需关闭
+func (ms *MemoryStore) Close() error {
+	return nil
+}
+
+// find 按ID查找任务，调用方需要持有锁
+func (ms *MemoryStore) find(id string) *memoryTask {
+	for _, task := range ms.tasks {
+		if task.message.ID == id {
+			return task
+		}
+	}
+	return nil
+}
+
+// matchTaskFilter 判断任务是否满足查询条件
+func matchTaskFilter(task *types.TaskMessage, filter types.TaskFilter) bool {
+	if filter.Status != "" && task.Status != filter.Status {
+		return false
+	}
+	if filter.Type != "" && task.Type != filter.Type {
+		return false
+	}
+	if filter.ObjectID != "" && task.ObjectID != filter.ObjectID {
+		return false
+	}
+	return true
+}
+
+// copyTask 复制任务，避免调用方修改存储中的任务
+func copyTask(task *types.TaskMessage) *types.TaskMessage {
+	copied := *task
+	if task.Data != nil {
+		copied.Data = make(map[string]any, len(task.Data))
+		for key, value := range task.Data {
+			copied.Data[key] = value
+		}
+	}
+	return &copied
+}
@@ -0,0 +1,116 @@
+package queue
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// forEachStore 对内存存储和SQLite存储分别执行测试
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "queue.db"))
+		if err != nil {
+			t.Fatalf("open queue store: %v", err)
+		}
+		defer store.Close()
+		test(t, store)
+	})
+}
+
+// pushTask 入队一个测试任务
+func pushTask(t *testing.T, store Store) string {
+	t.Helper()
+	task := &types.TaskMessage{ID: "task-1", Type: "test", ObjectID: "bucket/object", CreatedAt: time.Now()}
+	if err := store.Push(task); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	return task.ID
+}
+
+// claimTask 领取任务，没有可领取的任务时测试失败
+func claimTask(t *testing.T, store Store, owner string, leaseUntil time.Time) *types.TaskMessage {
+	t.Helper()
+	task, err := store.Claim(owner, leaseUntil, nil)
+	if err != nil || task == nil {
+		t.Fatalf("claim by %s = %v, %v", owner, task, err)
+	}
+	return task
+}
+
+func TestReclaimedLeaseCountsAsAttempt(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		id := pushTask(t, store)
+		claimTask(t, store, "old", time.Now().Add(-time.Second))
+
+		task := claimTask(t, store, "new", time.Now().Add(time.Minute))
+		if task.Attempts != 1 || task.LastError == "" {
+			t.Fatalf("reclaimed task attempts = %d, last error %q, want 1 and the expired lease", task.Attempts, task.LastError)
+		}
+
+		if err := store.Ack(id, "old"); !errors.Is(err, ErrLeaseLost) {
+			t.Fatalf("ack by expired owner = %v, want ErrLeaseLost", err)
+		}
+		if err := store.Ack(id, "new"); err != nil {
+			t.Fatalf("ack by current owner: %v", err)
+		}
+		if err := store.Ack(id, "new"); !errors.Is(err, ErrLeaseLost) {
+			t.Fatalf("second ack = %v, want ErrLeaseLost", err)
+		}
+	})
+}
+
+func TestRecoverLeasesCountsAsAttempt(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		id := pushTask(t, store)
+		claimTask(t, store, "crashed", time.Now().Add(time.Minute))
+
+		if recovered, err := store.RecoverLeases(); err != nil || recovered != 1 {
+			t.Fatalf("recover = %d, %v, want 1", recovered, err)
+		}
+		task, err := store.Get(id)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if task.Status != types.TaskStatusQueued || task.Attempts != 1 || task.LastError == "" {
+			t.Fatalf("recovered task = %+v, want queued with one attempt", task)
+		}
+	})
+}
+
+func TestRetryByExpiredOwnerIsRejected(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		id := pushTask(t, store)
+		claimTask(t, store, "old", time.Now().Add(-time.Second))
+		claimTask(t, store, "new", time.Now().Add(time.Minute))
+
+		if err := store.Retry(id, "old", 1, "stale failure", time.Now()); !errors.Is(err, ErrLeaseLost) {
+			t.Fatalf("retry by expired owner = %v, want ErrLeaseLost", err)
+		}
+		if err := store.Bury(id, "old", 5, "stale failure"); !errors.Is(err, ErrLeaseLost) {
+			t.Fatalf("bury by expired owner = %v, want ErrLeaseLost", err)
+		}
+
+		// 新持有者的租约不受影响，任务不会被其他节点领取
+		task, err := store.Get(id)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if task.Status != types.TaskStatusRunning || task.Worker != "new" || task.LastError == "stale failure" {
+			t.Fatalf("task after stale retry = %+v, want still leased by new", task)
+		}
+		if other, err := store.Claim("other", time.Now().Add(time.Minute), nil); err != nil || other != nil {
+			t.Fatalf("claim while leased = %v, %v, want nothing", other, err)
+		}
+
+		if err := store.Retry(id, "new", 2, "failure", time.Now()); err != nil {
+			t.Fatalf("retry by current owner: %v", err)
+		}
+	})
+}
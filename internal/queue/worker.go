@@ -1,42 +1,50 @@
 package queue
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"sync"
-	"time"
 
 	"mock-storage/internal/types"
+
+	"github.com/google/uuid"
 )
 
-// StorageManager 存储管理器接口（避免循环依赖）
-type StorageManager interface {
-	GetNodes() []types.StorageNode
+// processID 本进程的标识：主机名、进程号和随机后缀，重启后或其他主机上的进程不会相同
+var processID = newProcessID()
+
+func newProcessID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8])
+}
+
+// WorkerID 生成全局唯一的工作节点ID，name为进程内的名称
+// 租约按工作节点ID区分持有者，共用队列存储的进程之间以及进程重启前后的ID都不能相同
+func WorkerID(name string) string {
+	return processID + "/" + name
 }
 
-// Worker 工作节点
+// Worker 工作节点，通过所属队列管理器的处理器注册表处理任务
 type Worker struct {
 	ID             string
 	running        bool
 	mutex          sync.RWMutex
 	tasksProcessed int64
-	processor      types.TaskProcessor
-	storageManager StorageManager
+	registry       *Registry
 }
 
-// NewWorker 创建工作节点
-func NewWorker(id string, processor types.TaskProcessor) *Worker {
+// NewWorker 创建工作节点，加入队列管理器后才能处理任务
+func NewWorker(id string) *Worker {
 	return &Worker{
-		ID:        id,
-		running:   false,
-		processor: processor,
+		ID:      id,
+		running: false,
 	}
 }
 
-// SetStorageManager 设置存储管理器
-func (w *Worker) SetStorageManager(sm StorageManager) {
-	w.storageManager = sm
-}
-
 // Start 启动工作节点
 func (w *Worker) Start() {
 	w.mutex.Lock()
@@ -63,18 +71,17 @@ func (w *Worker) IsRunning() bool {
 	return w.running
 }
 
-// ProcessTask 处理任务
-func (w *Worker) ProcessTask(task *types.TaskMessage) error {
+// ProcessTask 处理任务，任务类型没有注册处理器时返回ErrUnknownTaskType
+func (w *Worker) ProcessTask(ctx context.Context, task *types.TaskMessage) error {
 	w.mutex.Lock()
 	w.tasksProcessed++
+	registry := w.registry
 	w.mutex.Unlock()
 
-	if w.processor != nil {
-		return w.processor.Process(task)
+	if registry == nil {
+		return fmt.Errorf("worker %s is not attached to a queue manager", w.ID)
 	}
-
-	// 默认处理逻辑
-	return w.defaultProcess(task)
+	return registry.Process(ctx, task)
 }
 
 // GetTasksProcessed 获取已处理的任务数量
@@ -85,110 +92,10 @@ func (w *Worker) GetTasksProcessed() int64 {
 	return w.tasksProcessed
 }
 
-// defaultProcess 默认任务处理逻辑
-func (w *Worker) defaultProcess(task *types.TaskMessage) error {
-	switch task.Type {
-	case "upload_completed":
-		return w.processUploadCompleted(task)
-	case "cleanup":
-		return w.processCleanup(task)
-	case "replication_check":
-		return w.processReplicationCheck(task)
-	case "delete_from_storage":
-		return w.processDeleteFromStorage(task)
-	default:
-		fmt.Printf("[WORKER] Unknown task type: %s\n", task.Type)
-		return nil
-	}
-}
-
-// processUploadCompleted 处理上传完成任务
-func (w *Worker) processUploadCompleted(task *types.TaskMessage) error {
-	fmt.Printf("[WORKER] Processing upload completed for object: %s\n", task.ObjectID)
-
-	// 这里可以添加上传后的处理逻辑，比如：
-	// - 发送通知
-	// - 触发第三方服务
-	// - 生成缩略图
-	// - 病毒扫描等
-
-	// 模拟处理时间
-	time.Sleep(100 * time.Millisecond)
-
-	return nil
-}
-
-// processCleanup 处理清理任务
-func (w *Worker) processCleanup(task *types.TaskMessage) error {
-	fmt.Printf("[WORKER] Processing cleanup for object: %s\n", task.ObjectID)
-
-	// 这里可以添加清理逻辑，比如：
-	// - 清理临时文件
-	// - 清理过期数据
-	// - 垃圾回收等
-
-	time.Sleep(50 * time.Millisecond)
-
-	return nil
-}
-
-// processReplicationCheck 处理副本检查任务
-func (w *Worker) processReplicationCheck(task *types.TaskMessage) error {
-	fmt.Printf("[WORKER] Processing replication check for object: %s\n", task.ObjectID)
-
-	// 这里可以添加副本检查逻辑，比如：
-	// - 检查数据完整性
-	// - 验证副本一致性
-	// - 修复损坏的副本等
-
-	time.Sleep(200 * time.Millisecond)
-
-	return nil
-}
-
-// processDeleteFromStorage 处理从存储节点删除任务
-func (w *Worker) processDeleteFromStorage(task *types.TaskMessage) error {
-	fmt.Printf("[WORKER] Processing delete from storage for object: %s\n", task.ObjectID)
-
-	// 从任务数据中获取key
-	key, ok := task.Data["key"].(string)
-	if !ok {
-		return fmt.Errorf("invalid key in delete task data")
-	}
-
-	// 检查是否有存储管理器
-	if w.storageManager == nil {
-		fmt.Printf("[WORKER] Warning: No storage manager available, cannot delete file %s\n", key)
-		return fmt.Errorf("storage manager not available")
-	}
-
-	// 从所有存储节点删除文件
-	fmt.Printf("[WORKER] Deleting file %s from all storage nodes\n", key)
-
-	var lastError error
-	deletedCount := 0
-
-	for _, node := range w.storageManager.GetNodes() {
-		err := node.Delete(key)
-		if err != nil {
-			fmt.Printf("[WORKER] Warning: failed to delete from node %s: %v\n", node.GetNodeID(), err)
-			lastError = err
-		} else {
-			fmt.Printf("[WORKER] Successfully deleted from node %s: %s\n", node.GetNodeID(), key)
-			deletedCount++
-		}
-	}
-
-	// 如果至少有一个节点删除成功，认为任务成功
-	if deletedCount > 0 {
-		fmt.Printf("[WORKER] Delete task completed: %d nodes processed, %d successful\n", len(w.storageManager.GetNodes()), deletedCount)
-		return nil
-	}
-
-	// 如果所有节点都删除失败，返回最后一个错误
-	if lastError != nil {
-		return fmt.Errorf("failed to delete from all storage nodes: %v", lastError)
-	}
+// setRegistry 设置处理器注册表，由队列管理器在添加工作节点时调用
+func (w *Worker) setRegistry(registry *Registry) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 
-	return nil
+	w.registry = registry
 }
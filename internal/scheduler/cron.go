@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 任务的触发时间表
+type Schedule interface {
+	// Next 返回t之后的下一次触发时间，没有时返回零值
+	Next(t time.Time) time.Time
+}
+
+// 常用时间表的简写
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField 一个cron字段的取值范围
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0和7都表示周日
+}
+
+// searchYears 查找下一次触发时间的最大范围，超出时认为时间表不会再触发（如2月30日）
+const searchYears = 5
+
+// ParseSchedule 解析时间表，支持5个字段的cron表达式（分 时 日 月 周，使用本地时区）、
+// @hourly/@daily等简写以及 @every <间隔>（如 @every 30m）
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval in %q: %v", spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("interval in %q must be at least 1s", spec)
+		}
+		return everySchedule(interval), nil
+	}
+
+	if expr, ok := scheduleDescriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", spec, len(cronFields))
+	}
+
+	bits := make([]uint64, len(cronFields))
+	for i, field := range fields {
+		var err error
+		if bits[i], err = parseCronField(field, cronFields[i]); err != nil {
+			return nil, fmt.Errorf("invalid %s in %q: %v", cronFields[i].name, spec, err)
+		}
+	}
+
+	// 周日可以写成0或7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField 解析一个字段，支持 *、a、a-b、*/n、a-b/n、a/n 以及用逗号分隔的列表
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := spec.min, spec.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", lowPart)
+			}
+			if high, err = strconv.Atoi(highPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", highPart)
+			}
+		default:
+			var err error
+			if low, err = strconv.Atoi(rangePart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+			// 单个值不带步长时只匹配该值，带步长时（a/n）到最大值为止
+			if !hasStep {
+				high = low
+			}
+		}
+
+		if low < spec.min || high > spec.max || low > high {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, spec.min, spec.max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// cronSchedule cron表达式时间表，每个字段用位图表示允许的值
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// 日和周都被限制时满足任意一个即可（与标准cron一致），其中一个为*时只看另一个
+	domStar, dowStar bool
+}
+
+// Next 返回t之后（精确到分钟）的下一次触发时间
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(searchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay 检查日期是否满足日和周字段
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// everySchedule 固定间隔的时间表
+type everySchedule time.Duration
+
+// Next 返回t之后一个间隔（精确到秒）的时间
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(time.Duration(s))
+}
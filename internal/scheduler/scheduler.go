@@ -0,0 +1,322 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// JobDataKey 定时任务加入队列时在任务数据中记录的任务名，用于判断上一次执行是否完成
+const JobDataKey = "scheduled_job"
+
+// 一次触发的结果
+const (
+	ResultEnqueued = "enqueued" // 已加入队列
+	ResultSkipped  = "skipped"  // 上一次加入队列的任务尚未完成，本次跳过
+	ResultFailed   = "failed"   // 加入队列失败
+)
+
+// ErrJobNotFound 定时任务不存在
+var ErrJobNotFound = errors.New("scheduled job not found")
+
+// Queue 任务队列接口（避免循环依赖）
+type Queue interface {
+	Enqueue(task *types.TaskMessage) error
+	GetTask(id string) (*types.TaskMessage, error)
+	ListTasks(filter types.TaskFilter) ([]*types.TaskMessage, error)
+}
+
+// Job 定时任务：按时间表将TaskType类型的任务加入队列
+type Job struct {
+	Name     string
+	Schedule string         // cron表达式，见ParseSchedule
+	TaskType string         // 加入队列的任务类型
+	Data     map[string]any // 任务数据
+}
+
+// RunRecord 定时任务的一次触发记录
+type RunRecord struct {
+	At         time.Time `json:"at"`
+	Manual     bool      `json:"manual,omitempty"` // 通过管理API手动触发
+	Result     string    `json:"result"`
+	TaskID     string    `json:"task_id,omitempty"`     // 本次加入队列（或因其未完成而跳过）的任务
+	TaskStatus string    `json:"task_status,omitempty"` // 查询状态时任务所处的状态，任务已从队列中删除时为空
+	Error      string    `json:"error,omitempty"`
+}
+
+// JobStatus 定时任务状态
+type JobStatus struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	TaskType string     `json:"task_type"`
+	NextRun  time.Time  `json:"next_run"`
+	Enqueued int        `json:"enqueued"` // 启动以来加入队列的次数
+	Skipped  int        `json:"skipped"`  // 启动以来因上一次未完成而跳过的次数
+	LastRun  *RunRecord `json:"last_run,omitempty"`
+}
+
+// scheduledJob 定时任务及其运行状态
+type scheduledJob struct {
+	Job
+	schedule Schedule
+	next     time.Time
+	enqueued int
+	skipped  int
+	lastRun  *RunRecord
+}
+
+// Scheduler 定时任务调度器，到达触发时间时将任务加入队列，由队列的工作节点执行
+type Scheduler struct {
+	queue Queue
+	jobs  []*scheduledJob
+
+	mutex   sync.Mutex
+	runMu   sync.Mutex // 保证检查上一次执行和加入队列之间不会被另一次触发打断
+	running bool
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// NewScheduler 创建调度器，任务名不能重复
+func NewScheduler(q Queue, jobs []Job) (*Scheduler, error) {
+	scheduler := &Scheduler{queue: q}
+
+	names := make(map[string]bool, len(jobs))
+	for i, job := range jobs {
+		if job.Name == "" {
+			return nil, fmt.Errorf("scheduled job %d has no name", i)
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("duplicate scheduled job name: %s", job.Name)
+		}
+		names[job.Name] = true
+
+		if job.TaskType == "" {
+			return nil, fmt.Errorf("scheduled job %s has no task type", job.Name)
+		}
+
+		schedule, err := ParseSchedule(job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("scheduled job %s: %v", job.Name, err)
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			return nil, fmt.Errorf("scheduled job %s: schedule %q never fires", job.Name, job.Schedule)
+		}
+		scheduler.jobs = append(scheduler.jobs, &scheduledJob{Job: job, schedule: schedule})
+	}
+
+	return scheduler, nil
+}
+
+// Start 启动调度循环，停止期间错过的触发不会补执行
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.running {
+		return
+	}
+
+	now := time.Now()
+	for _, job := range s.jobs {
+		job.next = job.schedule.Next(now)
+	}
+
+	s.running = true
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+
+	go s.loop(s.stopCh, s.doneCh)
+	fmt.Printf("[SCHEDULER] Scheduler started with %d jobs\n", len(s.jobs))
+}
+
+// Stop 停止调度循环
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	if !s.running {
+		s.mutex.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopCh)
+	doneCh := s.doneCh
+	s.mutex.Unlock()
+
+	<-doneCh
+	fmt.Println("[SCHEDULER] Scheduler stopped")
+}
+
+// loop 等待最近的触发时间，触发所有到期的任务
+func (s *Scheduler) loop(stopCh, doneCh chan struct{}) {
+	defer close(doneCh)
+
+	for {
+		timer := time.NewTimer(time.Until(s.nextWakeup()))
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		for _, job := range s.dueJobs(now) {
+			s.trigger(job, now, false)
+		}
+	}
+}
+
+// nextWakeup 获取最近的触发时间，没有任务时一小时后再检查
+func (s *Scheduler) nextWakeup() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	wakeup := time.Now().Add(time.Hour)
+	for _, job := range s.jobs {
+		if !job.next.IsZero() && job.next.Before(wakeup) {
+			wakeup = job.next
+		}
+	}
+	return wakeup
+}
+
+// dueJobs 获取已到触发时间的任务并计算它们的下一次触发时间
+func (s *Scheduler) dueJobs(now time.Time) []*scheduledJob {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var due []*scheduledJob
+	for _, job := range s.jobs {
+		if !job.next.IsZero() && !job.next.After(now) {
+			due = append(due, job)
+			job.next = job.schedule.Next(now)
+		}
+	}
+	return due
+}
+
+// RunJob 立即触发一次指定的定时任务，上一次加入队列的任务未完成时同样跳过
+func (s *Scheduler) RunJob(name string) (*RunRecord, error) {
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return s.trigger(job, time.Now(), true), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+}
+
+// trigger 上一次加入队列的任务已完成时将任务加入队列，并记录本次触发的结果
+func (s *Scheduler) trigger(job *scheduledJob, now time.Time, manual bool) *RunRecord {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	record := &RunRecord{At: now, Manual: manual}
+
+	pending, err := s.pendingTask(job.Name, job.TaskType)
+	switch {
+	case err != nil:
+		record.Result = ResultFailed
+		record.Error = fmt.Sprintf("failed to check previous run: %v", err)
+	case pending != nil:
+		record.Result = ResultSkipped
+		record.TaskID = pending.ID
+		fmt.Printf("[SCHEDULER] Skipped job %s: previous task %s is still %s\n", job.Name, pending.ID, pending.Status)
+	default:
+		data := make(map[string]any, len(job.Data)+1)
+		for key, value := range job.Data {
+			data[key] = value
+		}
+		data[JobDataKey] = job.Name
+
+		task := &types.TaskMessage{Type: job.TaskType, Data: data, CreatedAt: now}
+		if err := s.queue.Enqueue(task); err != nil {
+			record.Result = ResultFailed
+			record.Error = err.Error()
+		} else {
+			record.Result = ResultEnqueued
+			record.TaskID = task.ID
+		}
+	}
+
+	if record.Result == ResultFailed {
+		fmt.Printf("[SCHEDULER] Job %s failed: %s\n", job.Name, record.Error)
+	}
+
+	s.mutex.Lock()
+	switch record.Result {
+	case ResultEnqueued:
+		job.enqueued++
+	case ResultSkipped:
+		job.skipped++
+	}
+	job.lastRun = record
+	s.mutex.Unlock()
+
+	copied := *record
+	return &copied
+}
+
+// pendingTask 查找该定时任务加入队列后尚未完成（等待中、等待重试或处理中）的任务
+// 从队列中查找而不是只看上一次记录的任务ID，重启前加入队列的任务同样会阻止重叠执行
+func (s *Scheduler) pendingTask(name, taskType string) (*types.TaskMessage, error) {
+	for _, status := range []string{types.TaskStatusRunning, types.TaskStatusQueued, types.TaskStatusFailed} {
+		tasks, err := s.queue.ListTasks(types.TaskFilter{Status: status, Type: taskType})
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if task.Data[JobDataKey] == name {
+				return task, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// GetStatus 获取调度器和各定时任务的状态，按任务名排序
+func (s *Scheduler) GetStatus() map[string]any {
+	s.mutex.Lock()
+	running := s.running
+	jobs := make([]*JobStatus, len(s.jobs))
+	for i, job := range s.jobs {
+		jobs[i] = &JobStatus{
+			Name:     job.Name,
+			Schedule: job.Schedule,
+			TaskType: job.TaskType,
+			NextRun:  job.next,
+			Enqueued: job.enqueued,
+			Skipped:  job.skipped,
+		}
+		if job.lastRun != nil {
+			lastRun := *job.lastRun
+			jobs[i].LastRun = &lastRun
+		}
+	}
+	s.mutex.Unlock()
+
+	// 在锁外查询队列，补充上一次触发的任务当前所处的状态
+	for _, job := range jobs {
+		if job.LastRun != nil && job.LastRun.TaskID != "" {
+			job.LastRun.TaskStatus = s.taskStatus(job.LastRun.TaskID)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+
+	return map[string]any{
+		"running": running,
+		"jobs":    jobs,
+	}
+}
+
+// taskStatus 获取任务在队列中的状态，成功后超过保留时间被删除的任务返回空
+func (s *Scheduler) taskStatus(id string) string {
+	task, err := s.queue.GetTask(id)
+	if err != nil {
+		return ""
+	}
+	return task.Status
+}
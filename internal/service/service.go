@@ -1,17 +1,26 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"time"
 
+	"mock-storage/internal/backup"
 	"mock-storage/internal/config"
 	"mock-storage/internal/handler/s3"
 	"mock-storage/internal/metadata"
 	"mock-storage/internal/queue"
+	"mock-storage/internal/scheduler"
 	"mock-storage/internal/storage"
+	"mock-storage/internal/tiering"
+	"mock-storage/internal/types"
+	"mock-storage/internal/wal"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,9 +29,14 @@ import (
 type ObjectStorageService struct {
 	config          *config.Config
 	storageManager  *storage.Manager
-	databaseManager *metadata.DatabaseManager
+	databaseManager metadata.MetadataStore
 	metadataService *metadata.MetaService
 	queueManager    *queue.Manager
+	tierer          *tiering.Tierer
+	scheduler       *scheduler.Scheduler
+	backupManager   *backup.Manager
+	wal             *wal.Log
+	s3Service       *s3.Service
 	s3Handler       *s3.Handler
 	server          *http.Server
 }
@@ -35,12 +49,18 @@ func NewObjectStorageService() (*ObjectStorageService, error) {
 		return nil, fmt.Errorf("failed to load config: %v", err)
 	}
 
+	return NewObjectStorageServiceWithConfig(cfg)
+}
+
+// NewObjectStorageServiceWithConfig 使用指定配置创建对象存储服务
+// 存储和数据库驱动都为memory时不访问磁盘，可用于集成测试
+func NewObjectStorageServiceWithConfig(cfg *config.Config) (*ObjectStorageService, error) {
 	service := &ObjectStorageService{
 		config: cfg,
 	}
 
 	// 初始化各个组件
-	err = service.initializeComponents()
+	err := service.initializeComponents()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize components: %v", err)
 	}
@@ -50,6 +70,11 @@ func NewObjectStorageService() (*ObjectStorageService, error) {
 
 // ensureDataDirectories 确保所有必要的数据目录存在
 func (oss *ObjectStorageService) ensureDataDirectories() error {
+	if oss.config.IsEphemeral() {
+		fmt.Println("- 内存模式，跳过数据目录")
+		return nil
+	}
+
 	// 1. 确保主数据目录存在
 	dataDir := oss.config.Storage.DataDir
 	err := os.MkdirAll(dataDir, 0755)
@@ -59,16 +84,24 @@ func (oss *ObjectStorageService) ensureDataDirectories() error {
 	fmt.Printf("- 确保数据目录存在: %s\n", dataDir)
 
 	// 2. 确保数据库文件目录存在
-	dbPath := oss.config.Database.DSN
-	dbDir := filepath.Dir(dbPath)
-	err = os.MkdirAll(dbDir, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create database directory %s: %v", dbDir, err)
+	if oss.config.Database.Driver == metadata.DriverSQLite {
+		dbPath := oss.config.Database.DSN
+		dbDir := filepath.Dir(dbPath)
+		err = os.MkdirAll(dbDir, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create database directory %s: %v", dbDir, err)
+		}
+		fmt.Printf("- 确保数据库目录存在: %s\n", dbDir)
 	}
-	fmt.Printf("- 确保数据库目录存在: %s\n", dbDir)
 
 	// 3. 确保所有存储节点目录存在
+	if oss.config.Storage.Driver == storage.DriverMemory {
+		return nil
+	}
 	for _, nodeConfig := range oss.config.Storage.Nodes {
+		if nodeConfig.Endpoint != "" {
+			continue
+		}
 		err = os.MkdirAll(nodeConfig.Path, 0755)
 		if err != nil {
 			return fmt.Errorf("failed to create storage node directory %s: %v", nodeConfig.Path, err)
@@ -92,7 +125,7 @@ func (oss *ObjectStorageService) initializeComponents() error {
 
 	// 1. 初始化数据库管理器
 	fmt.Println("初始化数据库...")
-	oss.databaseManager, err = metadata.NewDatabaseManager(oss.config.Database.Driver, oss.config.Database.DSN)
+	oss.databaseManager, err = metadata.NewMetadataStore(oss.config.Database.Driver, oss.config.Database.DSN)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %v", err)
 	}
@@ -103,35 +136,118 @@ func (oss *ObjectStorageService) initializeComponents() error {
 
 	// 创建存储节点
 	for _, nodeConfig := range oss.config.Storage.Nodes {
-		node, err := storage.NewFileStorageNode(nodeConfig.ID, nodeConfig.Path)
+		node, err := NewStorageNode(oss.config.Storage.Driver, nodeConfig)
 		if err != nil {
 			return fmt.Errorf("failed to create storage node %s: %v", nodeConfig.ID, err)
 		}
-		oss.storageManager.AddNode(node)
-		fmt.Printf("- 创建存储节点: %s (%s)\n", nodeConfig.ID, nodeConfig.Path)
+		if oss.config.FaultInjection.Enabled {
+			faults := oss.config.FaultInjection.Nodes[nodeConfig.ID]
+			oss.storageManager.AddNode(storage.NewFaultyNode(node, faults))
+		} else {
+			oss.storageManager.AddNode(node)
+		}
+		if nodeConfig.Endpoint != "" {
+			fmt.Printf("- 连接远程存储节点: %s (%s)\n", nodeConfig.ID, nodeConfig.Endpoint)
+		} else {
+			fmt.Printf("- 创建存储节点: %s (%s)\n", nodeConfig.ID, nodeConfig.Path)
+		}
+	}
+
+	if oss.config.FaultInjection.Enabled {
+		nodeIDs := oss.storageManager.GetNodeIDs()
+		for nodeID := range oss.config.FaultInjection.Nodes {
+			if !slices.Contains(nodeIDs, nodeID) {
+				return fmt.Errorf("invalid fault injection config: storage node %s not found", nodeID)
+			}
+		}
+		fmt.Println("- ⚠️  已启用存储节点故障注入")
+	}
+
+	// 设置存储类别
+	for class, nodeIDs := range oss.config.Storage.Classes {
+		err = oss.storageManager.SetStorageClass(class, nodeIDs)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("- 存储类别: %s -> %v\n", class, nodeIDs)
+	}
+	if !oss.storageManager.HasStorageClass(types.StorageClassStandard) {
+		return fmt.Errorf("storage class %s must be configured", types.StorageClassStandard)
+	}
+
+	// 设置读缓存
+	if oss.config.Cache.Enabled && oss.config.Cache.MaxBytes > 0 {
+		cache := storage.NewObjectCache(oss.config.Cache.MaxBytes, oss.config.Cache.MaxObjectSize)
+		oss.storageManager.SetCache(cache)
+		fmt.Printf("- 启用读缓存: %d bytes (单对象上限 %d bytes)\n", oss.config.Cache.MaxBytes, oss.config.Cache.MaxObjectSize)
+	}
+
+	// 设置对冲读取
+	if oss.config.HedgedRead.Enabled {
+		hedgeConfig := oss.config.HedgedRead
+		delay := time.Duration(hedgeConfig.Delay) * time.Millisecond
+		oss.storageManager.SetHedger(storage.NewHedger(delay, hedgeConfig.Adaptive, hedgeConfig.MinSamples))
+		fmt.Printf("- 启用对冲读取: 延迟 %v (adaptive: %v)\n", delay, hedgeConfig.Adaptive)
+	}
+
+	// 设置服务端加密
+	switch oss.config.Encryption.DefaultMode {
+	case types.EncryptionNone, types.EncryptionSSES3:
+	default:
+		return fmt.Errorf("unsupported default encryption mode: %s", oss.config.Encryption.DefaultMode)
+	}
+	if oss.config.IsEphemeral() {
+		// 内存模式下数据不持久化，主密钥也只保存在内存中
+		encryptor, err := storage.NewEphemeralEncryptor()
+		if err != nil {
+			return fmt.Errorf("failed to initialize encryptor: %v", err)
+		}
+		oss.storageManager.SetEncryptor(encryptor)
+	} else if oss.config.Encryption.KeyFile != "" {
+		fmt.Println("初始化服务端加密...")
+		encryptor, err := storage.NewEncryptor(oss.config.Encryption.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to initialize encryptor: %v", err)
+		}
+		oss.storageManager.SetEncryptor(encryptor)
+		fmt.Printf("- 加载主密钥: %s\n", oss.config.Encryption.KeyFile)
 	}
 
-	// 设置第三方服务
+	// 设置第三方回源服务
 	fmt.Println("初始化第三方服务...")
-	thirdPartyService := storage.NewMockThirdPartyService("mock-third-party", "http://mock-third-party.example.com/api")
-	oss.storageManager.SetThirdPartyService(thirdPartyService)
-	fmt.Printf("- 设置第三方服务: %s\n", thirdPartyService.GetName())
+	err = oss.initializeThirdParty()
+	if err != nil {
+		return fmt.Errorf("failed to initialize third party service: %v", err)
+	}
 
 	// 3. 初始化元数据服务
 	fmt.Println("初始化元数据服务...")
 	oss.metadataService = metadata.NewMetaService(oss.databaseManager)
 
+	// 初始化元数据备份，只有SQLite支持在线备份
+	if database, ok := oss.databaseManager.(*metadata.DatabaseManager); ok && oss.config.Database.Driver == metadata.DriverSQLite {
+		err = oss.initializeBackup(database)
+		if err != nil {
+			return fmt.Errorf("failed to initialize backup: %v", err)
+		}
+	}
+
 	// 4. 初始化队列管理器
 	fmt.Println("初始化队列管理器...")
-	oss.queueManager = queue.NewManager(oss.config.Queue.Size)
+	err = oss.initializeQueue()
+	if err != nil {
+		return fmt.Errorf("failed to initialize queue: %v", err)
+	}
 
-	// 创建工作节点
-	worker1 := queue.NewWorker("worker-1", nil) // 使用默认处理器
-	worker2 := queue.NewWorker("worker-2", nil) // 使用默认处理器
+	// 注册直接操作存储节点的任务处理器（文件删除、临时文件清理）
+	err = queue.RegisterStorageHandlers(oss.queueManager.Registry(), oss.storageManager)
+	if err != nil {
+		return fmt.Errorf("failed to register storage task handlers: %v", err)
+	}
 
-	// 为工作节点设置存储管理器，使其能够执行文件删除操作
-	worker1.SetStorageManager(oss.storageManager)
-	worker2.SetStorageManager(oss.storageManager)
+	// 创建工作节点
+	worker1 := queue.NewWorker(queue.WorkerID("worker-1"))
+	worker2 := queue.NewWorker(queue.WorkerID("worker-2"))
 
 	oss.queueManager.AddWorker(worker1)
 	oss.queueManager.AddWorker(worker2)
@@ -142,12 +258,319 @@ func (oss *ObjectStorageService) initializeComponents() error {
 	// 5. 初始化S3处理器
 	fmt.Println("初始化S3接口处理器...")
 	s3Service := s3.NewService(oss.storageManager, oss.metadataService, oss.queueManager)
+	s3Service.SetDefaultEncryption(oss.config.Encryption.DefaultMode)
+	oss.s3Service = s3Service
 	oss.s3Handler = s3.NewHandler(s3Service)
 
+	// 设置操作日志，未完成的操作在启动服务时恢复
+	if oss.config.WAL.Enabled {
+		if oss.config.IsEphemeral() {
+			fmt.Println("- 内存模式，跳过操作日志")
+		} else {
+			oss.wal, err = wal.Open(oss.config.WAL.Dir, oss.config.WAL.SegmentSize)
+			if err != nil {
+				return fmt.Errorf("failed to open operation log: %v", err)
+			}
+			s3Service.SetWAL(oss.wal)
+			fmt.Printf("- 启用操作日志: %s\n", oss.config.WAL.Dir)
+		}
+	}
+
+	// 初始化存储分层任务，迁移通过S3服务在对象锁内执行
+	if oss.config.Tiering.Enabled {
+		fmt.Println("初始化存储分层...")
+		err = oss.initializeTiering()
+		if err != nil {
+			return fmt.Errorf("failed to initialize tiering: %v", err)
+		}
+	}
+
+	// 源站同步（write-back模式）和副本校验任务由S3服务处理
+	err = s3Service.RegisterTaskHandlers(oss.queueManager.Registry())
+	if err != nil {
+		return fmt.Errorf("failed to register S3 task handlers: %v", err)
+	}
+
+	// 所有处理器注册后再应用配置中的并发和超时
+	err = oss.configureTaskHandlers()
+	if err != nil {
+		return err
+	}
+
+	// 初始化定时任务，到期的任务加入队列由工作节点执行
+	if oss.config.Scheduler.Enabled {
+		fmt.Println("初始化定时任务...")
+		err = oss.initializeScheduler()
+		if err != nil {
+			return fmt.Errorf("failed to initialize scheduler: %v", err)
+		}
+	}
+
 	fmt.Println("=== 所有组件初始化完成 ===")
 	return nil
 }
 
+// initializeQueue 根据配置创建任务队列，持久化队列中的任务在重启后继续处理
+func (oss *ObjectStorageService) initializeQueue() error {
+	driver := oss.config.Queue.Driver
+	if driver == "" {
+		driver = queue.DriverSQLite
+		if oss.config.IsEphemeral() {
+			driver = queue.DriverMemory
+		}
+	}
+
+	var store queue.Store
+	switch driver {
+	case queue.DriverSQLite:
+		path := oss.config.Queue.Path
+		if path == "" {
+			path = filepath.Join(oss.config.Storage.DataDir, "queue.db")
+		}
+		sqliteStore, err := queue.NewSQLiteStore(path)
+		if err != nil {
+			return err
+		}
+		store = sqliteStore
+		fmt.Printf("- 持久化任务队列: %s\n", path)
+	case queue.DriverMemory:
+		store = queue.NewMemoryStore()
+		fmt.Println("- 内存任务队列，重启后未处理的任务丢失")
+	default:
+		return fmt.Errorf("unsupported queue driver: %s", driver)
+	}
+
+	leaseTimeout := time.Duration(oss.config.Queue.LeaseTimeout) * time.Second
+	if leaseTimeout <= 0 {
+		leaseTimeout = 5 * time.Minute
+	}
+
+	oss.queueManager = queue.NewManager(store, oss.config.Queue.Size, leaseTimeout)
+	if oss.config.Queue.Retention > 0 {
+		oss.queueManager.SetRetention(time.Duration(oss.config.Queue.Retention) * time.Second)
+	}
+	for taskType, retry := range oss.config.Queue.Retry {
+		policy := newRetryPolicy(retry)
+		if taskType == "default" {
+			oss.queueManager.SetDefaultRetryPolicy(policy)
+		} else {
+			oss.queueManager.SetRetryPolicy(taskType, policy)
+		}
+	}
+	return nil
+}
+
+// configureTaskHandlers 按配置覆盖任务类型的并发上限和超时，未设置（为0）的字段保持处理器注册时的值
+func (oss *ObjectStorageService) configureTaskHandlers() error {
+	registry := oss.queueManager.Registry()
+	for taskType, handlerConfig := range oss.config.Queue.Handlers {
+		options, ok := registry.Options(taskType)
+		if !ok {
+			return fmt.Errorf("queue handler config references unknown task type %s", taskType)
+		}
+
+		if handlerConfig.Concurrency > 0 {
+			options.Concurrency = handlerConfig.Concurrency
+		}
+		if handlerConfig.Timeout > 0 {
+			options.Timeout = time.Duration(handlerConfig.Timeout) * time.Second
+		}
+		if err := registry.SetOptions(taskType, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRetryPolicy 将重试配置转换为重试策略，未设置的字段使用默认策略的值
+func newRetryPolicy(retry config.RetryConfig) queue.RetryPolicy {
+	policy := queue.DefaultRetryPolicy
+	if retry.MaxAttempts > 0 {
+		policy.MaxAttempts = retry.MaxAttempts
+	}
+	if retry.InitialBackoff > 0 {
+		policy.InitialBackoff = time.Duration(retry.InitialBackoff) * time.Millisecond
+	}
+	if retry.MaxBackoff > 0 {
+		policy.MaxBackoff = time.Duration(retry.MaxBackoff) * time.Millisecond
+	}
+	if retry.Multiplier > 0 {
+		policy.Multiplier = retry.Multiplier
+	}
+	if retry.Jitter > 0 {
+		policy.Jitter = retry.Jitter
+	}
+	return policy
+}
+
+// initializeScheduler 根据配置创建定时任务调度器
+func (oss *ObjectStorageService) initializeScheduler() error {
+	var jobs []scheduler.Job
+	for _, jobConfig := range oss.config.Scheduler.Jobs {
+		jobs = append(jobs, scheduler.Job{
+			Name:     jobConfig.Name,
+			Schedule: jobConfig.Schedule,
+			TaskType: jobConfig.Task,
+			Data:     jobConfig.Data,
+		})
+	}
+
+	sched, err := scheduler.NewScheduler(oss.queueManager, jobs)
+	if err != nil {
+		return err
+	}
+
+	oss.scheduler = sched
+	for _, job := range jobs {
+		fmt.Printf("- 定时任务 %s: %s (%s)\n", job.Name, job.TaskType, job.Schedule)
+	}
+	return nil
+}
+
+// initializeTiering 根据配置创建存储分层任务
+func (oss *ObjectStorageService) initializeTiering() error {
+	var rules []tiering.Rule
+	for _, ruleConfig := range oss.config.Tiering.Rules {
+		rules = append(rules, tiering.Rule{
+			From:      ruleConfig.From,
+			To:        ruleConfig.To,
+			Prefix:    ruleConfig.Prefix,
+			Tags:      ruleConfig.Tags,
+			Condition: ruleConfig.Condition,
+			Days:      ruleConfig.Days,
+		})
+	}
+
+	interval := time.Duration(oss.config.Tiering.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	tierer, err := tiering.NewTierer(oss.metadataService, oss.storageManager, oss.s3Service, rules, interval)
+	if err != nil {
+		return err
+	}
+
+	oss.tierer = tierer
+	fmt.Printf("- 分层规则: %d 条，扫描间隔 %v\n", len(rules), interval)
+	return nil
+}
+
+// initializeBackup 创建元数据备份任务，未启用定期备份时仍可通过管理API手动备份
+func (oss *ObjectStorageService) initializeBackup(database *metadata.DatabaseManager) error {
+	interval := time.Duration(oss.config.Backup.Interval) * time.Second
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	dir := oss.config.Backup.Dir
+	if dir == "" {
+		dir = backup.DefaultDir
+	}
+
+	manager, err := backup.NewManager(database, dir, interval, oss.config.Backup.Retention)
+	if err != nil {
+		return err
+	}
+
+	oss.backupManager = manager
+	if oss.config.Backup.Enabled {
+		fmt.Printf("- 定期备份元数据: %s (间隔 %v，保留 %d 个)\n", dir, interval, oss.config.Backup.Retention)
+	}
+	return nil
+}
+
+// initializeThirdParty 根据配置创建按bucket路由的回源服务
+func (oss *ObjectStorageService) initializeThirdParty() error {
+	thirdParty := oss.config.ThirdParty
+	if thirdParty.Default == nil && len(thirdParty.Buckets) == 0 {
+		fmt.Println("- 未配置第三方源站")
+		return nil
+	}
+
+	cache := storage.NewPullThroughCache(time.Duration(thirdParty.NegativeCacheTTL) * time.Second)
+
+	if thirdParty.Default != nil {
+		origin, err := newOriginService("default", *thirdParty.Default)
+		if err != nil {
+			return err
+		}
+		cache.SetDefaultOrigin(origin, thirdParty.Default.WriteMode)
+		fmt.Printf("- 设置默认源站: %s (%s)\n", thirdParty.Default.Type, thirdParty.Default.Endpoint)
+	}
+
+	for bucket, originConfig := range thirdParty.Buckets {
+		origin, err := newOriginService(bucket, originConfig)
+		if err != nil {
+			return err
+		}
+		cache.SetOrigin(bucket, origin, originConfig.WriteMode)
+		fmt.Printf("- 设置bucket源站: %s -> %s (%s)\n", bucket, originConfig.Type, originConfig.Endpoint)
+	}
+
+	oss.storageManager.SetThirdPartyService(cache)
+	return nil
+}
+
+// NewStorageNode 根据存储驱动创建存储节点
+func NewStorageNode(driver string, nodeConfig config.NodeConfig) (types.StorageNode, error) {
+	if nodeConfig.Endpoint != "" {
+		timeout := time.Duration(nodeConfig.Timeout) * time.Millisecond
+		return storage.NewRemoteStorageNode(nodeConfig.ID, nodeConfig.Endpoint, nodeConfig.Token, timeout), nil
+	}
+
+	switch driver {
+	case "", storage.DriverFile:
+		return storage.NewFileStorageNode(nodeConfig.ID, nodeConfig.Path)
+	case storage.DriverMemory:
+		return storage.NewMemoryStorageNode(nodeConfig.ID), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", driver)
+	}
+}
+
+// newOriginService 根据源站配置创建对应的第三方服务实现
+func newOriginService(name string, originConfig config.OriginConfig) (storage.ThirdPartyService, error) {
+	timeout := time.Duration(originConfig.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	switch originConfig.WriteMode {
+	case storage.WriteModeNone, storage.WriteModeThrough, storage.WriteModeBack:
+	default:
+		return nil, fmt.Errorf("origin %s: unsupported write mode %s", name, originConfig.WriteMode)
+	}
+
+	options := storage.OriginOptions{
+		Name:       name,
+		Endpoint:   originConfig.Endpoint,
+		Timeout:    timeout,
+		MaxRetries: originConfig.MaxRetries,
+		Bucket:     originConfig.Bucket,
+		Region:     originConfig.Region,
+		AccessKey:  originConfig.AccessKey,
+		SecretKey:  originConfig.SecretKey,
+	}
+
+	switch originConfig.Type {
+	case "http", "":
+		if originConfig.Endpoint == "" {
+			return nil, fmt.Errorf("origin %s: endpoint is required", name)
+		}
+		return storage.NewHTTPOriginService(options), nil
+	case "s3":
+		if originConfig.Endpoint == "" || originConfig.Bucket == "" {
+			return nil, fmt.Errorf("origin %s: endpoint and bucket are required for s3 origins", name)
+		}
+		return storage.NewS3OriginService(options), nil
+	case "mock":
+		return storage.NewMockThirdPartyService(name, originConfig.Endpoint), nil
+	default:
+		return nil, fmt.Errorf("origin %s: unsupported type %s", name, originConfig.Type)
+	}
+}
+
 // Start 启动服务
 func (oss *ObjectStorageService) Start() error {
 	fmt.Printf("启动对象存储服务在 %s:%s\n", oss.config.Server.Host, oss.config.Server.Port)
@@ -158,6 +581,30 @@ func (oss *ObjectStorageService) Start() error {
 		return fmt.Errorf("failed to start queue manager: %v", err)
 	}
 
+	// 开始处理请求前恢复操作日志中未完成的操作，源站同步任务需要队列已启动
+	recovered, err := oss.s3Service.RecoverOperations()
+	if err != nil {
+		fmt.Printf("⚠️  部分未完成的操作恢复失败，将在下次启动时重试: %v\n", err)
+	}
+	if recovered > 0 {
+		fmt.Printf("- 已恢复 %d 个未完成的操作\n", recovered)
+	}
+
+	// 启动存储分层任务
+	if oss.tierer != nil {
+		oss.tierer.Start()
+	}
+
+	// 启动定时任务
+	if oss.scheduler != nil {
+		oss.scheduler.Start()
+	}
+
+	// 启动定期备份
+	if oss.backupManager != nil && oss.config.Backup.Enabled {
+		oss.backupManager.Start()
+	}
+
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
 
@@ -170,7 +617,9 @@ func (oss *ObjectStorageService) Start() error {
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, HEAD, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, "+
+			"x-amz-server-side-encryption, x-amz-server-side-encryption-customer-algorithm, "+
+			"x-amz-server-side-encryption-customer-key, x-amz-server-side-encryption-customer-key-MD5")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -198,6 +647,34 @@ func (oss *ObjectStorageService) Start() error {
 		api.GET("/health", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "ok"})
 		})
+
+		// 存储分层
+		api.GET("/tiering", oss.getTieringStatus)
+		api.POST("/tiering/run", oss.runTiering)
+
+		// 任务队列
+		api.GET("/queue", oss.getQueueStats)
+		api.GET("/queue/dead", oss.listDeadTasks)
+		api.DELETE("/queue/dead", oss.purgeDeadTasks)
+		api.GET("/queue/dead/:id", oss.getDeadTask)
+		api.POST("/queue/dead/:id/retry", oss.retryDeadTask)
+		api.DELETE("/queue/dead/:id", oss.purgeDeadTasks)
+		api.GET("/tasks", oss.listTasks)
+		api.GET("/tasks/:id", oss.getTask)
+		api.GET("/tasks/:id/wait", oss.waitTask)
+
+		// 定时任务
+		api.GET("/scheduler", oss.getSchedulerStatus)
+		api.POST("/scheduler/jobs/:name/run", oss.runScheduledJob)
+
+		// 元数据备份
+		api.GET("/backups", oss.listBackups)
+		api.POST("/backups", oss.createBackup)
+
+		// 故障注入
+		api.GET("/faults", oss.getFaults)
+		api.PUT("/faults/:node", oss.setFaults)
+		api.DELETE("/faults/:node", oss.clearFaults)
 	}
 
 	// 创建HTTP服务器
@@ -238,11 +715,36 @@ func (oss *ObjectStorageService) Stop() error {
 		}
 	}
 
+	// 停止存储分层任务
+	if oss.tierer != nil {
+		oss.tierer.Stop()
+	}
+
+	// 停止定时任务
+	if oss.scheduler != nil {
+		oss.scheduler.Stop()
+	}
+
+	// 停止定期备份
+	if oss.backupManager != nil {
+		oss.backupManager.Stop()
+	}
+
 	// 停止队列管理器
 	if oss.queueManager != nil {
 		if err := oss.queueManager.Stop(); err != nil {
 			fmt.Printf("停止队列管理器时出错: %v\n", err)
 		}
+		if err := oss.queueManager.Close(); err != nil {
+			fmt.Printf("关闭任务队列时出错: %v\n", err)
+		}
+	}
+
+	// 关闭操作日志
+	if oss.wal != nil {
+		if err := oss.wal.Close(); err != nil {
+			fmt.Printf("关闭操作日志时出错: %v\n", err)
+		}
 	}
 
 	// 关闭数据库
@@ -255,3 +757,285 @@ func (oss *ObjectStorageService) Stop() error {
 	fmt.Println("✅ 对象存储服务已停止")
 	return nil
 }
+
+// getQueueStats 获取任务队列和工作节点状态
+func (oss *ObjectStorageService) getQueueStats(c *gin.Context) {
+	c.JSON(http.StatusOK, oss.queueManager.GetStats())
+}
+
+// listDeadTasks 列出死信队列中的任务，可按type过滤，limit/offset分页
+func (oss *ObjectStorageService) listDeadTasks(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		offset = 0
+	}
+
+	tasks, err := oss.queueManager.ListTasks(types.TaskFilter{
+		Status: types.TaskStatusDead,
+		Type:   c.Query("type"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list dead tasks: %v", err)})
+		return
+	}
+	if tasks == nil {
+		tasks = []*types.TaskMessage{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks, "count": len(tasks)})
+}
+
+// getDeadTask 获取死信队列中的任务
+func (oss *ObjectStorageService) getDeadTask(c *gin.Context) {
+	task, err := oss.queueManager.GetTask(c.Param("id"))
+	if err == nil && task.Status != types.TaskStatusDead {
+		err = fmt.Errorf("%w: no dead task %s", queue.ErrTaskNotFound, task.ID)
+	}
+	if err != nil {
+		respondTaskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// retryDeadTask 将死信队列中的任务重新放回队列
+func (oss *ObjectStorageService) retryDeadTask(c *gin.Context) {
+	id := c.Param("id")
+	if err := oss.queueManager.RetryDeadTask(id); err != nil {
+		respondTaskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "status": types.TaskStatusQueued})
+}
+
+// purgeDeadTasks 删除死信队列中的指定任务，未指定id时清空死信队列
+func (oss *ObjectStorageService) purgeDeadTasks(c *gin.Context) {
+	purged, err := oss.queueManager.PurgeDeadTasks(c.Param("id"))
+	if err != nil {
+		respondTaskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+// listTasks 列出队列任务，可按type、status和object_key过滤，最新入队的任务在前，limit/offset分页
+func (oss *ObjectStorageService) listTasks(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", types.TaskStatusQueued, types.TaskStatusRunning, types.TaskStatusSucceeded,
+		types.TaskStatusFailed, types.TaskStatusDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid task status: %s", status)})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		offset = 0
+	}
+
+	tasks, err := oss.queueManager.ListTasks(types.TaskFilter{
+		Status:      status,
+		Type:        c.Query("type"),
+		ObjectID:    c.Query("object_key"),
+		Limit:       limit,
+		Offset:      offset,
+		NewestFirst: true,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list tasks: %v", err)})
+		return
+	}
+	if tasks == nil {
+		tasks = []*types.TaskMessage{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks, "count": len(tasks)})
+}
+
+// getTask 获取任务及其当前状态
+func (oss *ObjectStorageService) getTask(c *gin.Context) {
+	task, err := oss.queueManager.GetTask(c.Param("id"))
+	if err != nil {
+		respondTaskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// maxTaskWait 等待任务的最长时间
+const maxTaskWait = 5 * time.Minute
+
+// waitTask 等待任务成功或进入死信队列后返回任务，超过timeout（秒，默认30）仍未结束时返回202和任务当前的状态
+func (oss *ObjectStorageService) waitTask(c *gin.Context) {
+	seconds, err := strconv.Atoi(c.DefaultQuery("timeout", "30"))
+	if err != nil || seconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timeout"})
+		return
+	}
+	timeout := time.Duration(seconds) * time.Second
+	if timeout > maxTaskWait {
+		timeout = maxTaskWait
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	task, err := oss.queueManager.WaitTask(ctx, c.Param("id"))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, task)
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		c.JSON(http.StatusAccepted, task)
+	default:
+		respondTaskError(c, err)
+	}
+}
+
+// respondTaskError 任务不存在时返回404，其他错误返回500
+func respondTaskError(c *gin.Context, err error) {
+	if errors.Is(err, queue.ErrTaskNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// getTieringStatus 获取存储分层任务状态
+func (oss *ObjectStorageService) getTieringStatus(c *gin.Context) {
+	if oss.tierer == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	status := oss.tierer.GetStatus()
+	status["enabled"] = true
+	c.JSON(http.StatusOK, status)
+}
+
+// runTiering 立即执行一次存储分层
+func (oss *ObjectStorageService) runTiering(c *gin.Context) {
+	if oss.tierer == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tiering is not enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, oss.tierer.RunOnce())
+}
+
+// getSchedulerStatus 获取定时任务状态和上一次触发的结果
+func (oss *ObjectStorageService) getSchedulerStatus(c *gin.Context) {
+	if oss.scheduler == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	status := oss.scheduler.GetStatus()
+	status["enabled"] = true
+	c.JSON(http.StatusOK, status)
+}
+
+// runScheduledJob 立即触发一次定时任务
+func (oss *ObjectStorageService) runScheduledJob(c *gin.Context) {
+	if oss.scheduler == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scheduler is not enabled"})
+		return
+	}
+
+	record, err := oss.scheduler.RunJob(c.Param("name"))
+	if errors.Is(err, scheduler.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// listBackups 获取备份任务状态和快照列表
+func (oss *ObjectStorageService) listBackups(c *gin.Context) {
+	if oss.backupManager == nil {
+		c.JSON(http.StatusOK, gin.H{"supported": false, "snapshots": []any{}})
+		return
+	}
+
+	snapshots, err := oss.backupManager.Snapshots()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := oss.backupManager.GetStatus()
+	status["supported"] = true
+	status["enabled"] = oss.config.Backup.Enabled
+	status["snapshots"] = snapshots
+	c.JSON(http.StatusOK, status)
+}
+
+// createBackup 立即生成一个元数据快照
+func (oss *ObjectStorageService) createBackup(c *gin.Context) {
+	if oss.backupManager == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Online backup is only supported for SQLite metadata"})
+		return
+	}
+
+	result := oss.backupManager.RunOnce()
+	if result.Snapshot == nil {
+		c.JSON(http.StatusInternalServerError, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// getFaults 获取存储节点故障注入配置和统计
+func (oss *ObjectStorageService) getFaults(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": oss.config.FaultInjection.Enabled,
+		"nodes":   oss.storageManager.GetNodeFaults(),
+	})
+}
+
+// setFaults 设置存储节点的故障注入配置
+func (oss *ObjectStorageService) setFaults(c *gin.Context) {
+	var faults types.FaultConfig
+	if err := c.ShouldBindJSON(&faults); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	nodeID := c.Param("node")
+	if err := oss.storageManager.SetNodeFaults(nodeID, faults); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Faults updated", "node": nodeID, "config": faults})
+}
+
+// clearFaults 清除存储节点的故障注入配置
+func (oss *ObjectStorageService) clearFaults(c *gin.Context) {
+	nodeID := c.Param("node")
+	if err := oss.storageManager.SetNodeFaults(nodeID, types.FaultConfig{}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Faults cleared", "node": nodeID})
+}
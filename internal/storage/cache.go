@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"container/list"
+	"sync"
+
+	"mock-storage/internal/types"
+)
+
+// ObjectCache 按字节数限制容量的LRU对象缓存
+// 缓存项以key和版本标识（对象ID+ETag）为键，版本不一致的缓存项视为失效
+type ObjectCache struct {
+	maxBytes      int64
+	maxObjectSize int64
+
+	mutex     sync.Mutex
+	items     map[string]*list.Element
+	lru       *list.List
+	usedBytes int64
+
+	// 统计
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// cacheItem 缓存项
+type cacheItem struct {
+	key     string
+	version string
+	obj     *types.FileObject
+}
+
+// NewObjectCache 创建对象缓存
+func NewObjectCache(maxBytes, maxObjectSize int64) *ObjectCache {
+	if maxObjectSize <= 0 || maxObjectSize > maxBytes {
+		maxObjectSize = maxBytes
+	}
+
+	return &ObjectCache{
+		maxBytes:      maxBytes,
+		maxObjectSize: maxObjectSize,
+		items:         make(map[string]*list.Element),
+		lru:           list.New(),
+	}
+}
+
+// Get 获取缓存的对象，版本不匹配时返回未命中
+func (oc *ObjectCache) Get(key, version string) (*types.FileObject, bool) {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	element, ok := oc.items[key]
+	if !ok {
+		oc.misses++
+		return nil, false
+	}
+
+	item := element.Value.(*cacheItem)
+	if item.version != version {
+		oc.removeElement(element)
+		oc.misses++
+		return nil, false
+	}
+
+	oc.lru.MoveToFront(element)
+	oc.hits++
+
+	obj := *item.obj
+	return &obj, true
+}
+
+// Put 将对象放入缓存，超过单对象大小限制的对象不缓存
+func (oc *ObjectCache) Put(key, version string, obj *types.FileObject) {
+	size := int64(len(obj.Data))
+	if size > oc.maxObjectSize {
+		return
+	}
+
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	if element, ok := oc.items[key]; ok {
+		oc.removeElement(element)
+	}
+
+	// 淘汰最久未使用的缓存项直到有足够空间
+	for oc.usedBytes+size > oc.maxBytes {
+		oldest := oc.lru.Back()
+		if oldest == nil {
+			break
+		}
+		oc.removeElement(oldest)
+		oc.evictions++
+	}
+
+	stored := *obj
+	element := oc.lru.PushFront(&cacheItem{key: key, version: version, obj: &stored})
+	oc.items[key] = element
+	oc.usedBytes += size
+}
+
+// Invalidate 使key的缓存失效
+func (oc *ObjectCache) Invalidate(key string) {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	if element, ok := oc.items[key]; ok {
+		oc.removeElement(element)
+	}
+}
+
+// removeElement 删除缓存项（调用方需持有锁）
+func (oc *ObjectCache) removeElement(element *list.Element) {
+	item := element.Value.(*cacheItem)
+	oc.lru.Remove(element)
+	delete(oc.items, item.key)
+	oc.usedBytes -= int64(len(item.obj.Data))
+}
+
+// GetStats 获取缓存统计信息
+func (oc *ObjectCache) GetStats() map[string]any {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	hitRate := 0.0
+	if total := oc.hits + oc.misses; total > 0 {
+		hitRate = float64(oc.hits) / float64(total) * 100
+	}
+
+	return map[string]any{
+		"entries":         len(oc.items),
+		"used_bytes":      oc.usedBytes,
+		"max_bytes":       oc.maxBytes,
+		"max_object_size": oc.maxObjectSize,
+		"hits":            oc.hits,
+		"misses":          oc.misses,
+		"evictions":       oc.evictions,
+		"hit_rate":        hitRate,
+	}
+}
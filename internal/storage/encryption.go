@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mock-storage/internal/types"
+)
+
+// 密钥长度（AES-256）
+const encryptionKeySize = 32
+
+// Encryptor 服务端加密器
+// 每个对象使用独立的数据密钥进行AES-256-GCM加密，数据密钥再由主密钥（SSE-S3）
+// 或客户提供的密钥（SSE-C）包装后记录到元数据中
+type Encryptor struct {
+	masterKey []byte
+}
+
+// NewEncryptor 创建加密器，从keyFile加载主密钥，文件不存在时自动生成
+func NewEncryptor(keyFile string) (*Encryptor, error) {
+	masterKey, err := loadOrCreateMasterKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Encryptor{masterKey: masterKey}, nil
+}
+
+// NewEphemeralEncryptor 创建使用随机主密钥的加密器，主密钥不落盘，进程退出后数据无法解密
+func NewEphemeralEncryptor() (*Encryptor, error) {
+	masterKey := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(masterKey); err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %v", err)
+	}
+
+	return &Encryptor{masterKey: masterKey}, nil
+}
+
+// loadOrCreateMasterKey 加载或生成主密钥文件（base64编码的32字节密钥）
+func loadOrCreateMasterKey(keyFile string) ([]byte, error) {
+	content, err := os.ReadFile(keyFile)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode master key %s: %v", keyFile, err)
+		}
+		if len(key) != encryptionKeySize {
+			return nil, fmt.Errorf("invalid master key length in %s: expected %d bytes, got %d", keyFile, encryptionKeySize, len(key))
+		}
+		return key, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read master key %s: %v", keyFile, err)
+	}
+
+	// 密钥文件不存在，生成新的主密钥
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %v", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(key) + "\n"
+	if err := os.WriteFile(keyFile, []byte(encoded), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write master key %s: %v", keyFile, err)
+	}
+
+	fmt.Printf("[ENCRYPTION] Generated new master key: %s\n", keyFile)
+	return key, nil
+}
+
+// EncryptObject 加密对象数据
+// 返回用于写入存储节点的密文副本，同时在obj上记录包装后的数据密钥
+func (e *Encryptor) EncryptObject(obj *types.FileObject) (*types.FileObject, error) {
+	wrappingKey, err := e.wrappingKey(obj.Encryption, obj.CustomerKey, obj.EncryptionKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成对象独立的数据密钥
+	dataKey := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+
+	wrapped, err := seal(wrappingKey, dataKey, []byte(obj.Key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %v", err)
+	}
+
+	ciphertext, err := seal(dataKey, obj.Data, []byte(obj.Key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt object data: %v", err)
+	}
+
+	obj.EncryptedDataKey = base64.StdEncoding.EncodeToString(wrapped)
+
+	stored := *obj
+	stored.Data = ciphertext
+	stored.Size = int64(len(ciphertext))
+	stored.MD5Hash = calculateMD5(ciphertext)
+	stored.CustomerKey = nil
+
+	return &stored, nil
+}
+
+// DecryptObject 使用元数据中的密钥信息解密从存储节点读取的对象
+func (e *Encryptor) DecryptObject(stored *types.FileObject, entry *types.MetadataEntry, customerKey []byte) (*types.FileObject, error) {
+	wrappingKey, err := e.wrappingKey(entry.Encryption, customerKey, entry.EncryptionKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(entry.EncryptedDataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data key: %v", err)
+	}
+
+	dataKey, err := open(wrappingKey, wrapped, []byte(entry.Key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+
+	plaintext, err := open(dataKey, stored.Data, []byte(entry.Key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt object data: %v", err)
+	}
+
+	obj := *stored
+	obj.Data = plaintext
+	obj.Size = int64(len(plaintext))
+	obj.MD5Hash = calculateMD5(plaintext)
+	obj.Encryption = entry.Encryption
+	obj.EncryptionKeyMD5 = entry.EncryptionKeyMD5
+
+	return &obj, nil
+}
+
+// wrappingKey 根据加密模式选择用于包装数据密钥的密钥
+func (e *Encryptor) wrappingKey(mode string, customerKey []byte, expectedKeyMD5 string) ([]byte, error) {
+	switch mode {
+	case types.EncryptionSSES3:
+		return e.masterKey, nil
+	case types.EncryptionSSEC:
+		if len(customerKey) == 0 {
+			return nil, ErrCustomerKeyRequired
+		}
+		if len(customerKey) != encryptionKeySize {
+			return nil, fmt.Errorf("customer key must be %d bytes, got %d", encryptionKeySize, len(customerKey))
+		}
+		if expectedKeyMD5 != "" && CustomerKeyMD5(customerKey) != expectedKeyMD5 {
+			return nil, ErrCustomerKeyMismatch
+		}
+		return customerKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported encryption mode: %s", mode)
+	}
+}
+
+// CustomerKeyMD5 计算SSE-C客户密钥的MD5（base64编码，与S3一致）
+func CustomerKeyMD5(key []byte) string {
+	hash := md5.Sum(key)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// SSE-C相关错误
+var (
+	ErrCustomerKeyRequired = fmt.Errorf("object is encrypted with a customer-provided key, but no key was supplied")
+	ErrCustomerKeyMismatch = fmt.Errorf("the supplied customer key does not match the key used to encrypt the object")
+)
+
+// seal 使用AES-256-GCM加密，输出格式为 nonce || ciphertext || tag
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open 解密seal生成的数据
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce := ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], additionalData)
+}
+
+// newGCM 创建AES-GCM实例
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
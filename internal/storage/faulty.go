@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"syscall"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// 故障注入的操作类型
+const (
+	faultOpRead   = "read"
+	faultOpWrite  = "write"
+	faultOpDelete = "delete"
+)
+
+// FaultyNode 故障注入存储节点装饰器
+// 按配置为读、写、删除操作注入延迟、错误和数据损坏，并可模拟只读和磁盘已满，用于本地混沌测试
+type FaultyNode struct {
+	node types.StorageNode
+
+	mu       sync.RWMutex
+	config   types.FaultConfig
+	injected map[string]int64 // 故障类型 -> 注入次数
+	rand     *rand.Rand
+}
+
+// NewFaultyNode 包装存储节点，初始配置为空时不注入任何故障
+func NewFaultyNode(node types.StorageNode, config types.FaultConfig) *FaultyNode {
+	return &FaultyNode{
+		node:     node,
+		config:   config,
+		injected: make(map[string]int64),
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetFaults 替换故障配置
+func (f *FaultyNode) SetFaults(config types.FaultConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config = config
+}
+
+// GetFaults 获取当前故障配置和注入统计
+func (f *FaultyNode) GetFaults() map[string]any {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	injected := make(map[string]int64, len(f.injected))
+	for kind, count := range f.injected {
+		injected[kind] = count
+	}
+
+	return map[string]any{
+		"config":   f.config,
+		"injected": injected,
+	}
+}
+
+// Unwrap 返回被包装的存储节点
+func (f *FaultyNode) Unwrap() types.StorageNode {
+	return f.node
+}
+
+// GetNodeID 获取节点ID
+func (f *FaultyNode) GetNodeID() string {
+	return f.node.GetNodeID()
+}
+
+// Write 写入对象，按配置注入故障
+func (f *FaultyNode) Write(obj *types.FileObject) error {
+	config, rule := f.rule(faultOpWrite)
+
+	if err := f.delay(context.Background(), rule); err != nil {
+		return err
+	}
+	if config.ReadOnly {
+		f.count("read_only")
+		return fmt.Errorf("[%s] injected fault: failed to write %s: %w", f.GetNodeID(), obj.Key, syscall.EROFS)
+	}
+	if config.DiskFull {
+		f.count("disk_full")
+		return fmt.Errorf("[%s] injected fault: failed to write %s: %w", f.GetNodeID(), obj.Key, syscall.ENOSPC)
+	}
+	if err := f.maybeError(faultOpWrite, obj.Key, rule); err != nil {
+		return err
+	}
+
+	if f.hit(rule.CorruptRate) && len(obj.Data) > 0 {
+		f.count("write_corrupt")
+		// 静默损坏：写入篡改后的数据并跳过节点的MD5校验，模拟位翻转
+		corrupted := *obj
+		corrupted.Data = f.corrupt(obj.Data)
+		corrupted.MD5Hash = ""
+		return f.node.Write(&corrupted)
+	}
+
+	return f.node.Write(obj)
+}
+
+// Read 读取对象，按配置注入故障
+func (f *FaultyNode) Read(key string) (*types.FileObject, error) {
+	return f.ReadContext(context.Background(), key)
+}
+
+// ReadContext 支持取消的读取，注入的延迟可被ctx打断
+func (f *FaultyNode) ReadContext(ctx context.Context, key string) (*types.FileObject, error) {
+	_, rule := f.rule(faultOpRead)
+
+	if err := f.delay(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := f.maybeError(faultOpRead, key, rule); err != nil {
+		return nil, err
+	}
+
+	obj, err := readWithContext(ctx, f.node, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.hit(rule.CorruptRate) && len(obj.Data) > 0 {
+		f.count("read_corrupt")
+		// 保留原始MD5，调用方可以通过校验发现损坏
+		corrupted := *obj
+		corrupted.Data = f.corrupt(obj.Data)
+		return &corrupted, nil
+	}
+
+	return obj, nil
+}
+
+// Stat 获取对象信息，按读取规则注入延迟和错误
+func (f *FaultyNode) Stat(key string) (*types.FileObject, error) {
+	_, rule := f.rule(faultOpRead)
+
+	if err := f.delay(context.Background(), rule); err != nil {
+		return nil, err
+	}
+	if err := f.maybeError(faultOpRead, key, rule); err != nil {
+		return nil, err
+	}
+
+	return f.node.Stat(key)
+}
+
+// Delete 删除对象，按配置注入故障
+func (f *FaultyNode) Delete(key string) error {
+	config, rule := f.rule(faultOpDelete)
+
+	if err := f.delay(context.Background(), rule); err != nil {
+		return err
+	}
+	if config.ReadOnly {
+		f.count("read_only")
+		return fmt.Errorf("[%s] injected fault: failed to delete %s: %w", f.GetNodeID(), key, syscall.EROFS)
+	}
+	if err := f.maybeError(faultOpDelete, key, rule); err != nil {
+		return err
+	}
+
+	return f.node.Delete(key)
+}
+
+// rule 获取当前配置及指定操作的规则
+func (f *FaultyNode) rule(op string) (types.FaultConfig, types.FaultRule) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	switch op {
+	case faultOpRead:
+		return f.config, f.config.Read
+	case faultOpWrite:
+		return f.config, f.config.Write
+	default:
+		return f.config, f.config.Delete
+	}
+}
+
+// delay 注入延迟
+func (f *FaultyNode) delay(ctx context.Context, rule types.FaultRule) error {
+	if rule.LatencyMs <= 0 {
+		return nil
+	}
+
+	f.count("latency")
+	timer := time.NewTimer(time.Duration(rule.LatencyMs) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// maybeError 按错误率注入错误
+func (f *FaultyNode) maybeError(op, key string, rule types.FaultRule) error {
+	if !f.hit(rule.ErrorRate) {
+		return nil
+	}
+
+	f.count(op + "_error")
+	return fmt.Errorf("[%s] injected fault: %s %s failed: %w", f.GetNodeID(), op, key, syscall.EIO)
+}
+
+// hit 按概率判断是否注入
+func (f *FaultyNode) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rand.Float64() < rate
+}
+
+// corrupt 返回随机翻转一个字节后的数据副本
+func (f *FaultyNode) corrupt(data []byte) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	corrupted := make([]byte, len(data))
+	copy(corrupted, data)
+	corrupted[f.rand.Intn(len(corrupted))] ^= 0xFF
+	return corrupted
+}
+
+// count 记录一次故障注入
+func (f *FaultyNode) count(kind string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.injected[kind]++
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// ContextReader 支持取消的读取接口，节点实现后对冲读取的落败请求可以被及时取消
+type ContextReader interface {
+	ReadContext(ctx context.Context, key string) (*types.FileObject, error)
+}
+
+// 用于计算p95的最近读取延迟样本数
+const hedgeLatencyWindow = 256
+
+// Hedger 对冲读取策略
+// 首个副本在延迟阈值内没有返回时，向下一个副本发起相同的读取，取先完成的结果并取消其余请求
+type Hedger struct {
+	delay      time.Duration // 固定延迟，adaptive模式下样本不足时也使用该值
+	adaptive   bool          // 使用最近读取延迟的p95作为阈值
+	minSamples int
+
+	mu        sync.Mutex
+	latencies []time.Duration // 环形缓冲区
+	next      int
+
+	reads  int64 // 经过对冲逻辑的读取次数
+	fired  int64 // 发起了对冲请求的次数
+	won    int64 // 对冲请求先于首个请求返回的次数
+	errors int64 // 所有副本都读取失败的次数
+}
+
+// NewHedger 创建对冲读取策略
+func NewHedger(delay time.Duration, adaptive bool, minSamples int) *Hedger {
+	if minSamples <= 0 {
+		minSamples = 20
+	}
+	return &Hedger{
+		delay:      delay,
+		adaptive:   adaptive,
+		minSamples: minSamples,
+		latencies:  make([]time.Duration, 0, hedgeLatencyWindow),
+	}
+}
+
+// Threshold 当前的对冲延迟阈值
+func (h *Hedger) Threshold() time.Duration {
+	if !h.adaptive {
+		return h.delay
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < h.minSamples {
+		return h.delay
+	}
+
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*95)/100]
+}
+
+// record 记录一次成功读取的延迟
+func (h *Hedger) record(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < hedgeLatencyWindow {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgeLatencyWindow
+}
+
+// hedgeResult 单个副本的读取结果
+type hedgeResult struct {
+	index int
+	obj   *types.FileObject
+	err   error
+}
+
+// Read 按顺序对节点发起对冲读取
+// 超过阈值未返回或读取失败时启动下一个副本，返回第一个成功的结果
+func (h *Hedger) Read(key string, nodes []types.StorageNode) (*types.FileObject, error) {
+	atomic.AddInt64(&h.reads, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 缓冲足够大，落败的请求返回后不会阻塞
+	results := make(chan hedgeResult, len(nodes))
+	start := time.Now()
+
+	launch := func(index int) {
+		go func() {
+			obj, err := readWithContext(ctx, nodes[index], key)
+			results <- hedgeResult{index: index, obj: obj, err: err}
+		}()
+	}
+
+	launch(0)
+	launched := 1
+	pending := 1
+
+	timer := time.NewTimer(h.Threshold())
+	defer timer.Stop()
+
+	var lastErr error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if launched < len(nodes) {
+				atomic.AddInt64(&h.fired, 1)
+				fmt.Printf("[HEDGE] Read of %s exceeded threshold, hedging to node %s\n", key, nodes[launched].GetNodeID())
+				launch(launched)
+				launched++
+				pending++
+				timer.Reset(h.Threshold())
+			}
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				h.record(time.Since(start))
+				if result.index > 0 {
+					atomic.AddInt64(&h.won, 1)
+				}
+				return result.obj, nil
+			}
+
+			lastErr = result.err
+			fmt.Printf("Failed to read from node %s: %v\n", nodes[result.index].GetNodeID(), result.err)
+
+			// 失败时立即尝试下一个副本，不等待阈值
+			if launched < len(nodes) {
+				launch(launched)
+				launched++
+				pending++
+			}
+		}
+	}
+
+	atomic.AddInt64(&h.errors, 1)
+	return nil, lastErr
+}
+
+// GetStats 获取对冲读取统计
+func (h *Hedger) GetStats() map[string]any {
+	reads := atomic.LoadInt64(&h.reads)
+	fired := atomic.LoadInt64(&h.fired)
+	won := atomic.LoadInt64(&h.won)
+
+	var fireRate float64
+	if reads > 0 {
+		fireRate = float64(fired) / float64(reads)
+	}
+
+	return map[string]any{
+		"reads":        reads,
+		"hedges_fired": fired,
+		"hedges_won":   won,
+		"fire_rate":    fireRate,
+		"failures":     atomic.LoadInt64(&h.errors),
+		"threshold_ms": h.Threshold().Milliseconds(),
+		"adaptive":     h.adaptive,
+	}
+}
+
+// readWithContext 节点支持ContextReader时传入ctx，否则退化为普通读取
+func readWithContext(ctx context.Context, node types.StorageNode, key string) (*types.FileObject, error) {
+	if reader, ok := node.(ContextReader); ok {
+		return reader.ReadContext(ctx, key)
+	}
+	return node.Read(key)
+}
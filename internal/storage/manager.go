@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"mock-storage/internal/types"
 )
@@ -10,16 +11,41 @@ type ThirdPartyService interface {
 	GetObject(key string) (*types.FileObject, error)
 }
 
+// WritableThirdPartyService 支持写入的第三方服务，key不包含bucket前缀
+type WritableThirdPartyService interface {
+	ThirdPartyService
+	PutObject(key string, obj *types.FileObject) error
+	DeleteObject(key string) error
+}
+
+// 存储节点驱动
+const (
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// 源站写入模式
+const (
+	WriteModeNone    = ""              // 只读回源
+	WriteModeThrough = "write-through" // PUT/DELETE同步写入源站
+	WriteModeBack    = "write-back"    // PUT/DELETE通过队列异步写入源站
+)
+
 // Manager 存储管理器，管理多个存储节点
 type Manager struct {
-	nodes           []types.StorageNode
+	nodes             []types.StorageNode
 	thirdPartyService ThirdPartyService
+	encryptor         *Encryptor
+	cache             *ObjectCache
+	classes           map[string][]string // 存储类别 -> 节点ID列表
+	hedger            *Hedger
 }
 
 // NewManager 创建存储管理器
 func NewManager() *Manager {
 	return &Manager{
-		nodes: make([]types.StorageNode, 0),
+		nodes:   make([]types.StorageNode, 0),
+		classes: make(map[string][]string),
 	}
 }
 
@@ -28,38 +54,260 @@ func (sm *Manager) AddNode(node types.StorageNode) {
 	sm.nodes = append(sm.nodes, node)
 }
 
+// SetStorageClass 设置存储类别对应的节点组
+func (sm *Manager) SetStorageClass(class string, nodeIDs []string) error {
+	for _, nodeID := range nodeIDs {
+		if sm.getNode(nodeID) == nil {
+			return fmt.Errorf("storage class %s references unknown node %s", class, nodeID)
+		}
+	}
+
+	sm.classes[class] = nodeIDs
+	return nil
+}
+
+// HasStorageClass 判断存储类别是否已配置，未配置任何类别时仅支持STANDARD
+func (sm *Manager) HasStorageClass(class string) bool {
+	if len(sm.classes) == 0 {
+		return class == types.StorageClassStandard
+	}
+
+	_, ok := sm.classes[class]
+	return ok
+}
+
+// NodeIDsForClass 获取存储类别对应的节点ID，未配置任何类别时使用全部节点
+func (sm *Manager) NodeIDsForClass(class string) []string {
+	if len(sm.classes) == 0 {
+		return sm.GetNodeIDs()
+	}
+	return sm.classes[class]
+}
+
 // SetThirdPartyService 设置第三方服务
 func (sm *Manager) SetThirdPartyService(service ThirdPartyService) {
 	sm.thirdPartyService = service
 }
 
-// WriteToAllNodes 顺序写入所有存储节点
-func (sm *Manager) WriteToAllNodes(obj *types.FileObject) error {
+// SetCache 设置读缓存
+func (sm *Manager) SetCache(cache *ObjectCache) {
+	sm.cache = cache
+}
+
+// InvalidateCache 使key的读缓存失效
+func (sm *Manager) InvalidateCache(key string) {
+	if sm.cache != nil {
+		sm.cache.Invalidate(key)
+	}
+}
+
+// GetCacheStats 获取读缓存统计信息
+func (sm *Manager) GetCacheStats() map[string]any {
+	if sm.cache == nil {
+		return nil
+	}
+	return sm.cache.GetStats()
+}
+
+// SetHedger 启用对冲读取
+func (sm *Manager) SetHedger(hedger *Hedger) {
+	sm.hedger = hedger
+}
+
+// GetHedgeStats 获取对冲读取统计，未启用时返回nil
+func (sm *Manager) GetHedgeStats() map[string]any {
+	if sm.hedger == nil {
+		return nil
+	}
+	return sm.hedger.GetStats()
+}
+
+// SetEncryptor 设置服务端加密器
+func (sm *Manager) SetEncryptor(encryptor *Encryptor) {
+	sm.encryptor = encryptor
+}
+
+// WriteToClassNodes 顺序写入对象存储类别对应的所有节点，返回写入成功的节点ID
+// 如果对象要求服务端加密，写入节点的是密文，包装后的数据密钥记录在obj上
+func (sm *Manager) WriteToClassNodes(obj *types.FileObject) ([]string, error) {
+	stored, err := sm.PrepareStored(obj)
+	if err != nil {
+		return nil, err
+	}
+	return sm.writeToNodes(stored, sm.NodeIDsForClass(obj.StorageClass), nil)
+}
+
+// PrepareStored 生成写入节点的原始数据：按需加密，并使用对象版本在节点上的key
+func (sm *Manager) PrepareStored(obj *types.FileObject) (*types.FileObject, error) {
+	if obj.StorageClass == "" {
+		obj.StorageClass = types.StorageClassStandard
+	}
+	if !sm.HasStorageClass(obj.StorageClass) {
+		return nil, fmt.Errorf("unknown storage class: %s", obj.StorageClass)
+	}
+
+	stored := obj
+	if obj.Encryption != types.EncryptionNone {
+		if sm.encryptor == nil {
+			return nil, fmt.Errorf("server-side encryption requested but no encryptor configured")
+		}
+
+		var err error
+		stored, err = sm.encryptor.EncryptObject(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt object: %v", err)
+		}
+	}
+
+	// 对象版本写入各自的节点key，加密使用的附加数据仍是对象key
+	storageKey := types.StorageKey(obj.Key, obj.VersionID)
+	if storageKey != obj.Key {
+		versioned := *stored
+		versioned.Key = storageKey
+		stored = &versioned
+	}
+
+	// 对象被覆盖，旧的缓存内容失效
+	sm.InvalidateCache(storageKey)
+	return stored, nil
+}
+
+// writeToNodes 将节点上存储的原始数据顺序写入指定节点，返回写入成功的节点ID
+// tx不为空时在写入前记录节点上原有的数据，用于回滚
+func (sm *Manager) writeToNodes(stored *types.FileObject, nodeIDs []string, tx *NodeTransaction) ([]string, error) {
 	var lastErr error
-	successCount := 0
+	written := make([]string, 0, len(nodeIDs))
 
 	// 顺序写入每个节点
-	for i, node := range sm.nodes {
-		err := node.Write(obj)
+	for i, nodeID := range nodeIDs {
+		node := sm.getNode(nodeID)
+		if node == nil {
+			lastErr = fmt.Errorf("unknown storage node: %s", nodeID)
+			continue
+		}
+
+		if tx != nil {
+			tx.recordBefore(node, stored.Key)
+		}
+
+		err := node.Write(stored)
 		if err != nil {
 			lastErr = err
 			fmt.Printf("Failed to write to node %s: %v\n", node.GetNodeID(), err)
 			continue
 		}
-		successCount++
+		written = append(written, nodeID)
 		fmt.Printf("Step %d: Successfully wrote to node %s\n", i+1, node.GetNodeID())
 	}
 
 	// 如果至少有一个节点写入成功，则认为写入成功
-	if successCount == 0 {
-		return fmt.Errorf("failed to write to any storage node, last error: %v", lastErr)
+	if len(written) == 0 {
+		return nil, fmt.Errorf("failed to write to any storage node, last error: %v", lastErr)
 	}
 
-	if successCount < len(sm.nodes) {
-		fmt.Printf("Warning: Only %d out of %d nodes wrote successfully\n", successCount, len(sm.nodes))
+	if len(written) < len(nodeIDs) {
+		fmt.Printf("Warning: Only %d out of %d nodes wrote successfully\n", len(written), len(nodeIDs))
 	}
 
-	return nil
+	return written, nil
+}
+
+// DeleteFromNodes 从指定节点删除对象
+func (sm *Manager) DeleteFromNodes(key string, nodeIDs []string) error {
+	var lastErr error
+	for _, nodeID := range nodeIDs {
+		node := sm.getNode(nodeID)
+		if node == nil {
+			continue
+		}
+		if err := node.Delete(key); err != nil {
+			fmt.Printf("Warning: failed to delete %s from node %s: %v\n", key, nodeID, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// DecryptObject 根据元数据解密从存储节点读取的对象，未加密的对象原样返回
+func (sm *Manager) DecryptObject(obj *types.FileObject, entry *types.MetadataEntry, customerKey []byte) (*types.FileObject, error) {
+	if entry.Encryption == types.EncryptionNone {
+		return obj, nil
+	}
+
+	if sm.encryptor == nil {
+		return nil, fmt.Errorf("object %s is encrypted but no encryptor configured", entry.Key)
+	}
+
+	return sm.encryptor.DecryptObject(obj, entry, customerKey)
+}
+
+// ReadObject 从元数据记录的节点读取对象，优先命中读缓存，不从第三方回源
+// version用于区分同一key的不同写入（对象ID+ETag），缓存中的是节点上存储的原始数据
+func (sm *Manager) ReadObject(key, version string, nodeIDs []string) (*types.FileObject, error) {
+	if sm.cache != nil {
+		if obj, ok := sm.cache.Get(key, version); ok {
+			return obj, nil
+		}
+	}
+
+	obj, err := sm.ReadFromLocalNodes(key, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if sm.cache != nil {
+		sm.cache.Put(key, version, obj)
+	}
+	return obj, nil
+}
+
+// ReadFromLocalNodes 按顺序从指定节点读取，不经过读缓存，也不从第三方回源
+// 启用对冲读取且有多个副本时，慢节点超过阈值后会并发读取下一个副本
+func (sm *Manager) ReadFromLocalNodes(key string, nodeIDs []string) (*types.FileObject, error) {
+	nodes := make([]types.StorageNode, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		if node := sm.getNode(nodeID); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+
+	if sm.hedger != nil && len(nodes) > 1 {
+		obj, err := sm.hedger.Read(key, nodes)
+		if err == nil {
+			return obj, nil
+		}
+	} else {
+		for _, node := range nodes {
+			obj, err := node.Read(key)
+			if err == nil {
+				return obj, nil
+			}
+			fmt.Printf("Failed to read from node %s: %v\n", node.GetNodeID(), err)
+		}
+	}
+
+	return nil, fmt.Errorf("failed to read file %s from nodes %v", key, nodeIDs)
+}
+
+// ReadFromNode 直接读取指定节点上存储的原始数据，不经过缓存和第三方回源
+// 节点支持ContextReader时ctx取消会中断读取
+func (sm *Manager) ReadFromNode(ctx context.Context, nodeID, key string) (*types.FileObject, error) {
+	node := sm.getNode(nodeID)
+	if node == nil {
+		return nil, fmt.Errorf("storage node %s not found", nodeID)
+	}
+	return readWithContext(ctx, node, key)
+}
+
+// RestoreReplica 将健康副本的原始数据写回指定节点，并清除可能缓存的损坏数据
+func (sm *Manager) RestoreReplica(nodeID string, stored *types.FileObject) error {
+	node := sm.getNode(nodeID)
+	if node == nil {
+		return fmt.Errorf("storage node %s not found", nodeID)
+	}
+
+	sm.InvalidateCache(stored.Key)
+	return node.Write(stored)
 }
 
 // ReadFromStg1OrThirdParty 优先从stg1读取，如果失败则从第三方获取
@@ -84,7 +332,7 @@ func (sm *Manager) ReadFromStg1OrThirdParty(key string) (*types.FileObject, erro
 		if err != nil {
 			return nil, fmt.Errorf("failed to get object from third party service: %v", err)
 		}
-		
+
 		fmt.Printf("Successfully fetched from third party service: %s\n", key)
 		return obj, nil
 	}
@@ -92,6 +340,67 @@ func (sm *Manager) ReadFromStg1OrThirdParty(key string) (*types.FileObject, erro
 	return nil, fmt.Errorf("failed to read file %s from stg1 and no third party service configured", key)
 }
 
+// HasThirdPartyOrigin 判断bucket是否配置了回源源站
+func (sm *Manager) HasThirdPartyOrigin(bucket string) bool {
+	if sm.thirdPartyService == nil {
+		return false
+	}
+
+	if router, ok := sm.thirdPartyService.(interface{ HasOrigin(bucket string) bool }); ok {
+		return router.HasOrigin(bucket)
+	}
+	return true
+}
+
+// FetchFromThirdParty 直接从第三方服务获取对象
+func (sm *Manager) FetchFromThirdParty(key string) (*types.FileObject, error) {
+	if sm.thirdPartyService == nil {
+		return nil, fmt.Errorf("no third party service configured")
+	}
+
+	return sm.thirdPartyService.GetObject(key)
+}
+
+// OriginWriteMode 获取bucket的源站写入模式
+func (sm *Manager) OriginWriteMode(bucket string) string {
+	if router, ok := sm.thirdPartyService.(interface{ WriteMode(bucket string) string }); ok {
+		return router.WriteMode(bucket)
+	}
+	return WriteModeNone
+}
+
+// PutToThirdParty 将对象（明文）写入第三方服务
+func (sm *Manager) PutToThirdParty(obj *types.FileObject) error {
+	writer, ok := sm.thirdPartyService.(interface {
+		PutObject(obj *types.FileObject) error
+	})
+	if !ok {
+		return fmt.Errorf("third party service does not support writes")
+	}
+
+	return writer.PutObject(obj)
+}
+
+// DeleteFromThirdParty 从第三方服务删除对象
+func (sm *Manager) DeleteFromThirdParty(key string) error {
+	writer, ok := sm.thirdPartyService.(interface {
+		DeleteObject(key string) error
+	})
+	if !ok {
+		return fmt.Errorf("third party service does not support writes")
+	}
+
+	return writer.DeleteObject(key)
+}
+
+// GetThirdPartyStats 获取回源统计信息
+func (sm *Manager) GetThirdPartyStats() map[string]any {
+	if stats, ok := sm.thirdPartyService.(interface{ GetStats() map[string]any }); ok {
+		return stats.GetStats()
+	}
+	return nil
+}
+
 // ReadFromAnyNode 从任意一个可用的节点读取
 func (sm *Manager) ReadFromAnyNode(key string) (*types.FileObject, error) {
 	for _, node := range sm.nodes {
@@ -105,11 +414,51 @@ func (sm *Manager) ReadFromAnyNode(key string) (*types.FileObject, error) {
 	return nil, fmt.Errorf("failed to read file %s from any storage node", key)
 }
 
+// SetNodeFaults 更新节点的故障注入配置，节点需在启动时被FaultyNode包装
+func (sm *Manager) SetNodeFaults(nodeID string, config types.FaultConfig) error {
+	node := sm.getNode(nodeID)
+	if node == nil {
+		return fmt.Errorf("storage node %s not found", nodeID)
+	}
+
+	faulty, ok := node.(*FaultyNode)
+	if !ok {
+		return fmt.Errorf("fault injection is not enabled for node %s", nodeID)
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	faulty.SetFaults(config)
+	return nil
+}
+
+// GetNodeFaults 获取所有启用故障注入的节点的配置和统计
+func (sm *Manager) GetNodeFaults() map[string]any {
+	faults := make(map[string]any)
+	for _, node := range sm.nodes {
+		if faulty, ok := node.(*FaultyNode); ok {
+			faults[node.GetNodeID()] = faulty.GetFaults()
+		}
+	}
+	return faults
+}
+
 // GetNodes 获取所有存储节点
 func (sm *Manager) GetNodes() []types.StorageNode {
 	return sm.nodes
 }
 
+// getNode 根据ID获取存储节点
+func (sm *Manager) getNode(nodeID string) types.StorageNode {
+	for _, node := range sm.nodes {
+		if node.GetNodeID() == nodeID {
+			return node
+		}
+	}
+	return nil
+}
+
 // GetNodeIDs 获取所有节点ID
 func (sm *Manager) GetNodeIDs() []string {
 	ids := make([]string, len(sm.nodes))
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// MemoryStorageNode 基于内存的存储节点实现，进程退出后数据丢失，用于测试和临时环境
+type MemoryStorageNode struct {
+	nodeID string
+
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// memoryObject 内存中保存的对象数据
+type memoryObject struct {
+	data      []byte
+	createdAt time.Time
+}
+
+// NewMemoryStorageNode 创建新的内存存储节点
+func NewMemoryStorageNode(nodeID string) *MemoryStorageNode {
+	return &MemoryStorageNode{
+		nodeID:  nodeID,
+		objects: make(map[string]memoryObject),
+	}
+}
+
+// GetNodeID 获取节点ID
+func (ms *MemoryStorageNode) GetNodeID() string {
+	return ms.nodeID
+}
+
+// Write 写入文件对象到存储节点
+func (ms *MemoryStorageNode) Write(obj *types.FileObject) error {
+	// 验证MD5
+	if obj.MD5Hash != "" {
+		calculatedHash := calculateMD5(obj.Data)
+		if calculatedHash != obj.MD5Hash {
+			return fmt.Errorf("MD5 hash mismatch: expected %s, got %s", obj.MD5Hash, calculatedHash)
+		}
+	}
+
+	data := make([]byte, len(obj.Data))
+	copy(data, obj.Data)
+
+	ms.mu.Lock()
+	ms.objects[obj.Key] = memoryObject{data: data, createdAt: time.Now()}
+	ms.mu.Unlock()
+
+	return nil
+}
+
+// Read 从存储节点读取文件对象
+func (ms *MemoryStorageNode) Read(key string) (*types.FileObject, error) {
+	ms.mu.RLock()
+	stored, exists := ms.objects[key]
+	ms.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", key)
+	}
+
+	data := make([]byte, len(stored.data))
+	copy(data, stored.data)
+
+	return &types.FileObject{
+		Key:       key,
+		Size:      int64(len(data)),
+		Data:      data,
+		MD5Hash:   calculateMD5(data),
+		CreatedAt: stored.createdAt,
+	}, nil
+}
+
+// Stat 获取对象信息，不复制数据
+func (ms *MemoryStorageNode) Stat(key string) (*types.FileObject, error) {
+	ms.mu.RLock()
+	stored, exists := ms.objects[key]
+	ms.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", key)
+	}
+
+	return &types.FileObject{
+		Key:       key,
+		Size:      int64(len(stored.data)),
+		CreatedAt: stored.createdAt,
+	}, nil
+}
+
+// Delete 从存储节点删除文件
+func (ms *MemoryStorageNode) Delete(key string) error {
+	ms.mu.Lock()
+	delete(ms.objects, key)
+	ms.mu.Unlock()
+
+	return nil
+}
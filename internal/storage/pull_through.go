@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// PullThroughCache 回源层
+// 按bucket将请求路由到对应源站，对源站返回的404做负缓存，并合并同一key的并发回源请求
+type PullThroughCache struct {
+	origins          map[string]ThirdPartyService
+	writeModes       map[string]string
+	defaultOrigin    ThirdPartyService
+	defaultWriteMode string
+	negativeTTL      time.Duration
+
+	mutex    sync.Mutex
+	notFound map[string]time.Time
+	inflight map[string]*fetchCall
+
+	// 统计
+	fetches     int64
+	deduped     int64
+	negativeHit int64
+}
+
+// fetchCall 一次进行中的回源请求
+type fetchCall struct {
+	done chan struct{}
+	obj  *types.FileObject
+	err  error
+}
+
+// NewPullThroughCache 创建回源层
+func NewPullThroughCache(negativeTTL time.Duration) *PullThroughCache {
+	return &PullThroughCache{
+		origins:     make(map[string]ThirdPartyService),
+		writeModes:  make(map[string]string),
+		negativeTTL: negativeTTL,
+		notFound:    make(map[string]time.Time),
+		inflight:    make(map[string]*fetchCall),
+	}
+}
+
+// SetOrigin 为bucket设置源站及写入模式
+func (pc *PullThroughCache) SetOrigin(bucket string, origin ThirdPartyService, writeMode string) {
+	pc.origins[bucket] = origin
+	pc.writeModes[bucket] = writeMode
+}
+
+// SetDefaultOrigin 设置未单独配置的bucket使用的源站及写入模式
+func (pc *PullThroughCache) SetDefaultOrigin(origin ThirdPartyService, writeMode string) {
+	pc.defaultOrigin = origin
+	pc.defaultWriteMode = writeMode
+}
+
+// WriteMode 获取bucket的源站写入模式，源站不支持写入时返回WriteModeNone
+func (pc *PullThroughCache) WriteMode(bucket string) string {
+	origin := pc.originFor(bucket)
+	if _, ok := origin.(WritableThirdPartyService); !ok {
+		return WriteModeNone
+	}
+
+	if mode, ok := pc.writeModes[bucket]; ok {
+		return mode
+	}
+	return pc.defaultWriteMode
+}
+
+// PutObject 将对象写入bucket对应的源站
+func (pc *PullThroughCache) PutObject(obj *types.FileObject) error {
+	bucket, objectKey := splitBucketKey(obj.Key)
+	origin, ok := pc.originFor(bucket).(WritableThirdPartyService)
+	if !ok {
+		return fmt.Errorf("origin for bucket %s does not support writes", bucket)
+	}
+
+	err := origin.PutObject(objectKey, obj)
+	if err != nil {
+		return err
+	}
+
+	// 对象已写入源站，清除负缓存
+	pc.mutex.Lock()
+	delete(pc.notFound, obj.Key)
+	pc.mutex.Unlock()
+
+	return nil
+}
+
+// DeleteObject 从bucket对应的源站删除对象
+func (pc *PullThroughCache) DeleteObject(key string) error {
+	bucket, objectKey := splitBucketKey(key)
+	origin, ok := pc.originFor(bucket).(WritableThirdPartyService)
+	if !ok {
+		return fmt.Errorf("origin for bucket %s does not support writes", bucket)
+	}
+
+	return origin.DeleteObject(objectKey)
+}
+
+// HasOrigin 判断bucket是否配置了源站
+func (pc *PullThroughCache) HasOrigin(bucket string) bool {
+	return pc.originFor(bucket) != nil
+}
+
+// originFor 获取bucket对应的源站
+func (pc *PullThroughCache) originFor(bucket string) ThirdPartyService {
+	if origin, ok := pc.origins[bucket]; ok {
+		return origin
+	}
+	return pc.defaultOrigin
+}
+
+// GetObject 从源站获取对象，key为包含bucket前缀的完整key
+func (pc *PullThroughCache) GetObject(key string) (*types.FileObject, error) {
+	bucket, objectKey := splitBucketKey(key)
+	origin := pc.originFor(bucket)
+	if origin == nil {
+		return nil, fmt.Errorf("no origin configured for bucket: %s", bucket)
+	}
+
+	pc.mutex.Lock()
+
+	// 负缓存：近期确认不存在的对象直接返回
+	if expiry, ok := pc.notFound[key]; ok {
+		if time.Now().Before(expiry) {
+			pc.negativeHit++
+			pc.mutex.Unlock()
+			return nil, ErrOriginNotFound
+		}
+		delete(pc.notFound, key)
+	}
+
+	// 合并并发回源
+	if call, ok := pc.inflight[key]; ok {
+		pc.deduped++
+		pc.mutex.Unlock()
+		<-call.done
+		// 调用方会修改返回的对象（加密、分配版本等），每个等待者各自持有一份副本
+		return copyFileObject(call.obj), call.err
+	}
+
+	call := &fetchCall{done: make(chan struct{})}
+	pc.inflight[key] = call
+	pc.fetches++
+	pc.mutex.Unlock()
+
+	call.obj, call.err = origin.GetObject(objectKey)
+	if call.obj != nil {
+		call.obj.Key = key
+	}
+
+	pc.mutex.Lock()
+	delete(pc.inflight, key)
+	if call.err == ErrOriginNotFound && pc.negativeTTL > 0 {
+		pc.notFound[key] = time.Now().Add(pc.negativeTTL)
+	}
+	pc.mutex.Unlock()
+	close(call.done)
+
+	return call.obj, call.err
+}
+
+// copyFileObject 复制对象，数据和各map不与原对象共享
+func copyFileObject(obj *types.FileObject) *types.FileObject {
+	if obj == nil {
+		return nil
+	}
+	copied := *obj
+	copied.Data = bytes.Clone(obj.Data)
+	copied.CustomerKey = bytes.Clone(obj.CustomerKey)
+	copied.Checksums = maps.Clone(obj.Checksums)
+	copied.UserMetadata = maps.Clone(obj.UserMetadata)
+	copied.Tags = maps.Clone(obj.Tags)
+	return &copied
+}
+
+// GetStats 获取回源统计信息
+func (pc *PullThroughCache) GetStats() map[string]any {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	buckets := make([]string, 0, len(pc.origins))
+	for bucket := range pc.origins {
+		buckets = append(buckets, bucket)
+	}
+
+	return map[string]any{
+		"buckets":             buckets,
+		"has_default_origin":  pc.defaultOrigin != nil,
+		"origin_fetches":      pc.fetches,
+		"deduplicated":        pc.deduped,
+		"negative_cache_hits": pc.negativeHit,
+		"negative_cache_size": len(pc.notFound),
+	}
+}
+
+// splitBucketKey 将完整key拆分为bucket和对象key
+func splitBucketKey(key string) (string, string) {
+	if idx := strings.Index(key, "/"); idx >= 0 {
+		return key[:idx], key[idx+1:]
+	}
+	return "", key
+}
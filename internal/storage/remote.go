@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// 存储节点协议使用的头部
+const (
+	HeaderNodeToken = "X-Node-Token" // 节点间共享令牌
+	HeaderObjectMD5 = "X-Object-MD5" // 对象数据的MD5（hex），GET时作为trailer在数据之后发送
+)
+
+// RemoteStorageNode 通过HTTP访问storagenode进程的存储节点客户端
+type RemoteStorageNode struct {
+	nodeID   string
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewRemoteStorageNode 创建远程存储节点客户端
+func NewRemoteStorageNode(nodeID, endpoint, token string, timeout time.Duration) *RemoteStorageNode {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &RemoteStorageNode{
+		nodeID:   nodeID,
+		endpoint: strings.TrimRight(endpoint, "/"),
+		token:    token,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// GetNodeID 获取节点ID
+func (rn *RemoteStorageNode) GetNodeID() string {
+	return rn.nodeID
+}
+
+// Write 上传对象数据，由远程节点校验MD5
+func (rn *RemoteStorageNode) Write(obj *types.FileObject) error {
+	req, err := rn.newRequest(context.Background(), http.MethodPut, obj.Key, bytes.NewReader(obj.Data))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(obj.Data))
+	if obj.MD5Hash != "" {
+		req.Header.Set(HeaderObjectMD5, obj.MD5Hash)
+	}
+
+	resp, err := rn.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("[%s] failed to write %s: %v", rn.nodeID, obj.Key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("[%s] failed to write %s: %s", rn.nodeID, obj.Key, readRemoteError(resp))
+	}
+
+	return nil
+}
+
+// Read 下载对象数据
+func (rn *RemoteStorageNode) Read(key string) (*types.FileObject, error) {
+	return rn.ReadContext(context.Background(), key)
+}
+
+// ReadContext 下载对象数据，边读边计算MD5并与节点发送的trailer比对，ctx取消时中断传输
+func (rn *RemoteStorageNode) ReadContext(ctx context.Context, key string) (*types.FileObject, error) {
+	req, err := rn.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rn.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to read %s: %v", rn.nodeID, key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("file not found: %s", key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[%s] failed to read %s: %s", rn.nodeID, key, readRemoteError(resp))
+	}
+
+	hash := md5.New()
+	data, err := io.ReadAll(io.TeeReader(resp.Body, hash))
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to read %s: %v", rn.nodeID, key, err)
+	}
+
+	// trailer在读完body后才可用
+	calculatedHash := hex.EncodeToString(hash.Sum(nil))
+	if expected := resp.Trailer.Get(HeaderObjectMD5); expected != "" && expected != calculatedHash {
+		return nil, fmt.Errorf("[%s] checksum mismatch reading %s: expected %s, got %s", rn.nodeID, key, expected, calculatedHash)
+	}
+
+	createdAt, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+
+	return &types.FileObject{
+		Key:       key,
+		Size:      int64(len(data)),
+		Data:      data,
+		MD5Hash:   calculatedHash,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// Stat 获取对象大小和修改时间
+func (rn *RemoteStorageNode) Stat(key string) (*types.FileObject, error) {
+	req, err := rn.newRequest(context.Background(), http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rn.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to stat %s: %v", rn.nodeID, key, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("file not found: %s", key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[%s] failed to stat %s: status %d", rn.nodeID, key, resp.StatusCode)
+	}
+
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	createdAt, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+
+	return &types.FileObject{
+		Key:       key,
+		Size:      size,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// Delete 删除对象，对象不存在视为成功
+func (rn *RemoteStorageNode) Delete(key string) error {
+	req, err := rn.newRequest(context.Background(), http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := rn.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("[%s] failed to delete %s: %v", rn.nodeID, key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("[%s] failed to delete %s: %s", rn.nodeID, key, readRemoteError(resp))
+	}
+
+	return nil
+}
+
+// newRequest 构造对象请求
+func (rn *RemoteStorageNode) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rn.endpoint+"/objects/"+awsURIEncode(key, false), body)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to create request: %v", rn.nodeID, err)
+	}
+	if rn.token != "" {
+		req.Header.Set(HeaderNodeToken, rn.token)
+	}
+	return req, nil
+}
+
+// readRemoteError 读取节点返回的错误信息
+func readRemoteError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Sprintf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
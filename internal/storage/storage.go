@@ -1,15 +1,26 @@
 package storage
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"mock-storage/internal/types"
 )
 
+// ErrChecksumMismatch 写入数据与提供的校验和不一致
+var ErrChecksumMismatch = errors.New("MD5 hash mismatch")
+
+// tempFilePrefix 流式写入时临时文件的前缀，进程在写入过程中崩溃时会残留
+const tempFilePrefix = ".upload-"
+
 // FileStorageNode 基于文件系统的存储节点实现
 type FileStorageNode struct {
 	nodeID   string
@@ -69,36 +80,129 @@ func (fs *FileStorageNode) Write(obj *types.FileObject) error {
 
 // Read 从存储节点读取文件对象
 func (fs *FileStorageNode) Read(key string) (*types.FileObject, error) {
-	filePath := fs.getFilePath(key)
+	return fs.ReadContext(context.Background(), key)
+}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("file not found: %s", key)
+// readChunkSize 支持取消的读取每次读取的字节数，每读完一块检查一次ctx
+const readChunkSize = 1 << 20
+
+// ReadContext 支持取消的读取，按块读取文件，ctx取消后在下一块之前中断并返回ctx的错误
+func (fs *FileStorageNode) ReadContext(ctx context.Context, key string) (*types.FileObject, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// 读取文件数据
-	data, err := os.ReadFile(filePath)
+	file, fileInfo, err := fs.Open(key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+		return nil, err
 	}
+	defer file.Close()
 
-	// 获取文件信息
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file info %s: %v", filePath, err)
+	data := make([]byte, 0, fileInfo.Size())
+	hash := md5.New()
+	buf := make([]byte, readChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, err := file.Read(buf)
+		data = append(data, buf[:n]...)
+		hash.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %v", fs.getFilePath(key), err)
+		}
 	}
 
 	obj := &types.FileObject{
 		Key:       key,
-		Size:      fileInfo.Size(),
+		Size:      int64(len(data)),
 		Data:      data,
-		MD5Hash:   calculateMD5(data),
+		MD5Hash:   hex.EncodeToString(hash.Sum(nil)),
 		CreatedAt: fileInfo.ModTime(),
 	}
 
 	return obj, nil
 }
 
+// Stat 获取文件信息，不读取数据
+func (fs *FileStorageNode) Stat(key string) (*types.FileObject, error) {
+	fileInfo, err := os.Stat(fs.getFilePath(key))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("file not found: %s", key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info %s: %v", key, err)
+	}
+
+	return &types.FileObject{
+		Key:       key,
+		Size:      fileInfo.Size(),
+		CreatedAt: fileInfo.ModTime(),
+	}, nil
+}
+
+// WriteStream 流式写入文件，边写边计算MD5
+// 数据先写入临时文件，校验通过后重命名，避免读到写了一半的文件
+func (fs *FileStorageNode) WriteStream(key string, reader io.Reader, expectedMD5 string) (string, int64, error) {
+	filePath := fs.getFilePath(key)
+
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create directory %s: %v", dir, err)
+	}
+
+	tmpFile, err := os.CreateTemp(dir, tempFilePrefix+"*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp file in %s: %v", dir, err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hash := md5.New()
+	size, err := io.Copy(io.MultiWriter(tmpFile, hash), reader)
+	closeErr := tmpFile.Close()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write file %s: %v", filePath, err)
+	}
+	if closeErr != nil {
+		return "", 0, fmt.Errorf("failed to write file %s: %v", filePath, closeErr)
+	}
+
+	calculatedHash := hex.EncodeToString(hash.Sum(nil))
+	if expectedMD5 != "" && calculatedHash != expectedMD5 {
+		return "", 0, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expectedMD5, calculatedHash)
+	}
+
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		return "", 0, fmt.Errorf("failed to write file %s: %v", filePath, err)
+	}
+
+	fmt.Printf("[%s] Successfully wrote file: %s (size: %d bytes)\n", fs.nodeID, key, size)
+	return calculatedHash, size, nil
+}
+
+// Open 打开文件用于流式读取，调用方负责关闭
+func (fs *FileStorageNode) Open(key string) (*os.File, os.FileInfo, error) {
+	file, err := os.Open(fs.getFilePath(key))
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("file not found: %s", key)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file %s: %v", key, err)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to get file info %s: %v", key, err)
+	}
+
+	return file, fileInfo, nil
+}
+
 // Delete 从存储节点删除文件
 func (fs *FileStorageNode) Delete(key string) error {
 	filePath := fs.getFilePath(key)
@@ -112,6 +216,82 @@ func (fs *FileStorageNode) Delete(key string) error {
 	return nil
 }
 
+// SweepTempFiles 删除修改时间早于olderThan之前的残留临时文件，返回删除的数量
+// 正在写入的临时文件会持续更新修改时间，olderThan应远大于单次上传的耗时
+func (fs *FileStorageNode) SweepTempFiles(olderThan time.Duration) (int, error) {
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+
+	err := filepath.WalkDir(fs.basePath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), tempFilePrefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to sweep temp files in %s: %v", fs.basePath, err)
+	}
+
+	if removed > 0 {
+		fmt.Printf("[%s] Removed %d leftover temp files\n", fs.nodeID, removed)
+	}
+	return removed, nil
+}
+
+// WalkObjects 遍历修改时间早于olderThan之前的所有对象文件（不包括上传临时文件），fn返回错误时停止
+func (fs *FileStorageNode) WalkObjects(olderThan time.Duration, fn func(key string) error) error {
+	cutoff := time.Now().Add(-olderThan)
+
+	err := filepath.WalkDir(fs.basePath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), tempFilePrefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(fs.basePath, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk objects in %s: %v", fs.basePath, err)
+	}
+	return nil
+}
+
 // getFilePath 根据key生成文件路径
 func (fs *FileStorageNode) getFilePath(key string) string {
 	// 直接使用key作为相对路径，保持bucket/object的层次结构
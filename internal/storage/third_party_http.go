@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"mock-storage/internal/types"
+
+	"github.com/google/uuid"
+)
+
+// ErrOriginNotFound 源站上不存在请求的对象
+var ErrOriginNotFound = fmt.Errorf("object not found on origin")
+
+// OriginOptions 源站连接配置
+type OriginOptions struct {
+	Name       string
+	Endpoint   string
+	Timeout    time.Duration
+	MaxRetries int
+
+	// 仅S3兼容源站使用
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// HTTPOriginService 普通HTTP源站，通过 GET <endpoint>/<key> 获取对象
+type HTTPOriginService struct {
+	options OriginOptions
+	client  *http.Client
+}
+
+// NewHTTPOriginService 创建HTTP源站服务
+func NewHTTPOriginService(options OriginOptions) *HTTPOriginService {
+	return &HTTPOriginService{
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+	}
+}
+
+// GetObject 从HTTP源站获取对象
+func (hs *HTTPOriginService) GetObject(key string) (*types.FileObject, error) {
+	url := hs.objectURL(key)
+	fmt.Printf("[THIRD_PARTY] Fetching object from %s: %s\n", hs.options.Name, url)
+
+	return fetchWithRetry(hs.options.MaxRetries, func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		return hs.client.Do(req)
+	}, key)
+}
+
+// PutObject 将对象写入HTTP源站（PUT <endpoint>/<key>）
+func (hs *HTTPOriginService) PutObject(key string, obj *types.FileObject) error {
+	url := hs.objectURL(key)
+
+	return sendWithRetry(hs.options.MaxRetries, func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(obj.Data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", obj.ContentType)
+		return hs.client.Do(req)
+	}, key)
+}
+
+// DeleteObject 从HTTP源站删除对象（DELETE <endpoint>/<key>）
+func (hs *HTTPOriginService) DeleteObject(key string) error {
+	url := hs.objectURL(key)
+
+	return sendWithRetry(hs.options.MaxRetries, func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodDelete, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		return hs.client.Do(req)
+	}, key)
+}
+
+// objectURL 构建对象URL
+func (hs *HTTPOriginService) objectURL(key string) string {
+	return strings.TrimRight(hs.options.Endpoint, "/") + "/" + awsURIEncode(key, false)
+}
+
+// GetName 获取服务名称
+func (hs *HTTPOriginService) GetName() string {
+	return hs.options.Name
+}
+
+// fetchWithRetry 发送请求并在网络错误、5xx或429时按指数退避重试
+func fetchWithRetry(maxRetries int, do func() (*http.Response, error), key string) (*types.FileObject, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(100<<uint(attempt-1)) * time.Millisecond
+			fmt.Printf("[THIRD_PARTY] Retrying %s in %v (attempt %d/%d): %v\n", key, backoff, attempt, maxRetries, lastErr)
+			time.Sleep(backoff)
+		}
+
+		resp, err := do()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		obj, retryable, err := objectFromResponse(resp, key)
+		if err == nil {
+			return obj, nil
+		}
+		if !retryable {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("origin request failed after %d attempts: %v", maxRetries+1, lastErr)
+}
+
+// sendWithRetry 发送写请求，2xx视为成功，删除时404也视为成功
+func sendWithRetry(maxRetries int, do func() (*http.Response, error), key string) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(100<<uint(attempt-1)) * time.Millisecond
+			fmt.Printf("[THIRD_PARTY] Retrying %s in %v (attempt %d/%d): %v\n", key, backoff, attempt, maxRetries, lastErr)
+			time.Sleep(backoff)
+		}
+
+		resp, err := do()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusNotFound && resp.Request.Method == http.MethodDelete:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("origin returned status %d", resp.StatusCode)
+		default:
+			return fmt.Errorf("origin returned status %d", resp.StatusCode)
+		}
+	}
+
+	return fmt.Errorf("origin request failed after %d attempts: %v", maxRetries+1, lastErr)
+}
+
+// objectFromResponse 将源站响应转换为文件对象，保留Content-Type、ETag和Last-Modified
+func objectFromResponse(resp *http.Response, key string) (*types.FileObject, bool, error) {
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, false, ErrOriginNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("origin returned status %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("origin returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read origin response: %v", err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	now := time.Now()
+	obj := &types.FileObject{
+		ID:          uuid.New().String(),
+		Key:         key,
+		Size:        int64(len(data)),
+		ContentType: contentType,
+		Data:        data,
+		MD5Hash:     calculateMD5(data),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		CreatedAt:   now,
+	}
+
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.LastModified = lastModified
+	}
+
+	fmt.Printf("[THIRD_PARTY] Successfully fetched object: %s (size: %d bytes)\n", key, len(data))
+	return obj, false, nil
+}
+
+// awsURIEncode 按S3规则对URI进行编码，encodeSlash为false时保留路径分隔符
+func awsURIEncode(value string, encodeSlash bool) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'),
+			c == '-', c == '_', c == '.', c == '~':
+			builder.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			builder.WriteByte(c)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// 未签名负载的SHA256（空请求体）
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3OriginService S3兼容的上游存储，使用AWS Signature V4进行认证
+type S3OriginService struct {
+	options OriginOptions
+	client  *http.Client
+}
+
+// NewS3OriginService 创建S3兼容源站服务
+func NewS3OriginService(options OriginOptions) *S3OriginService {
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+
+	return &S3OriginService{
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+	}
+}
+
+// GetObject 从上游S3存储获取对象
+func (ss *S3OriginService) GetObject(key string) (*types.FileObject, error) {
+	url := ss.objectURL(key)
+	fmt.Printf("[THIRD_PARTY] Fetching object from %s: %s\n", ss.options.Name, url)
+
+	return fetchWithRetry(ss.options.MaxRetries, func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		ss.sign(req, emptyPayloadHash, time.Now())
+		return ss.client.Do(req)
+	}, key)
+}
+
+// PutObject 将对象写入上游S3存储
+func (ss *S3OriginService) PutObject(key string, obj *types.FileObject) error {
+	url := ss.objectURL(key)
+	payloadHash := sha256.Sum256(obj.Data)
+
+	return sendWithRetry(ss.options.MaxRetries, func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(obj.Data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", obj.ContentType)
+		ss.sign(req, hex.EncodeToString(payloadHash[:]), time.Now())
+		return ss.client.Do(req)
+	}, key)
+}
+
+// DeleteObject 从上游S3存储删除对象
+func (ss *S3OriginService) DeleteObject(key string) error {
+	url := ss.objectURL(key)
+
+	return sendWithRetry(ss.options.MaxRetries, func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodDelete, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		ss.sign(req, emptyPayloadHash, time.Now())
+		return ss.client.Do(req)
+	}, key)
+}
+
+// GetName 获取服务名称
+func (ss *S3OriginService) GetName() string {
+	return ss.options.Name
+}
+
+// objectURL 构建路径风格的对象URL
+func (ss *S3OriginService) objectURL(key string) string {
+	endpoint := strings.TrimRight(ss.options.Endpoint, "/")
+	return endpoint + "/" + awsURIEncode(ss.options.Bucket, true) + "/" + awsURIEncode(key, false)
+}
+
+// sign 使用AWS Signature V4为请求签名，未配置凭证时发送匿名请求
+func (ss *S3OriginService) sign(req *http.Request, payloadHash string, now time.Time) {
+	if ss.options.AccessKey == "" {
+		return
+	}
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	dateStamp := now.UTC().Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	// 规范请求
+	signedHeaderNames := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headerValues := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	sort.Strings(signedHeaderNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaderNames {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headerValues[name]) + "\n")
+	}
+	signedHeaders := strings.Join(signedHeaderNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQueryString(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	// 待签名字符串
+	scope := dateStamp + "/" + ss.options.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	// 派生签名密钥
+	signingKey := hmacSHA256([]byte("AWS4"+ss.options.SecretKey), dateStamp)
+	signingKey = hmacSHA256(signingKey, ss.options.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		ss.options.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalQueryString 构建SigV4规范查询字符串
+func canonicalQueryString(req *http.Request) string {
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, awsURIEncode(key, true)+"="+awsURIEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
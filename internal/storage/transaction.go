@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"fmt"
+
+	"mock-storage/internal/types"
+)
+
+// NodeTransaction 记录一次上传、删除或分层迁移中对存储节点的修改，失败时按相反顺序撤销
+// 每个节点在第一次修改某个key之前保存其原有数据（不存在时为nil），
+// 回滚时写回原有数据或删除新写入的数据
+type NodeTransaction struct {
+	sm      *Manager
+	journal []nodeChange
+	seen    map[string]bool // nodeID + key，同一节点上的同一key只记录第一次修改前的数据
+}
+
+// nodeChange 节点上一个key被修改前的状态
+type nodeChange struct {
+	node     types.StorageNode
+	key      string
+	previous *types.FileObject
+}
+
+// BeginNodeTransaction 开始记录节点修改
+func (sm *Manager) BeginNodeTransaction() *NodeTransaction {
+	return &NodeTransaction{
+		sm:   sm,
+		seen: make(map[string]bool),
+	}
+}
+
+// WriteStored 顺序写入PrepareStored生成的节点数据，写入前记录各节点上的原有数据
+func (nt *NodeTransaction) WriteStored(stored *types.FileObject, nodeIDs []string) ([]string, error) {
+	return nt.sm.writeToNodes(stored, nodeIDs, nt)
+}
+
+// CopyToClass 将对象在fromNodes上存储的原始数据复制到目标存储类别的节点组，返回写入成功的节点ID
+// 复制的是原始数据（加密对象保持密文），写入前记录各节点上的原有数据；旧副本由调用方在元数据提交后删除
+func (nt *NodeTransaction) CopyToClass(key string, fromNodes []string, toClass string) ([]string, error) {
+	if !nt.sm.HasStorageClass(toClass) {
+		return nil, fmt.Errorf("unknown storage class: %s", toClass)
+	}
+
+	stored, err := nt.sm.ReadFromLocalNodes(key, fromNodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object for migration: %v", err)
+	}
+
+	written, err := nt.sm.writeToNodes(stored, nt.sm.NodeIDsForClass(toClass), nt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write object to %s nodes: %v", toClass, err)
+	}
+	return written, nil
+}
+
+// DeleteFromNodes 从指定节点删除key，任一节点删除失败即返回错误
+// 与Manager.DeleteFromNodes不同，删除前会读取并保存数据以便回滚
+func (nt *NodeTransaction) DeleteFromNodes(key string, nodeIDs []string) error {
+	for _, nodeID := range nodeIDs {
+		node := nt.sm.getNode(nodeID)
+		if node == nil {
+			return fmt.Errorf("unknown storage node: %s", nodeID)
+		}
+
+		recorded := nt.recordBefore(node, key)
+		if err := node.Delete(key); err != nil {
+			// 删除失败时节点上的数据没有变化，回滚时无需写回
+			if recorded {
+				nt.journal = nt.journal[:len(nt.journal)-1]
+				delete(nt.seen, changeID(node, key))
+			}
+			return fmt.Errorf("failed to delete %s from node %s: %v", key, nodeID, err)
+		}
+	}
+
+	nt.sm.InvalidateCache(key)
+	return nil
+}
+
+// recordBefore 在节点上的key第一次被修改前保存其原有数据，返回是否新增了记录
+func (nt *NodeTransaction) recordBefore(node types.StorageNode, key string) bool {
+	id := changeID(node, key)
+	if nt.seen[id] {
+		return false
+	}
+	nt.seen[id] = true
+
+	// 读取失败视为原来不存在，回滚时删除
+	previous, err := node.Read(key)
+	if err != nil {
+		previous = nil
+	}
+	nt.journal = append(nt.journal, nodeChange{node: node, key: key, previous: previous})
+	return true
+}
+
+// changeID 节点上key的唯一标识
+func changeID(node types.StorageNode, key string) string {
+	return node.GetNodeID() + "\x00" + key
+}
+
+// Rollback 按相反顺序撤销所有修改，返回最后一个撤销失败的错误
+func (nt *NodeTransaction) Rollback() error {
+	var lastErr error
+	for i := len(nt.journal) - 1; i >= 0; i-- {
+		change := nt.journal[i]
+
+		var err error
+		if change.previous != nil {
+			err = change.node.Write(change.previous)
+		} else {
+			err = change.node.Delete(change.key)
+		}
+		if err != nil {
+			fmt.Printf("Warning: failed to roll back %s on node %s: %v\n", change.key, change.node.GetNodeID(), err)
+			lastErr = err
+		}
+		nt.sm.InvalidateCache(change.key)
+	}
+
+	if len(nt.journal) > 0 {
+		fmt.Printf("Rolled back %d storage node changes\n", len(nt.journal))
+	}
+	nt.journal = nil
+	return lastErr
+}
+
+// Commit 确认所有修改，丢弃保存的原有数据
+func (nt *NodeTransaction) Commit() {
+	nt.journal = nil
+	nt.seen = make(map[string]bool)
+}
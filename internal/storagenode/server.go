@@ -0,0 +1,184 @@
+package storagenode
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mock-storage/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server 存储节点HTTP服务，将本地FileStorageNode暴露给API服务器
+//
+// 协议：
+//
+//	PUT    /objects/{key}  流式上传，X-Object-MD5 可选，不一致时返回400
+//	GET    /objects/{key}  流式下载，数据之后以trailer发送 X-Object-MD5
+//	HEAD   /objects/{key}  获取大小和修改时间
+//	DELETE /objects/{key}  删除对象，不存在也返回204
+//	GET    /health         健康检查
+type Server struct {
+	node  *storage.FileStorageNode
+	token string
+}
+
+// NewServer 创建存储节点服务，token非空时要求请求携带相同的X-Node-Token
+func NewServer(node *storage.FileStorageNode, token string) *Server {
+	return &Server{
+		node:  node,
+		token: token,
+	}
+}
+
+// SetupRoutes 设置路由
+func (s *Server) SetupRoutes(router *gin.Engine) {
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ok",
+			"node_id": s.node.GetNodeID(),
+			"time":    time.Now().Format(time.RFC3339),
+		})
+	})
+
+	objects := router.Group("/objects", s.authenticate)
+	{
+		objects.PUT("/*key", s.putObject)
+		objects.GET("/*key", s.getObject)
+		objects.HEAD("/*key", s.headObject)
+		objects.DELETE("/*key", s.deleteObject)
+	}
+}
+
+// authenticate 校验节点令牌
+func (s *Server) authenticate(c *gin.Context) {
+	if s.token == "" {
+		c.Next()
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader(storage.HeaderNodeToken)), []byte(s.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid node token"})
+		return
+	}
+	c.Next()
+}
+
+// putObject 流式写入对象
+func (s *Server) putObject(c *gin.Context) {
+	key, ok := objectKey(c)
+	if !ok {
+		return
+	}
+
+	md5Hash, size, err := s.node.WriteStream(key, c.Request.Body, c.GetHeader(storage.HeaderObjectMD5))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrChecksumMismatch) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header(storage.HeaderObjectMD5, md5Hash)
+	c.JSON(http.StatusCreated, gin.H{"key": key, "size": size, "md5_hash": md5Hash})
+}
+
+// getObject 流式读取对象，MD5在数据发送完后通过trailer返回
+func (s *Server) getObject(c *gin.Context) {
+	key, ok := objectKey(c)
+	if !ok {
+		return
+	}
+
+	file, fileInfo, err := s.node.Open(key)
+	if err != nil {
+		c.JSON(statusForError(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	// 不设置Content-Length，使用分块传输以便在数据之后发送trailer
+	c.Header("Trailer", storage.HeaderObjectMD5)
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Last-Modified", fileInfo.ModTime().UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(c.Writer, hash), file); err != nil {
+		// 已经开始发送数据，只能中断连接，客户端会发现长度或校验和不一致
+		fmt.Printf("[%s] Failed to stream %s: %v\n", s.node.GetNodeID(), key, err)
+		return
+	}
+
+	c.Writer.Header().Set(storage.HeaderObjectMD5, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// headObject 获取对象大小和修改时间
+func (s *Server) headObject(c *gin.Context) {
+	key, ok := objectKey(c)
+	if !ok {
+		return
+	}
+
+	obj, err := s.node.Stat(key)
+	if err != nil {
+		c.Status(statusForError(err))
+		return
+	}
+
+	c.Header("Content-Length", strconv.FormatInt(obj.Size, 10))
+	c.Header("Last-Modified", obj.CreatedAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+// deleteObject 删除对象
+func (s *Server) deleteObject(c *gin.Context) {
+	key, ok := objectKey(c)
+	if !ok {
+		return
+	}
+
+	if err := s.node.Delete(key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// objectKey 从路径中提取对象key，拒绝可能越出节点目录的key
+func objectKey(c *gin.Context) (string, bool) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	valid := key != ""
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			valid = false
+			break
+		}
+	}
+
+	if !valid {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid object key"})
+		return "", false
+	}
+	return key, true
+}
+
+// statusForError 将节点错误映射为HTTP状态码
+func statusForError(err error) int {
+	if strings.Contains(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
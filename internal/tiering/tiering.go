@@ -0,0 +1,237 @@
+package tiering
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+)
+
+// 分层规则的条件类型
+const (
+	ConditionAge  = "age"  // 按创建时间
+	ConditionIdle = "idle" // 按最后访问时间（从未访问过时按创建时间）
+)
+
+// MetadataService 元数据服务接口（避免循环依赖）
+type MetadataService interface {
+	ListMetadata(limit, offset int) ([]*types.MetadataEntry, error)
+}
+
+// StorageManager 存储管理器接口
+type StorageManager interface {
+	HasStorageClass(class string) bool
+}
+
+// ObjectMover 迁移对象的存储类别（由S3服务实现）
+// 迁移与同一对象的上传和删除互斥，复制、更新元数据和删除旧副本作为一次操作执行
+type ObjectMover interface {
+	MoveStorageClass(entry *types.MetadataEntry, toClass string) error
+}
+
+// Rule 分层规则：满足条件的对象从From类别迁移到To类别
+type Rule struct {
+	From      string
+	To        string
+	Prefix    string            // 只匹配以该前缀开头的key（包含bucket）
+	Tags      map[string]string // 只匹配带有全部这些标签（名称和值都相同）的对象
+	Condition string
+	Days      int
+}
+
+// RunResult 一次分层执行的结果
+type RunResult struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Scanned    int       `json:"scanned"`
+	Moved      int       `json:"moved"`
+	Failed     int       `json:"failed"`
+	Errors     []string  `json:"errors,omitempty"`
+}
+
+// 单次扫描的分页大小，以及结果中最多保留的错误条数
+const (
+	scanPageSize   = 500
+	maxResultError = 20
+)
+
+// Tierer 后台分层任务，定期按规则在存储类别之间迁移对象
+type Tierer struct {
+	metadata MetadataService
+	mover    ObjectMover
+	rules    []Rule
+	interval time.Duration
+
+	mutex   sync.Mutex
+	runMu   sync.Mutex
+	running bool
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+	lastRun *RunResult
+}
+
+// NewTierer 创建分层任务
+func NewTierer(metadata MetadataService, storage StorageManager, mover ObjectMover, rules []Rule, interval time.Duration) (*Tierer, error) {
+	for i, rule := range rules {
+		if !storage.HasStorageClass(rule.From) || !storage.HasStorageClass(rule.To) {
+			return nil, fmt.Errorf("tiering rule %d references unknown storage class (%s -> %s)", i, rule.From, rule.To)
+		}
+		if rule.From == rule.To {
+			return nil, fmt.Errorf("tiering rule %d moves objects to the same class %s", i, rule.From)
+		}
+		if rule.Condition != ConditionAge && rule.Condition != ConditionIdle {
+			return nil, fmt.Errorf("tiering rule %d has unsupported condition %q", i, rule.Condition)
+		}
+	}
+
+	return &Tierer{
+		metadata: metadata,
+		mover:    mover,
+		rules:    rules,
+		interval: interval,
+	}, nil
+}
+
+// Start 启动后台分层循环
+func (t *Tierer) Start() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.running {
+		return
+	}
+
+	t.running = true
+	t.stopCh = make(chan struct{})
+	t.doneCh = make(chan struct{})
+
+	go t.loop(t.stopCh, t.doneCh)
+	fmt.Printf("[TIERING] Tiering started with %d rules (interval: %v)\n", len(t.rules), t.interval)
+}
+
+// Stop 停止后台分层循环
+func (t *Tierer) Stop() {
+	t.mutex.Lock()
+	if !t.running {
+		t.mutex.Unlock()
+		return
+	}
+	t.running = false
+	close(t.stopCh)
+	doneCh := t.doneCh
+	t.mutex.Unlock()
+
+	<-doneCh
+	fmt.Println("[TIERING] Tiering stopped")
+}
+
+// loop 分层循环
+func (t *Tierer) loop(stopCh, doneCh chan struct{}) {
+	defer close(doneCh)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			t.RunOnce()
+		}
+	}
+}
+
+// RunOnce 执行一次完整扫描，同一时间只允许一次执行
+func (t *Tierer) RunOnce() *RunResult {
+	t.runMu.Lock()
+	defer t.runMu.Unlock()
+
+	result := &RunResult{StartedAt: time.Now()}
+
+	for offset := 0; ; offset += scanPageSize {
+		entries, err := t.metadata.ListMetadata(scanPageSize, offset)
+		if err != nil {
+			result.addError(fmt.Sprintf("failed to list metadata: %v", err))
+			break
+		}
+
+		for _, entry := range entries {
+			result.Scanned++
+
+			rule := t.matchRule(entry, result.StartedAt)
+			if rule == nil {
+				continue
+			}
+
+			err := t.mover.MoveStorageClass(entry, rule.To)
+			if err != nil {
+				result.Failed++
+				result.addError(fmt.Sprintf("%s: %v", entry.Key, err))
+				continue
+			}
+			result.Moved++
+		}
+
+		if len(entries) < scanPageSize {
+			break
+		}
+	}
+
+	result.FinishedAt = time.Now()
+	fmt.Printf("[TIERING] Run finished: scanned %d, moved %d, failed %d\n", result.Scanned, result.Moved, result.Failed)
+
+	t.mutex.Lock()
+	t.lastRun = result
+	t.mutex.Unlock()
+
+	return result
+}
+
+// matchRule 获取对象匹配的第一条规则
+func (t *Tierer) matchRule(entry *types.MetadataEntry, now time.Time) *Rule {
+	for i := range t.rules {
+		rule := &t.rules[i]
+		if entry.StorageClass != rule.From {
+			continue
+		}
+		if rule.Prefix != "" && !strings.HasPrefix(entry.Key, rule.Prefix) {
+			continue
+		}
+		if !types.MatchTags(entry.Tags, rule.Tags) {
+			continue
+		}
+
+		reference := entry.CreatedAt
+		if rule.Condition == ConditionIdle && !entry.LastAccessedAt.IsZero() {
+			reference = entry.LastAccessedAt
+		}
+
+		if now.Sub(reference) >= time.Duration(rule.Days)*24*time.Hour {
+			return rule
+		}
+	}
+	return nil
+}
+
+// GetStatus 获取分层任务状态
+func (t *Tierer) GetStatus() map[string]any {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return map[string]any{
+		"running":  t.running,
+		"interval": t.interval.String(),
+		"rules":    len(t.rules),
+		"last_run": t.lastRun,
+	}
+}
+
+// addError 记录错误，超过上限的错误只计数不保留
+func (r *RunResult) addError(message string) {
+	if len(r.Errors) < maxResultError {
+		r.Errors = append(r.Errors, message)
+	}
+}
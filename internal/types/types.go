@@ -1,6 +1,10 @@
 package types
 
 import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -13,6 +17,39 @@ type FileObject struct {
 	MD5Hash     string    `json:"md5_hash"`
 	Data        []byte    `json:"-"` // 文件数据，不序列化到JSON
 	CreatedAt   time.Time `json:"created_at"`
+
+	// 来自源站的对象保留源站的ETag和最后修改时间
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+
+	// 服务端加密信息
+	Encryption       string `json:"encryption,omitempty"`         // 加密模式：""、SSE-S3、SSE-C
+	EncryptedDataKey string `json:"-"`                            // 被包装后的数据密钥（base64）
+	EncryptionKeyMD5 string `json:"encryption_key_md5,omitempty"` // SSE-C客户密钥的MD5（base64）
+	CustomerKey      []byte `json:"-"`                            // SSE-C客户密钥，仅在请求处理期间存在
+
+	SyncStatus   string `json:"sync_status,omitempty"`   // 源站同步状态
+	StorageClass string `json:"storage_class,omitempty"` // 存储类别
+
+	Checksums map[string]string `json:"checksums,omitempty"` // 附加校验和：算法 -> base64值（基于明文）
+
+	ObjectHeaders
+
+	VersionID string `json:"version_id,omitempty"` // 版本ID，bucket未启用版本控制时为空
+
+	Tags map[string]string `json:"-"` // 对象标签，只保存在元数据中
+}
+
+// ObjectHeaders 上传时保存、GET/HEAD时原样返回的对象头
+type ObjectHeaders struct {
+	CacheControl       string `json:"cache_control,omitempty" db:"cache_control"`
+	ContentDisposition string `json:"content_disposition,omitempty" db:"content_disposition"`
+	ContentEncoding    string `json:"content_encoding,omitempty" db:"content_encoding"`
+	ContentLanguage    string `json:"content_language,omitempty" db:"content_language"`
+	Expires            string `json:"expires,omitempty" db:"expires"` // 按请求中的原始格式保存
+
+	// 用户自定义元数据（x-amz-meta-*）：小写名称（不含前缀） -> 值
+	UserMetadata map[string]string `json:"user_metadata,omitempty" db:"user_metadata"`
 }
 
 // MetadataEntry 元数据条目
@@ -25,6 +62,162 @@ type MetadataEntry struct {
 	StorageNodes []string  `json:"storage_nodes" db:"storage_nodes"` // 存储节点列表
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ETag         string    `json:"etag,omitempty" db:"etag"` // 源站ETag，为空时使用MD5
+
+	// 服务端加密信息
+	Encryption       string `json:"encryption,omitempty" db:"encryption"`
+	EncryptedDataKey string `json:"encrypted_data_key,omitempty" db:"encrypted_data_key"`
+	EncryptionKeyMD5 string `json:"encryption_key_md5,omitempty" db:"encryption_key_md5"`
+
+	// 源站同步信息（write-through/write-back模式）
+	SyncStatus string `json:"sync_status,omitempty" db:"sync_status"`
+	SyncError  string `json:"sync_error,omitempty" db:"sync_error"`
+
+	// 存储分层信息
+	StorageClass   string    `json:"storage_class" db:"storage_class"`
+	LastAccessedAt time.Time `json:"last_accessed_at,omitempty" db:"last_accessed_at"`
+
+	// 附加校验和（CRC32、CRC32C、SHA1、SHA256）：算法 -> base64值
+	Checksums map[string]string `json:"checksums,omitempty" db:"checksums"`
+
+	// 标准HTTP头与用户自定义元数据
+	ObjectHeaders
+
+	// 版本控制信息，未启用版本控制的bucket中的对象版本ID为空
+	VersionID    string `json:"version_id,omitempty" db:"version_id"`
+	DeleteMarker bool   `json:"delete_marker,omitempty" db:"delete_marker"` // 删除标记，只存在于版本历史中
+
+	// 对象标签：标签名 -> 值，当前版本保存在object_tags表中，历史版本随版本记录保存
+	Tags map[string]string `json:"tags,omitempty" db:"-"`
+}
+
+// 存储类别
+const (
+	StorageClassStandard   = "STANDARD"
+	StorageClassInfrequent = "INFREQUENT"
+	StorageClassCold       = "COLD"
+)
+
+// 源站同步状态
+const (
+	SyncStatusNone    = ""        // 未配置源站写入
+	SyncStatusPending = "pending" // 等待异步写入源站
+	SyncStatusSynced  = "synced"  // 已写入源站
+	SyncStatusFailed  = "failed"  // 写入源站失败
+)
+
+// 服务端加密模式
+const (
+	EncryptionNone  = ""
+	EncryptionSSES3 = "SSE-S3"
+	EncryptionSSEC  = "SSE-C"
+)
+
+// bucket版本控制状态（与S3 VersioningConfiguration.Status取值一致）
+const (
+	VersioningUnversioned = "" // 从未启用过版本控制
+	VersioningEnabled     = "Enabled"
+	VersioningSuspended   = "Suspended"
+)
+
+// NullVersionID 未启用或暂停版本控制时写入的对象的版本ID
+const NullVersionID = "null"
+
+// versionKeySeparator 对象版本在存储节点上的key分隔符
+const versionKeySeparator = "#"
+
+// ErrInvalidObjectKey 对象key包含版本分隔符，会与其他对象版本在存储节点上的key冲突
+var ErrInvalidObjectKey = errors.New("object key must not contain " + versionKeySeparator)
+
+// ValidateObjectKey 检查对象key不包含版本分隔符，写入和删除对象前调用
+func ValidateObjectKey(key string) error {
+	if strings.Contains(key, versionKeySeparator) {
+		return fmt.Errorf("%w: %s", ErrInvalidObjectKey, key)
+	}
+	return nil
+}
+
+// StorageKey 获取对象版本在存储节点上的key
+// null版本使用对象key本身（被同一key的null版本覆盖），其余版本各自保存
+func StorageKey(key, versionID string) string {
+	if versionID == "" || versionID == NullVersionID {
+		return key
+	}
+	return key + versionKeySeparator + versionID
+}
+
+// NormalizeVersionID 将空版本ID（未启用版本控制时写入的对象）视为null版本
+func NormalizeVersionID(versionID string) string {
+	if versionID == "" {
+		return NullVersionID
+	}
+	return versionID
+}
+
+// VersioningConfiguration bucket版本控制配置（S3 PutBucketVersioning请求体）
+type VersioningConfiguration struct {
+	XMLName xml.Name `xml:"VersioningConfiguration" json:"-"`
+	Status  string   `xml:"Status,omitempty" json:"status,omitempty"`
+}
+
+// Tagging 对象标签集合（S3 PutObjectTagging请求体和GetObjectTagging响应）
+type Tagging struct {
+	XMLName xml.Name `xml:"Tagging" json:"-"`
+	TagSet  []Tag    `xml:"TagSet>Tag" json:"tag_set"`
+}
+
+// Tag 单个对象标签
+type Tag struct {
+	Key   string `xml:"Key" json:"key"`
+	Value string `xml:"Value" json:"value"`
+}
+
+// S3对象标签限制
+const (
+	MaxObjectTags     = 10  // 每个对象最多10个标签
+	MaxTagKeyLength   = 128 // 标签名最多128个字符（Unicode）
+	MaxTagValueLength = 256 // 标签值最多256个字符（Unicode）
+)
+
+// MatchTags 判断标签是否包含所有要求的标签名和值，required为空时总是匹配
+// 元数据搜索的标签过滤和分层规则的标签条件使用同一判断
+func MatchTags(tags, required map[string]string) bool {
+	for name, value := range required {
+		if actual, ok := tags[name]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ListVersionsResult S3 ListObjectVersions响应
+type ListVersionsResult struct {
+	XMLName       xml.Name            `xml:"ListVersionsResult"`
+	Name          string              `xml:"Name"`
+	Prefix        string              `xml:"Prefix"`
+	MaxKeys       int                 `xml:"MaxKeys"`
+	IsTruncated   bool                `xml:"IsTruncated"`
+	Versions      []ObjectVersion     `xml:"Version"`
+	DeleteMarkers []DeleteMarkerEntry `xml:"DeleteMarker"`
+}
+
+// ObjectVersion ListObjectVersions中的对象版本
+type ObjectVersion struct {
+	Key          string `xml:"Key"`
+	VersionID    string `xml:"VersionId"`
+	IsLatest     bool   `xml:"IsLatest"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// DeleteMarkerEntry ListObjectVersions中的删除标记
+type DeleteMarkerEntry struct {
+	Key          string `xml:"Key"`
+	VersionID    string `xml:"VersionId"`
+	IsLatest     bool   `xml:"IsLatest"`
+	LastModified string `xml:"LastModified"`
 }
 
 // StorageNode 存储节点接口
@@ -32,9 +225,156 @@ type StorageNode interface {
 	Write(obj *FileObject) error
 	Read(key string) (*FileObject, error)
 	Delete(key string) error
+	Stat(key string) (*FileObject, error) // 获取对象大小和修改时间，不读取数据
 	GetNodeID() string
 }
 
+// 副本校验状态
+const (
+	ReplicaHealthy      = "healthy"      // 数据与校验和一致
+	ReplicaCorrupt      = "corrupt"      // 数据与校验和不一致
+	ReplicaMissing      = "missing"      // 节点上不存在或读取失败
+	ReplicaUnverifiable = "unverifiable" // SSE-C对象没有客户密钥，无法校验
+	ReplicaRepaired     = "repaired"     // 已用健康副本修复
+)
+
+// ScrubResult 对象副本校验结果
+type ScrubResult struct {
+	Key       string            `json:"key"`
+	Algorithm string            `json:"algorithm"` // 用于校验的算法
+	Replicas  map[string]string `json:"replicas"`  // 节点ID -> 副本状态
+	Errors    map[string]string `json:"errors,omitempty"`
+	Healthy   bool              `json:"healthy"` // 校验（及修复）后所有副本均可用
+}
+
+// 元数据搜索的排序字段
+const (
+	SearchSortKey     = "key"
+	SearchSortSize    = "size"
+	SearchSortCreated = "created"
+	SearchSortUpdated = "updated"
+)
+
+// SearchQuery 元数据搜索条件，零值字段不参与过滤
+type SearchQuery struct {
+	Text         string            // 全文检索key、内容类型和用户元数据，多个词需同时匹配
+	KeyPrefix    string            // 完整key（bucket/key）前缀
+	KeyPattern   string            // 完整key的glob模式：* 任意字符，? 单个字符，[abc] 字符集
+	ContentType  string            // 不区分大小写精确匹配，以/*结尾时只匹配主类型（如image/*）
+	StorageClass string            // 存储类别
+	MinSize      *int64            // 最小大小（字节，含）
+	MaxSize      *int64            // 最大大小（字节，含）
+	UserMetadata map[string]string // 需要与用户元数据精确匹配的名称和值
+	Tags         map[string]string // 需要与对象标签精确匹配的标签名和值
+
+	CreatedAfter  time.Time // 创建时间范围（含）
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time // 更新时间范围（含）
+	UpdatedBefore time.Time
+
+	SortBy     string // key、size、created（默认）或updated
+	Descending bool
+	Limit      int
+	Cursor     string // 上一页返回的游标
+}
+
+// SearchResult 元数据搜索结果
+type SearchResult struct {
+	Entries    []*MetadataEntry `json:"results"`
+	NextCursor string           `json:"next_cursor,omitempty"` // 还有更多结果时用于获取下一页
+}
+
+// 元数据导入时key已存在的处理策略
+const (
+	ImportConflictSkip      = "skip"       // 保留已有元数据
+	ImportConflictOverwrite = "overwrite"  // 用导入的元数据覆盖
+	ImportConflictNewerWins = "newer-wins" // 导入的更新时间较新时覆盖
+)
+
+// CatalogFilter 元数据导出和导入的过滤条件，零值字段不参与过滤
+type CatalogFilter struct {
+	Bucket        string
+	Prefix        string    // bucket内的key前缀，未指定bucket时为完整key（bucket/key）前缀
+	UpdatedAfter  time.Time // 更新时间范围（含）
+	UpdatedBefore time.Time
+}
+
+// ImportOptions 元数据导入选项
+type ImportOptions struct {
+	Filter   CatalogFilter
+	Conflict string   // skip（默认）、overwrite或newer-wins
+	DryRun   bool     // 只检查并统计，不写入
+	Nodes    []string // 已配置的存储节点ID，非空时引用其他节点的记录导入失败
+}
+
+// ImportReport 元数据导入结果
+type ImportReport struct {
+	DryRun   bool           `json:"dry_run"`
+	Conflict string         `json:"conflict"`
+	Total    int            `json:"total"`    // 读取的记录数
+	Imported int            `json:"imported"` // 写入（dry-run时为将会写入）的记录数
+	Skipped  int            `json:"skipped"`
+	Failed   int            `json:"failed"`
+	Reasons  map[string]int `json:"reasons"` // 跳过和失败的原因及次数
+	Issues   []ImportIssue  `json:"issues"`  // 跳过和失败的记录明细，最多保留MaxImportIssues条
+}
+
+// MaxImportIssues 导入结果中保留的明细数量上限
+const MaxImportIssues = 1000
+
+// ImportIssue 导入时被跳过或失败的记录
+type ImportIssue struct {
+	Line   int    `json:"line"` // 在NDJSON中的行号（从1开始）
+	Key    string `json:"key,omitempty"`
+	Result string `json:"result"` // skipped或failed
+	Reason string `json:"reason"`
+}
+
+// FaultRule 单个操作的故障注入规则
+type FaultRule struct {
+	LatencyMs   int     `json:"latency_ms"`   // 每次操作额外增加的延迟（毫秒）
+	ErrorRate   float64 `json:"error_rate"`   // 返回错误的概率（0-1）
+	CorruptRate float64 `json:"corrupt_rate"` // 读写数据被篡改一个字节的概率（0-1）
+}
+
+// Validate 校验延迟不为负数、概率在0-1之间
+func (r FaultRule) Validate() error {
+	if r.LatencyMs < 0 {
+		return fmt.Errorf("latency_ms must not be negative, got %d", r.LatencyMs)
+	}
+	if r.ErrorRate < 0 || r.ErrorRate > 1 {
+		return fmt.Errorf("error_rate must be between 0 and 1, got %v", r.ErrorRate)
+	}
+	if r.CorruptRate < 0 || r.CorruptRate > 1 {
+		return fmt.Errorf("corrupt_rate must be between 0 and 1, got %v", r.CorruptRate)
+	}
+	return nil
+}
+
+// FaultConfig 存储节点的故障注入配置
+type FaultConfig struct {
+	ReadOnly bool      `json:"read_only"` // 写入和删除返回只读文件系统错误
+	DiskFull bool      `json:"disk_full"` // 写入返回磁盘已满错误
+	Read     FaultRule `json:"read"`
+	Write    FaultRule `json:"write"`
+	Delete   FaultRule `json:"delete"`
+}
+
+// Validate 校验各操作的故障规则
+func (c FaultConfig) Validate() error {
+	rules := []struct {
+		op   string
+		rule FaultRule
+	}{{"read", c.Read}, {"write", c.Write}, {"delete", c.Delete}}
+
+	for _, r := range rules {
+		if err := r.rule.Validate(); err != nil {
+			return fmt.Errorf("invalid %s fault: %v", r.op, err)
+		}
+	}
+	return nil
+}
+
 // UploadRequest 上传请求
 type UploadRequest struct {
 	Key         string `json:"key"`
@@ -52,15 +392,96 @@ type UploadResponse struct {
 	MD5Hash  string `json:"md5_hash,omitempty"`
 }
 
+// 队列任务状态
+const (
+	TaskStatusQueued    = "queued"    // 等待工作节点领取
+	TaskStatusRunning   = "running"   // 已被工作节点租用，租约到期前其他节点不会领取
+	TaskStatusSucceeded = "succeeded" // 处理成功，保留到超过保留时间后删除
+	TaskStatusFailed    = "failed"    // 上一次处理失败，等待到not_before后重试
+	TaskStatusDead      = "dead"      // 重试次数用尽或无法处理，进入死信队列等待人工处理
+)
+
+// TaskFinished 任务是否已结束（成功或进入死信队列），结束的任务不会再被处理
+func TaskFinished(status string) bool {
+	return status == TaskStatusSucceeded || status == TaskStatusDead
+}
+
 // TaskMessage 队列任务消息
 type TaskMessage struct {
+	ID        string         `json:"id"` // 入队时分配，用于确认和续租
 	Type      string         `json:"type"`
 	ObjectID  string         `json:"object_id"`
 	Data      map[string]any `json:"data"`
 	CreatedAt time.Time      `json:"created_at"`
+	NotBefore *time.Time     `json:"not_before,omitempty"` // 设置后任务在该时间之前不会被领取；等待重试时为下次重试的时间
+
+	Status     string     `json:"status,omitempty"`      // 从队列存储读取时填充
+	Attempts   int        `json:"attempts"`              // 已失败的处理次数
+	LastError  string     `json:"last_error,omitempty"`  // 最近一次失败的错误
+	Worker     string     `json:"worker,omitempty"`      // 最近一次领取任务的工作节点
+	StartedAt  *time.Time `json:"started_at,omitempty"`  // 最近一次开始处理的时间
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // 处理成功或进入死信队列的时间
+	UpdatedAt  time.Time  `json:"updated_at"`            // 最近一次状态变化的时间
+}
+
+// TaskFilter 查询队列任务的条件，字段为空时不限制
+type TaskFilter struct {
+	Status      string
+	Type        string
+	ObjectID    string
+	NewestFirst bool // 按入队时间倒序，默认正序
+	Limit       int
+	Offset      int
+}
+
+// 队列任务类型
+const (
+	TaskTypeUploadCompleted   = "upload_completed"
+	TaskTypeCleanup           = "cleanup"             // 指定对象时清理该对象，否则清理残留的临时文件
+	TaskTypeReplicationCheck  = "replication_check"   // 校验并修复单个对象的副本
+	TaskTypeScrub             = "scrub"               // 校验并修复所有对象的副本
+	TaskTypeDeleteFromStorage = "delete_from_storage" // 从所有存储节点删除文件
+	TaskTypeOriginSync        = "origin_sync"         // 将对象同步到源站（write-back）
+	TaskTypeOrphanSweep       = "orphan_sweep"        // 删除存储节点上没有元数据引用的对象文件
+)
+
+// ObjectTaskPayload 针对单个对象的任务数据（replication_check、delete_from_storage）
+type ObjectTaskPayload struct {
+	Key string `json:"key"`
+}
+
+// Validate 校验任务数据
+func (p *ObjectTaskPayload) Validate() error {
+	if p.Key == "" {
+		return errors.New("key is required")
+	}
+	return nil
+}
+
+// OriginSyncPayload 源站同步任务数据
+type OriginSyncPayload struct {
+	Key       string `json:"key"`
+	Operation string `json:"operation"` // put 或 delete
+}
+
+// Validate 校验任务数据
+func (p *OriginSyncPayload) Validate() error {
+	if p.Key == "" {
+		return errors.New("key is required")
+	}
+	if p.Operation == "" {
+		return errors.New("operation is required")
+	}
+	return nil
+}
+
+// CleanupPayload 清理任务数据
+type CleanupPayload struct {
+	OlderThan int `json:"older_than"` // 清理临时文件时，只删除残留超过该秒数的文件
 }
 
-// TaskProcessor 任务处理器接口
-type TaskProcessor interface {
-	Process(task *TaskMessage) error
+// OrphanSweepPayload 孤立文件清理任务数据
+type OrphanSweepPayload struct {
+	OlderThan int  `json:"older_than"` // 只检查修改时间早于该秒数之前的文件，跳过正在写入、元数据尚未提交的对象
+	DryRun    bool `json:"dry_run"`    // 只记录孤立文件，不删除
 }
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"strings"
+)
+
+// 校验和算法（与S3 x-amz-checksum-algorithm取值一致）
+const (
+	ChecksumCRC32  = "CRC32"
+	ChecksumCRC32C = "CRC32C"
+	ChecksumSHA1   = "SHA1"
+	ChecksumSHA256 = "SHA256"
+	ChecksumMD5    = "MD5" // 仅用于校验，对象总是保存MD5
+)
+
+// ChecksumAlgorithms 支持的附加校验和算法，按强度从高到低排列
+var ChecksumAlgorithms = []string{ChecksumSHA256, ChecksumSHA1, ChecksumCRC32C, ChecksumCRC32}
+
+// IsChecksumAlgorithm 检查是否为支持的附加校验和算法
+func IsChecksumAlgorithm(algorithm string) bool {
+	for _, supported := range ChecksumAlgorithms {
+		if supported == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+// CalculateChecksum 计算数据的校验和，返回base64编码（CRC为大端序4字节，与S3一致）
+func CalculateChecksum(algorithm string, data []byte) (string, error) {
+	var h hash.Hash
+	switch strings.ToUpper(algorithm) {
+	case ChecksumCRC32:
+		h = crc32.NewIEEE()
+	case ChecksumCRC32C:
+		h = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case ChecksumSHA1:
+		h = sha1.New()
+	case ChecksumSHA256:
+		h = sha256.New()
+	case ChecksumMD5:
+		h = md5.New()
+	default:
+		return "", fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+
+	h.Write(data)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// StrongestChecksum 从已有校验和中选择最强的算法
+func StrongestChecksum(checksums map[string]string) (string, string, bool) {
+	for _, algorithm := range ChecksumAlgorithms {
+		if value, ok := checksums[algorithm]; ok && value != "" {
+			return algorithm, value, true
+		}
+	}
+	return "", "", false
+}
@@ -9,4 +9,4 @@ import (
 func CalculateMD5(data []byte) string {
 	hash := md5.Sum(data)
 	return hex.EncodeToString(hash[:])
-}
\ No newline at end of file
+}
@@ -0,0 +1,478 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mock-storage/internal/types"
+
+	"github.com/google/uuid"
+)
+
+// 记录类型
+const (
+	RecordIntent = "intent" // 操作开始前写入，fsync后才修改存储节点和元数据
+	RecordCommit = "commit" // 操作完成
+	RecordAbort  = "abort"  // 操作失败且已回滚，或恢复时确认无需处理
+)
+
+// 操作类型
+const (
+	OpPut    = "put"
+	OpDelete = "delete"
+	OpRepair = "repair"
+)
+
+// 段文件命名：wal-<段内第一条记录的序号>.log
+const (
+	segmentPrefix = "wal-"
+	segmentSuffix = ".log"
+
+	// 每条记录的头部：4字节长度 + 4字节CRC32
+	recordHeaderSize = 8
+	maxRecordSize    = 64 << 20
+)
+
+// DefaultSegmentSize 默认段文件大小，超过后切换到新段
+const DefaultSegmentSize = 16 << 20
+
+// Record 操作日志记录
+type Record struct {
+	Seq   uint64               `json:"seq"`
+	Type  string               `json:"type"`
+	TxID  string               `json:"tx_id"`
+	Op    string               `json:"op,omitempty"`
+	Key   string               `json:"key,omitempty"`
+	Entry *types.MetadataEntry `json:"entry,omitempty"` // 操作涉及的元数据，恢复时用于完成或撤销操作
+	Data  map[string]string    `json:"data,omitempty"`  // 操作相关的附加参数
+	Error string               `json:"error,omitempty"` // abort原因
+	Time  time.Time            `json:"time"`
+}
+
+// SegmentInfo 段文件信息
+type SegmentInfo struct {
+	Path     string `json:"path"`
+	FirstSeq uint64 `json:"first_seq"`
+	Size     int64  `json:"size"`
+	Records  int    `json:"records"`
+	Pending  int    `json:"pending"` // 段中尚未完成的操作数
+}
+
+// Log 追加写入的操作日志（WAL）
+// 意图记录写入并fsync后才返回，保证崩溃后重启时能找到所有未完成的操作；
+// 提交和中止记录不单独fsync，丢失时恢复流程会重新检查操作是否已经完成
+type Log struct {
+	mu          sync.Mutex
+	dir         string
+	segmentSize int64
+
+	file     *os.File
+	writer   *bufio.Writer
+	segment  uint64 // 当前段的第一个序号
+	size     int64  // 当前段大小
+	nextSeq  uint64
+	segments []uint64 // 所有段的第一个序号，升序
+
+	pending          map[string]*Record // txID -> 未完成的意图记录
+	pendingBySegment map[uint64]int     // 段 -> 未完成的意图数
+	intentSegment    map[string]uint64  // txID -> 意图记录所在段
+}
+
+// Open 打开日志目录，扫描已有的段文件并在最后一个段上继续追加
+// 最后一个段末尾不完整的记录（写入时崩溃）会被截断
+func Open(dir string, segmentSize int64) (*Log, error) {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create wal directory: %v", err)
+	}
+
+	l := &Log{
+		dir:              dir,
+		segmentSize:      segmentSize,
+		nextSeq:          1,
+		pending:          make(map[string]*Record),
+		pendingBySegment: make(map[uint64]int),
+		intentSegment:    make(map[string]uint64),
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		records, validSize, err := readSegment(l.segmentPath(segment))
+		if err != nil && !last {
+			return nil, err
+		}
+		if err != nil {
+			// 最后一个段的尾部是崩溃时未写完的记录，截断后继续追加
+			fmt.Printf("[WAL] Truncating torn tail of %s at %d bytes: %v\n", l.segmentPath(segment), validSize, err)
+			if err := os.Truncate(l.segmentPath(segment), validSize); err != nil {
+				return nil, fmt.Errorf("failed to truncate wal segment: %v", err)
+			}
+		}
+
+		for _, record := range records {
+			l.apply(record, segment)
+			if record.Seq >= l.nextSeq {
+				l.nextSeq = record.Seq + 1
+			}
+		}
+		l.segments = append(l.segments, segment)
+	}
+
+	if len(l.segments) == 0 {
+		if err := l.openSegment(l.nextSeq); err != nil {
+			return nil, err
+		}
+	} else {
+		last := l.segments[len(l.segments)-1]
+		file, err := os.OpenFile(l.segmentPath(last), os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open wal segment: %v", err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to stat wal segment: %v", err)
+		}
+		l.file, l.writer, l.segment, l.size = file, bufio.NewWriter(file), last, info.Size()
+	}
+
+	fmt.Printf("[WAL] Opened %s: %d segments, next seq %d, %d pending operations\n",
+		dir, len(l.segments), l.nextSeq, len(l.pending))
+	return l, nil
+}
+
+// Begin 写入操作的意图记录并fsync，返回事务ID
+func (l *Log) Begin(op, key string, entry *types.MetadataEntry, data map[string]string) (string, error) {
+	record := &Record{
+		Type:  RecordIntent,
+		TxID:  uuid.New().String(),
+		Op:    op,
+		Key:   key,
+		Entry: entry,
+		Data:  data,
+	}
+	if err := l.append(record, true); err != nil {
+		return "", err
+	}
+	return record.TxID, nil
+}
+
+// Commit 记录操作已完成
+func (l *Log) Commit(txID string) error {
+	return l.append(&Record{Type: RecordCommit, TxID: txID}, false)
+}
+
+// Abort 记录操作已回滚或无需处理
+func (l *Log) Abort(txID string, reason error) error {
+	record := &Record{Type: RecordAbort, TxID: txID}
+	if reason != nil {
+		record.Error = reason.Error()
+	}
+	return l.append(record, false)
+}
+
+// Pending 获取所有未完成的意图记录，按序号排列
+func (l *Log) Pending() []*Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := make([]*Record, 0, len(l.pending))
+	for _, record := range l.pending {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Seq < records[j].Seq })
+	return records
+}
+
+// Segments 获取所有段文件的信息
+func (l *Log) Segments() []SegmentInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	infos := make([]SegmentInfo, 0, len(l.segments))
+	for _, segment := range l.segments {
+		info := SegmentInfo{Path: l.segmentPath(segment), FirstSeq: segment, Pending: l.pendingBySegment[segment]}
+		if stat, err := os.Stat(info.Path); err == nil {
+			info.Size = stat.Size()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Close 刷新并关闭当前段
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// append 写入一条记录，sync为true时fsync后返回
+func (l *Log) append(record *Record, sync bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("wal is closed")
+	}
+
+	record.Seq = l.nextSeq
+	record.Time = time.Now()
+	frame, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	if l.size > 0 && l.size+int64(len(frame)) > l.segmentSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := l.writer.Write(frame); err != nil {
+		return fmt.Errorf("failed to write wal record: %v", err)
+	}
+	if err := l.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush wal: %v", err)
+	}
+	if sync {
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync wal: %v", err)
+		}
+	}
+
+	l.nextSeq++
+	l.size += int64(len(frame))
+	l.apply(record, l.segment)
+	return nil
+}
+
+// apply 根据记录更新未完成操作的状态
+func (l *Log) apply(record *Record, segment uint64) {
+	switch record.Type {
+	case RecordIntent:
+		l.pending[record.TxID] = record
+		l.pendingBySegment[segment]++
+		l.intentSegment[record.TxID] = segment
+	case RecordCommit, RecordAbort:
+		if _, ok := l.pending[record.TxID]; !ok {
+			return
+		}
+		delete(l.pending, record.TxID)
+		intentSegment := l.intentSegment[record.TxID]
+		delete(l.intentSegment, record.TxID)
+		l.pendingBySegment[intentSegment]--
+		if l.pendingBySegment[intentSegment] <= 0 {
+			delete(l.pendingBySegment, intentSegment)
+		}
+	}
+}
+
+// rotate 切换到新段，并删除开头所有操作都已完成的旧段
+// 保留的段中已完成的意图，其提交或中止记录在后面的段中，因此遇到仍有未完成意图的段即停止，
+// 否则重启时这些操作会被当作未完成而再次恢复
+func (l *Log) rotate() error {
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %v", err)
+	}
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close wal segment: %v", err)
+	}
+
+	if err := l.openSegment(l.nextSeq); err != nil {
+		return err
+	}
+	l.segments = append(l.segments, l.segment)
+
+	removed := 0
+	for _, segment := range l.segments {
+		if segment == l.segment || l.pendingBySegment[segment] > 0 {
+			break
+		}
+		if err := os.Remove(l.segmentPath(segment)); err != nil {
+			fmt.Printf("[WAL] Warning: failed to remove segment %s: %v\n", l.segmentPath(segment), err)
+			break
+		}
+		removed++
+	}
+	l.segments = l.segments[removed:]
+	return nil
+}
+
+// openSegment 创建以firstSeq开头的新段
+func (l *Log) openSegment(firstSeq uint64) error {
+	file, err := os.OpenFile(l.segmentPath(firstSeq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create wal segment: %v", err)
+	}
+
+	l.file, l.writer, l.segment, l.size = file, bufio.NewWriter(file), firstSeq, 0
+	if len(l.segments) == 0 {
+		l.segments = []uint64{firstSeq}
+	}
+	return nil
+}
+
+// segmentPath 段文件路径
+func (l *Log) segmentPath(firstSeq uint64) string {
+	return segmentPath(l.dir, firstSeq)
+}
+
+func segmentPath(dir string, firstSeq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d%s", segmentPrefix, firstSeq, segmentSuffix))
+}
+
+// encodeRecord 编码记录：长度 + CRC32 + JSON
+func encodeRecord(record *Record) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal wal record: %v", err)
+	}
+
+	frame := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+	copy(frame[recordHeaderSize:], payload)
+	return frame, nil
+}
+
+// errTornRecord 记录不完整或校验失败
+var errTornRecord = errors.New("torn or corrupt wal record")
+
+// readSegment 读取段中的所有记录，返回有效数据的长度
+// 遇到不完整或校验失败的记录时停止，返回此前的记录和errTornRecord
+func readSegment(path string) ([]*Record, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open wal segment: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var records []*Record
+	var offset int64
+	header := make([]byte, recordHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return records, offset, nil
+			}
+			return records, offset, errTornRecord
+		}
+
+		length := binary.BigEndian.Uint32(header[0:4])
+		if length > maxRecordSize {
+			return records, offset, errTornRecord
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return records, offset, errTornRecord
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return records, offset, errTornRecord
+		}
+
+		var record Record
+		if err := json.Unmarshal(payload, &record); err != nil {
+			return records, offset, errTornRecord
+		}
+		records = append(records, &record)
+		offset += int64(recordHeaderSize) + int64(length)
+	}
+}
+
+// listSegments 列出目录中的段文件，按第一个序号升序
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wal directory: %v", err)
+	}
+
+	var segments []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, seq)
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+// ReadDir 只读地读取目录中所有段的记录和段信息，用于检查工具，不修改任何文件
+func ReadDir(dir string) ([]*Record, []SegmentInfo, error) {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var all []*Record
+	var infos []SegmentInfo
+	for _, segment := range segments {
+		path := segmentPath(dir, segment)
+		records, validSize, err := readSegment(path)
+		if err != nil && err != errTornRecord {
+			return nil, nil, err
+		}
+
+		info := SegmentInfo{Path: path, FirstSeq: segment, Size: validSize, Records: len(records)}
+		if stat, statErr := os.Stat(path); statErr == nil {
+			info.Size = stat.Size()
+		}
+		infos = append(infos, info)
+		all = append(all, records...)
+	}
+
+	// 统计每个段中未完成的意图
+	resolved := make(map[string]bool)
+	for _, record := range all {
+		if record.Type == RecordCommit || record.Type == RecordAbort {
+			resolved[record.TxID] = true
+		}
+	}
+	for i := range infos {
+		for _, record := range all {
+			if record.Type == RecordIntent && !resolved[record.TxID] && record.Seq >= infos[i].FirstSeq &&
+				(i == len(infos)-1 || record.Seq < infos[i+1].FirstSeq) {
+				infos[i].Pending++
+			}
+		}
+	}
+	return all, infos, nil
+}
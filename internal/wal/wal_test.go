@@ -0,0 +1,60 @@
+package wal
+
+import (
+	"testing"
+)
+
+func TestRotateKeepsCommitsOfRetainedIntents(t *testing.T) {
+	dir := t.TempDir()
+
+	// 两个意图写在同一个段中
+	log, err := Open(dir, DefaultSegmentSize)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	committed, err := log.Begin(OpPut, "committed", nil, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	pending, err := log.Begin(OpPut, "pending", nil, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// 段大小为1时每条记录都写入新段：提交记录在第二个段中，之后的记录触发切换段
+	log, err = Open(dir, 1)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if err := log.Commit(committed); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	other, err := log.Begin(OpDelete, "other", nil, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := log.Commit(other); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	log, err = Open(dir, 1)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer log.Close()
+
+	records := log.Pending()
+	if len(records) != 1 || records[0].TxID != pending {
+		keys := make([]string, len(records))
+		for i, record := range records {
+			keys[i] = record.Key
+		}
+		t.Fatalf("pending after reopen = %v, want only the pending intent", keys)
+	}
+}